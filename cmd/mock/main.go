@@ -3,10 +3,13 @@ package main
 import (
 	"context"
 	"fmt"
+	"github.com/novel/internal/jobqueue"
 	"github.com/novel/internal/model"
 	"github.com/novel/internal/pkg/config"
 	"github.com/novel/internal/pkg/db"
 	"github.com/novel/internal/pkg/logger"
+	"github.com/novel/internal/repository"
+	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 	"log"
 )
@@ -29,7 +32,12 @@ func main() {
 		panic(fmt.Errorf("fatal error database connection: %w", err))
 	}
 
-	if err := AddNovels(database); err != nil {
+	users, err := AddUsers(database)
+	if err != nil {
+		log.Fatalf("failed to seed users: %v", err)
+	}
+
+	if err := AddNovels(database, users); err != nil {
 		log.Fatalf("failed to seed novels: %v", err)
 	}
 
@@ -37,7 +45,28 @@ func main() {
 
 }
 
-func AddNovels(db *gorm.DB) error {
+// AddUsers 创建若干用于填充评分的读者账号 (密码统一为 password)
+func AddUsers(db *gorm.DB) ([]model.User, error) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("could not hash seed password: %v", err)
+	}
+
+	users := make([]model.User, 3)
+	for i := range users {
+		users[i] = model.User{
+			Username:     fmt.Sprintf("seed_reader_%d", i+1),
+			PasswordHash: string(hashedPassword),
+			Role:         model.RoleReader,
+		}
+		if err := db.Where("username = ?", users[i].Username).FirstOrCreate(&users[i]).Error; err != nil {
+			return nil, fmt.Errorf("could not create user %s: %v", users[i].Username, err)
+		}
+	}
+	return users, nil
+}
+
+func AddNovels(db *gorm.DB, users []model.User) error {
 	// 定义我们要填充的小说数据
 	novels := []model.Novel{
 		{
@@ -72,12 +101,13 @@ func AddNovels(db *gorm.DB) error {
 		// 如果是新创建的小说 (RowsAffected > 0)，我们就为它添加一些评分
 		if result.RowsAffected > 0 {
 			fmt.Printf("Novel '%s' created. Seeding ratings...\n", novel.Title)
+			// 每个用户对同一本小说只能评分一次
 			ratings := []model.Rating{
-				{NovelID: novel.ID, Score: 8},
-				{NovelID: novel.ID, Score: 9},
-				{NovelID: novel.ID, Score: 10},
+				{NovelID: novel.ID, UserID: users[0].ID, Score: 8},
+				{NovelID: novel.ID, UserID: users[1].ID, Score: 9},
+				{NovelID: novel.ID, UserID: users[2].ID, Score: 10},
 			}
-			if err := db.Create(&ratings).Error; err != nil {
+			if err := seedRatings(db, &novel, ratings); err != nil {
 				return fmt.Errorf("could not create ratings for novel %s: %v", novel.Title, err)
 			}
 		} else {
@@ -87,3 +117,23 @@ func AddNovels(db *gorm.DB) error {
 
 	return nil
 }
+
+// seedRatings 写入评分并与正常评分流程一样计入评分数、提交计算任务，权重与分数由后台任务计算
+func seedRatings(db *gorm.DB, novel *model.Novel, ratings []model.Rating) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&ratings).Error; err != nil {
+			return err
+		}
+		err := tx.Model(novel).UpdateColumn("ratings_count", gorm.Expr("ratings_count + ?", len(ratings))).Error
+		if err != nil {
+			return err
+		}
+		jobs := make([]*model.Job, len(ratings))
+		for i, r := range ratings {
+			if jobs[i], err = jobqueue.NewJob(model.JobTypeRatingCreated, map[string]uint{"rating_id": r.ID}); err != nil {
+				return err
+			}
+		}
+		return repository.NewJobRepository(tx).Enqueue(jobs...)
+	})
+}
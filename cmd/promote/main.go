@@ -0,0 +1,66 @@
+// promote 修改已注册用户的角色，用于创建第一个管理员 (之后可通过 PUT /api/v1/admin/users/:id/role 管理角色)
+//
+//	go run ./cmd/promote -username alice            # 设为管理员
+//	go run ./cmd/promote -username bob -role editor
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/novel/internal/model"
+	"github.com/novel/internal/pkg/config"
+	"github.com/novel/internal/pkg/db"
+	"github.com/novel/internal/pkg/logger"
+	"github.com/novel/internal/repository"
+	"gorm.io/gorm"
+	"log"
+	"time"
+)
+
+func main() {
+	username := flag.String("username", "", "要修改角色的用户名 (必填)")
+	role := flag.String("role", string(model.RoleAdmin), "新的角色：reader、editor、moderator 或 admin")
+	flag.Parse()
+	if *username == "" {
+		flag.Usage()
+		log.Fatal("缺少 -username 参数")
+	}
+	if !model.Role(*role).IsValid() {
+		log.Fatalf("无效的角色: %s", *role)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("无法加载配置: %v", err)
+	}
+	err = logger.InitLogger(&cfg.Logger)
+	if err != nil {
+		log.Fatalf("无法初始化日志记录器: %v", err)
+	}
+	defer logger.Sync()
+
+	database, err := db.InitDB(&cfg.Database)
+	if err != nil {
+		log.Fatalf("无法连接数据库: %v", err)
+	}
+	// 只依赖数据库配置，不加载 JWT 密钥
+	userRepo := repository.NewUserRepository(database)
+	tokenRepo := repository.NewTokenRepository(database)
+
+	user, err := userRepo.FindByUsername(*username)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Fatalf("用户 %s 不存在，请先注册", *username)
+	}
+	if err != nil {
+		log.Fatalf("无法查询用户: %v", err)
+	}
+	if err := userRepo.UpdateRole(user.ID, model.Role(*role)); err != nil {
+		log.Fatalf("无法修改角色: %v", err)
+	}
+	// 与管理接口一样吊销该用户已签发的访问令牌，新的角色在重新登录或刷新令牌后生效
+	if err := tokenRepo.RevokeUserAccessTokens(user.ID, time.Now()); err != nil {
+		log.Fatalf("角色已修改，但无法吊销已签发的访问令牌 (旧令牌在过期前仍按原角色生效): %v", err)
+	}
+	fmt.Printf("User %s is now %s\n", *username, *role)
+}
@@ -2,14 +2,26 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"github.com/novel/internal/jobqueue"
 	"github.com/novel/internal/pkg/config"
 	"github.com/novel/internal/pkg/db"
 	"github.com/novel/internal/pkg/logger"
+	"github.com/novel/internal/repository"
 	"github.com/novel/internal/router"
+	"github.com/novel/internal/scheduler"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
+// shutdownTimeout 是收到退出信号后，等待请求与后台任务处理完成的最长时间
+const shutdownTimeout = 30 * time.Second
+
 func main() {
 	// 初始化项目配置
 	cfg, err := config.LoadConfig()
@@ -30,12 +42,46 @@ func main() {
 		panic(fmt.Errorf("fatal error database connection: %w", err))
 	}
 
-	r := router.SetupRouter(database, cfg)
-	addr := fmt.Sprintf(":%s", cfg.Server.Port)
-	logger.Infof("Server is running on port %s", addr)
+	// 初始化后台任务队列，任务处理函数在装配路由时注册
+	queue := jobqueue.New(repository.NewJobRepository(database), &cfg.JobQueue)
+
+	// 初始化定时任务调度器，通过 advisory lock 保证多实例部署时同一任务只执行一次
+	sched := scheduler.New(func(ctx context.Context, name string) (func(), bool, error) {
+		return db.TryAdvisoryLock(ctx, database, name)
+	})
 
-	if err := r.Run(addr); err != nil {
-		logger.Fatalf("Server run failed: %v", err)
+	r, err := router.SetupRouter(database, cfg, queue, sched)
+	if err != nil {
+		logger.Fatalf("Failed to setup router: %v", err)
 	}
+	queue.Start()
+	sched.Start()
+
+	addr := fmt.Sprintf(":%s", cfg.Server.Port)
+	srv := &http.Server{Addr: addr, Handler: r}
+	go func() {
+		logger.Infof("Server is running on port %s", addr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Fatalf("Server run failed: %v", err)
+		}
+	}()
+
+	// 等待退出信号，先停止接收新请求，再停止定时任务并等待后台任务排空
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+	logger.InfoRaw("Shutting down server...")
 
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		logger.Errorf("Server forced to shutdown: %v", err)
+	}
+	if err := sched.Shutdown(ctx); err != nil {
+		logger.Errorf("Scheduler did not stop in time: %v", err)
+	}
+	if err := queue.Shutdown(ctx); err != nil {
+		logger.Errorf("Job queue did not drain in time: %v", err)
+	}
+	logger.InfoRaw("Server exited")
 }
@@ -6,6 +6,12 @@ type CreateRatingRequest struct {
 	Comment string `json:"comment"` // 允许用户在评分时直接带上评论
 }
 
+// UpdateRatingRequest 定义了修改评分API的请求体
+type UpdateRatingRequest struct {
+	Score   int    `json:"score" binding:"required,min=1,max=10"`
+	Comment string `json:"comment"`
+}
+
 // VoteForRatingRequest 定义了为评分投票API的请求体
 type VoteForRatingRequest struct {
 	// 校验确保了投票值只能是 1 (赞同) 或 -1 (反对)
@@ -28,3 +34,75 @@ type CreateNovelRequest struct {
 	CategoryName string   `json:"category_name" binding:"required"`
 	TagNames     []string `json:"tag_names"`
 }
+
+// UpdateNovelRequest 定义了部分更新小说 (PATCH) 的请求体
+// 所有字段均为指针：nil 表示保持原值不变
+type UpdateNovelRequest struct {
+	Title               *string `json:"title" binding:"omitempty,min=1"`
+	Author              *string `json:"author" binding:"omitempty,min=1"`
+	Description         *string `json:"description"`
+	CoverImageURL       *string `json:"cover_image_url"`
+	PublicationType     *int    `json:"publication_type" binding:"omitempty,oneof=1 2"`
+	WordCount           *int    `json:"word_count"`
+	Publisher           *string `json:"publisher"` // 传空字符串表示清空
+	Isbn                *string `json:"isbn"`      // 传空字符串表示清空
+	PublicationSite     *string `json:"publication_site"`
+	SerializationStatus *int    `json:"serialization_status"`
+
+	CategoryName *string   `json:"category_name" binding:"omitempty,min=1"`
+	TagNames     *[]string `json:"tag_names"` // 传空数组表示清空所有标签
+}
+
+// ToUpdateRequest 将完整的创建请求转换为“全量覆盖”语义的更新请求，供 PUT 使用
+// 请求中未提供的可选字段会被重置为零值
+func (r *CreateNovelRequest) ToUpdateRequest() *UpdateNovelRequest {
+	wordCount := 0
+	if r.WordCount != nil {
+		wordCount = *r.WordCount
+	}
+	tagNames := r.TagNames
+	if tagNames == nil {
+		tagNames = []string{}
+	}
+	return &UpdateNovelRequest{
+		Title:               &r.Title,
+		Author:              &r.Author,
+		Description:         &r.Description,
+		CoverImageURL:       &r.CoverImageURL,
+		PublicationType:     &r.PublicationType,
+		WordCount:           &wordCount,
+		Publisher:           stringOrEmpty(r.Publisher),
+		Isbn:                stringOrEmpty(r.Isbn),
+		PublicationSite:     stringOrEmpty(r.PublicationSite),
+		SerializationStatus: &r.SerializationStatus,
+		CategoryName:        &r.CategoryName,
+		TagNames:            &tagNames,
+	}
+}
+
+// stringOrEmpty 将 nil 指针转换为指向空字符串的指针
+func stringOrEmpty(s *string) *string {
+	if s == nil {
+		empty := ""
+		return &empty
+	}
+	return s
+}
+
+// TrendingQuery 定义了热度榜的查询参数
+type TrendingQuery struct {
+	Window   string `form:"window" binding:"omitempty,oneof=day week month"` // 时间窗口，默认 week
+	Page     int    `form:"page,default=1"`
+	PageSize int    `form:"page_size,default=10"`
+	Cursor   string `form:"cursor"`
+
+	PublicationType *int  `form:"publication_type"`
+	CategoryID      *uint `form:"category_id"`
+}
+
+// WeeklyChartQuery 定义了周榜的查询参数
+type WeeklyChartQuery struct {
+	Date       string `form:"date"`        // 榜单日期 YYYY-MM-DD，默认最近一次快照
+	CategoryID *uint  `form:"category_id"` // 指定时生成分类内的周榜
+	Limit      int    `form:"limit"`       // 每组最多返回的条数，默认 20
+}
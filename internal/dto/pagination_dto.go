@@ -1,18 +1,24 @@
 package dto
 
 // PaginatedResponse 定义了标准的分页响应格式
+// 同时支持两种翻页方式：
+//   - 按 page/page_size 偏移翻页，返回总数 total
+//   - 按游标翻页：把上一页返回的 next_cursor 作为 cursor 参数传回，此时忽略 page 且不返回 total
 type PaginatedResponse struct {
-	Total    int64       `json:"total"`
-	Page     int         `json:"page"`
-	PageSize int         `json:"page_size"`
-	Data     interface{} `json:"data"`
+	Total      *int64      `json:"total,omitempty"`
+	Page       int         `json:"page,omitempty"`
+	PageSize   int         `json:"page_size"`
+	NextCursor string      `json:"next_cursor,omitempty"` // 还有下一页时返回
+	Data       interface{} `json:"data"`
 }
 
 type ListQuery struct {
 	Page     int    `form:"page,default=1"`
 	PageSize int    `form:"page_size,default=10"`
-	SortBy   string `form:"sort_by,default=created_at"`
-	Order    string `form:"order,default=desc"`
+	SortBy   string `form:"sort_by"`                                  // 可选值：score、ratings_count、newest、word_count、trending (及 trending_day/week/month)、title_pinyin、relevance，默认 newest，带搜索词时默认 relevance
+	Order    string `form:"order" binding:"omitempty,oneof=asc desc"` // 为空时使用排序方式的默认方向 (拼音升序，其余降序)
+	Q        string `form:"q" binding:"max=100"`                      // 搜索词，在标题、作者、简介中全文检索
+	Cursor   string `form:"cursor"`                                   // 上一页返回的 next_cursor，传入时按游标翻页
 
 	// --- 新增的筛选字段 ---
 	PublicationType *int   `form:"publication_type"` // 使用指针以允许不传此参数
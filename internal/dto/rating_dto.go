@@ -0,0 +1,88 @@
+package dto
+
+import (
+	"github.com/novel/internal/model"
+	"time"
+)
+
+// RatingListQuery 定义了小说评分列表的查询参数，翻页方式与 ListQuery 相同
+type RatingListQuery struct {
+	Page     int    `form:"page,default=1"`
+	PageSize int    `form:"page_size,default=10"`
+	SortBy   string `form:"sort_by"`                                  // 可选值：helpful (默认)、weight、newest、score
+	Order    string `form:"order" binding:"omitempty,oneof=asc desc"` // 为空时降序
+	Cursor   string `form:"cursor"`                                   // 上一页返回的 next_cursor，传入时按游标翻页
+}
+
+// ReviewerInfo 是评分作者的公开信息
+type ReviewerInfo struct {
+	Username  string          `json:"username"`
+	TrustTier model.TrustTier `json:"trust_tier"`
+}
+
+// RatingResponse 是对外展示的评分，不包含权重、信誉分等内部计算字段
+type RatingResponse struct {
+	ID             uint         `json:"id"`
+	NovelID        uint         `json:"novel_id"`
+	Score          int          `json:"score"`
+	Comment        string       `json:"comment"`
+	UpvotesCount   int          `json:"upvotes_count"`
+	DownvotesCount int          `json:"downvotes_count"`
+	CreatedAt      time.Time    `json:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at"`
+	Reviewer       ReviewerInfo `json:"reviewer"`
+}
+
+// NewRatingResponse 将评分及其预加载的作者转换为对外展示的结构
+func NewRatingResponse(r *model.Rating) RatingResponse {
+	return RatingResponse{
+		ID:             r.ID,
+		NovelID:        r.NovelID,
+		Score:          r.Score,
+		Comment:        r.Comment,
+		UpvotesCount:   r.UpvotesCount,
+		DownvotesCount: r.DownvotesCount,
+		CreatedAt:      r.CreatedAt,
+		UpdatedAt:      r.UpdatedAt,
+		Reviewer: ReviewerInfo{
+			Username:  r.User.Username,
+			TrustTier: model.TrustTierOf(r.User.TrustScore),
+		},
+	}
+}
+
+// ScoreBucket 是评分分布直方图中的一项
+type ScoreBucket struct {
+	Score int   `json:"score"`
+	Count int64 `json:"count"`
+}
+
+// RatingStats 是一本小说所有有效评分的统计信息，没有评分时各统计值为 null
+type RatingStats struct {
+	Count        int64         `json:"count"`
+	Histogram    []ScoreBucket `json:"histogram"`     // 1~10 分各自的评分数
+	Mean         *float64      `json:"mean"`          // 算术平均分
+	Median       *float64      `json:"median"`        // 中位数
+	WeightedMean *float64      `json:"weighted_mean"` // 按评分权重加权的平均分 (IMDb 收缩之前)
+	StdDev       *float64      `json:"std_dev"`       // 总体标准差
+}
+
+// IncidentListQuery 定义了评分异常事件列表的查询参数
+type IncidentListQuery struct {
+	Status   string `form:"status" binding:"omitempty,oneof=open confirmed dismissed expired"` // 为空时返回全部
+	Page     int    `form:"page,default=1"`
+	PageSize int    `form:"page_size,default=10"`
+}
+
+// ResolveIncidentRequest 定义了处理评分异常事件的请求体
+type ResolveIncidentRequest struct {
+	// confirm：确认为刷分，可疑评分保持降权；dismiss：误报，可疑评分恢复正常权重
+	Action string `json:"action" binding:"required,oneof=confirm dismiss"`
+	Note   string `json:"note" binding:"max=500"`
+}
+
+// VoteRingReportQuery 定义了互赞团体分析报告列表的查询参数
+type VoteRingReportQuery struct {
+	Page     int `form:"page,default=1"`
+	PageSize int `form:"page_size,default=10"`
+}
@@ -9,3 +9,13 @@ type LoginRequest struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
 }
+
+// RefreshTokenRequest 定义了刷新访问令牌的请求体
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// UpdateUserRoleRequest 定义了管理员修改用户角色的请求体
+type UpdateUserRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=reader editor moderator admin"`
+}
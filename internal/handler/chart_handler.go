@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/novel/internal/dto"
+	"github.com/novel/internal/pkg/response"
+	"github.com/novel/internal/service"
+	"gorm.io/gorm"
+	"strconv"
+)
+
+// ChartHandler 处理得分历史与榜单相关的请求
+type ChartHandler struct {
+	svc service.ChartService
+}
+
+// NewChartHandler 是 ChartHandler 的构造函数
+func NewChartHandler(svc service.ChartService) *ChartHandler {
+	return &ChartHandler{svc: svc}
+}
+
+// GetNovelHistory 返回小说每日的得分与排名历史，可通过 days 参数指定天数
+func (h *ChartHandler) GetNovelHistory(c *gin.Context) {
+	novelID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的小说ID")
+		return
+	}
+	days, err := strconv.Atoi(c.DefaultQuery("days", "0"))
+	if err != nil {
+		response.BadRequest(c, "查询参数错误")
+		return
+	}
+	history, err := h.svc.GetNovelHistory(uint(novelID), days)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.NotFound(c)
+		} else {
+			response.ServerError(c)
+		}
+		return
+	}
+	response.Ok(c, history)
+}
+
+// GetWeeklyChart 返回周榜：与一周前相比上升、下降和新上榜的小说
+func (h *ChartHandler) GetWeeklyChart(c *gin.Context) {
+	var query dto.WeeklyChartQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(c, "查询参数错误")
+		return
+	}
+	chart, err := h.svc.GetWeeklyChart(&query)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidDate) {
+			response.BadRequest(c, "日期格式应为 YYYY-MM-DD")
+		} else if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.NotFound(c)
+		} else {
+			response.ServerError(c)
+		}
+		return
+	}
+	response.Ok(c, chart)
+}
@@ -0,0 +1,23 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/novel/internal/pkg/jwtkeys"
+	"net/http"
+)
+
+// JWKSHandler 发布校验访问令牌所用的公钥
+type JWKSHandler struct {
+	keys *jwtkeys.KeySet
+}
+
+// NewJWKSHandler 是 JWKSHandler 的构造函数
+func NewJWKSHandler(keys *jwtkeys.KeySet) *JWKSHandler {
+	return &JWKSHandler{keys: keys}
+}
+
+// GetJWKS 按 RFC 7517 的格式返回公钥集合，不使用统一的响应包装，以便标准的 JWT 库直接读取
+func (h *JWKSHandler) GetJWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.keys.JWKS())
+}
@@ -31,13 +31,38 @@ func (h *NovelHandler) GetNovels(c *gin.Context) {
 	}
 	paginatedResult, err := h.svc.GetRankedNovels(&query)
 	if err != nil {
-		response.ServerError(c)
+		if errors.Is(err, service.ErrUnknownSortKey) {
+			response.BadRequest(c, "不支持的排序方式")
+		} else if errors.Is(err, service.ErrInvalidCursor) {
+			response.BadRequest(c, "无效的分页游标")
+		} else {
+			response.ServerError(c)
+		}
 		return
 	}
 
 	response.Ok(c, paginatedResult)
 }
 
+// GetTrendingNovels 获取日、周、月热度榜
+func (h *NovelHandler) GetTrendingNovels(c *gin.Context) {
+	var query dto.TrendingQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(c, "查询参数错误")
+		return
+	}
+	result, err := h.svc.GetTrendingNovels(&query)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCursor) {
+			response.BadRequest(c, "无效的分页游标")
+		} else {
+			response.ServerError(c)
+		}
+		return
+	}
+	response.Ok(c, result)
+}
+
 // GetNovelByID 获取单本小说的详情 (已为高性能预计算做好准备)
 func (h *NovelHandler) GetNovelByID(c *gin.Context) {
 	novelID, err := strconv.ParseUint(c.Param("id"), 10, 32)
@@ -57,6 +82,59 @@ func (h *NovelHandler) GetNovelByID(c *gin.Context) {
 	response.Ok(c, details)
 }
 
+// GetNovelRatings 分页获取小说的评分列表
+func (h *NovelHandler) GetNovelRatings(c *gin.Context) {
+	novelID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的小说ID")
+		return
+	}
+	var query dto.RatingListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(c, "查询参数错误")
+		return
+	}
+	result, err := h.svc.GetNovelRatings(uint(novelID), &query)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			response.NotFound(c)
+		case errors.Is(err, service.ErrUnknownSortKey):
+			response.BadRequest(c, "不支持的排序方式")
+		case errors.Is(err, service.ErrInvalidCursor):
+			response.BadRequest(c, "无效的分页游标")
+		default:
+			response.ServerError(c)
+		}
+		return
+	}
+	response.Ok(c, result)
+}
+
+// ExplainNovelScore 返回小说最终得分的计算明细，可通过 top 参数指定展示的高权重评分条数
+func (h *NovelHandler) ExplainNovelScore(c *gin.Context) {
+	novelID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的小说ID")
+		return
+	}
+	top, err := strconv.Atoi(c.DefaultQuery("top", "0"))
+	if err != nil {
+		response.BadRequest(c, "查询参数错误")
+		return
+	}
+	explanation, err := h.svc.ExplainNovelScore(uint(novelID), top)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.NotFound(c)
+		} else {
+			response.ServerError(c)
+		}
+		return
+	}
+	response.Ok(c, explanation)
+}
+
 // CreateRating 为小说创建新评分 (已完善)
 func (h *NovelHandler) CreateRating(c *gin.Context) {
 	// 1. 解析小说ID，确保类型正确
@@ -89,9 +167,12 @@ func (h *NovelHandler) CreateRating(c *gin.Context) {
 	// 4. 调用 Service 处理业务，现在参数完全匹配
 	rating, err := h.svc.CreateRatingForNovel(userID, uint(novelID), req.Score, req.Comment)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
 			response.NotFound(c)
-		} else {
+		case errors.Is(err, service.ErrRatingAlreadyExists):
+			response.FailWithCode(c, 409, "您已经评价过这本小说，请修改原有评分")
+		default:
 			response.ServerError(c)
 		}
 		return
@@ -137,6 +218,72 @@ func (h *NovelHandler) VoteForRating(c *gin.Context) {
 	response.OkWithMessage(c, "投票成功", nil)
 }
 
+// UpdateRating 修改当前用户自己的评分
+func (h *NovelHandler) UpdateRating(c *gin.Context) {
+	ratingID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的评分ID")
+		return
+	}
+
+	userID, exists := c.Get(middleware.CtxUserIDKey)
+	if !exists {
+		response.Fail(c, "无法获取用户信息，请重新登录")
+		return
+	}
+
+	var req dto.UpdateRatingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数不合法")
+		return
+	}
+
+	rating, err := h.svc.UpdateRatingForUser(userID.(uint), uint(ratingID), req.Score, req.Comment)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			response.NotFound(c)
+		case errors.Is(err, service.ErrNotRatingOwner):
+			response.FailWithCode(c, 403, "只能修改自己的评分")
+		default:
+			response.ServerError(c)
+		}
+		return
+	}
+
+	response.OkWithMessage(c, "评分修改成功", rating)
+}
+
+// DeleteRating 撤回当前用户自己的评分
+func (h *NovelHandler) DeleteRating(c *gin.Context) {
+	ratingID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的评分ID")
+		return
+	}
+
+	userID, exists := c.Get(middleware.CtxUserIDKey)
+	if !exists {
+		response.Fail(c, "无法获取用户信息，请重新登录")
+		return
+	}
+
+	err = h.svc.WithdrawRating(userID.(uint), uint(ratingID))
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			response.NotFound(c)
+		case errors.Is(err, service.ErrNotRatingOwner):
+			response.FailWithCode(c, 403, "只能撤回自己的评分")
+		default:
+			response.ServerError(c)
+		}
+		return
+	}
+
+	response.OkWithMessage(c, "评分已撤回", nil)
+}
+
 func (h *NovelHandler) CreateNovel(c *gin.Context) {
 	// 1. 绑定并校验请求体到我们专用的 DTO
 	var req dto.CreateNovelRequest
@@ -162,3 +309,186 @@ func (h *NovelHandler) CreateNovel(c *gin.Context) {
 		Data: novel,
 	})
 }
+
+// UpdateNovel 部分更新小说信息 (PATCH)，只修改请求中提供的字段
+func (h *NovelHandler) UpdateNovel(c *gin.Context) {
+	novelID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的小说ID")
+		return
+	}
+
+	var req dto.UpdateNovelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数不合法")
+		return
+	}
+
+	h.updateNovel(c, uint(novelID), &req)
+}
+
+// ReplaceNovel 全量更新小说信息 (PUT)，未提供的可选字段会被清空
+func (h *NovelHandler) ReplaceNovel(c *gin.Context) {
+	novelID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的小说ID")
+		return
+	}
+
+	var req dto.CreateNovelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数不合法或缺少必要字段")
+		return
+	}
+
+	h.updateNovel(c, uint(novelID), req.ToUpdateRequest())
+}
+
+// updateNovel 是 PUT 与 PATCH 共用的更新流程
+func (h *NovelHandler) updateNovel(c *gin.Context, novelID uint, req *dto.UpdateNovelRequest) {
+	novel, err := h.svc.UpdateNovel(novelID, req)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.NotFound(c)
+		} else {
+			response.ServerError(c)
+		}
+		return
+	}
+	response.OkWithMessage(c, "小说更新成功", novel)
+}
+
+// DeleteNovel 软删除小说，其评分与投票一并被软删除
+func (h *NovelHandler) DeleteNovel(c *gin.Context) {
+	novelID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的小说ID")
+		return
+	}
+
+	if err := h.svc.DeleteNovel(uint(novelID)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.NotFound(c)
+		} else {
+			response.ServerError(c)
+		}
+		return
+	}
+	response.OkWithMessage(c, "小说已删除", nil)
+}
+
+// RestoreNovel 恢复一本已被删除的小说
+func (h *NovelHandler) RestoreNovel(c *gin.Context) {
+	novelID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的小说ID")
+		return
+	}
+
+	novel, err := h.svc.RestoreNovel(uint(novelID))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.NotFound(c)
+		} else {
+			response.ServerError(c)
+		}
+		return
+	}
+	response.OkWithMessage(c, "小说已恢复", novel)
+}
+
+// RebuildNovelScores 全量重新统计一本小说的评分聚合值 (修复工具)
+func (h *NovelHandler) RebuildNovelScores(c *gin.Context) {
+	novelID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的小说ID")
+		return
+	}
+
+	novel, err := h.svc.RebuildNovelScores(uint(novelID))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.NotFound(c)
+		} else {
+			response.ServerError(c)
+		}
+		return
+	}
+	response.OkWithMessage(c, "小说分数已重新计算", novel)
+}
+
+// RebuildAllNovelScores 提交一个后台任务，全量重新统计所有小说的评分聚合值 (修复工具)
+func (h *NovelHandler) RebuildAllNovelScores(c *gin.Context) {
+	if err := h.svc.ScheduleRebuildAllNovelScores(); err != nil {
+		response.ServerError(c)
+		return
+	}
+	response.OkWithMessage(c, "重算任务已提交", nil)
+}
+
+// ListIncidents 分页列出评分异常事件，可按 status 筛选
+func (h *NovelHandler) ListIncidents(c *gin.Context) {
+	var query dto.IncidentListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(c, "查询参数错误")
+		return
+	}
+	result, err := h.svc.ListIncidents(&query)
+	if err != nil {
+		response.ServerError(c)
+		return
+	}
+	response.Ok(c, result)
+}
+
+// GetIncident 返回评分异常事件的详情及可疑评分
+func (h *NovelHandler) GetIncident(c *gin.Context) {
+	incidentID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的事件ID")
+		return
+	}
+	details, err := h.svc.GetIncident(uint(incidentID))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.NotFound(c)
+		} else {
+			response.ServerError(c)
+		}
+		return
+	}
+	response.Ok(c, details)
+}
+
+// ResolveIncident 处理评分异常事件：确认刷分或判定为误报，并解除小说分数的冻结
+func (h *NovelHandler) ResolveIncident(c *gin.Context) {
+	incidentID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的事件ID")
+		return
+	}
+	moderatorID, exists := c.Get(middleware.CtxUserIDKey)
+	if !exists {
+		response.Fail(c, "无法获取用户信息，请重新登录")
+		return
+	}
+	var req dto.ResolveIncidentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数不合法")
+		return
+	}
+
+	incident, err := h.svc.ResolveIncident(moderatorID.(uint), uint(incidentID), &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			response.NotFound(c)
+		case errors.Is(err, service.ErrIncidentResolved):
+			response.FailWithCode(c, 409, "该事件已处理")
+		default:
+			response.ServerError(c)
+		}
+		return
+	}
+	response.OkWithMessage(c, "事件已处理", incident)
+}
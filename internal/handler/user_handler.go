@@ -4,18 +4,23 @@ import (
 	"errors"
 	"github.com/gin-gonic/gin"
 	"github.com/novel/internal/dto"
+	"github.com/novel/internal/middleware"
+	"github.com/novel/internal/model"
 	"github.com/novel/internal/pkg/response"
 	"github.com/novel/internal/service"
+	"gorm.io/gorm"
+	"strconv"
 )
 
 // UserHandler 结构体
 type UserHandler struct {
-	svc service.UserService
+	svc      service.UserService
+	trustSvc service.TrustService
 }
 
 // NewUserHandler 构造函数
-func NewUserHandler(svc service.UserService) *UserHandler {
-	return &UserHandler{svc: svc}
+func NewUserHandler(svc service.UserService, trustSvc service.TrustService) *UserHandler {
+	return &UserHandler{svc: svc, trustSvc: trustSvc}
 }
 
 // Register 处理用户注册请求
@@ -48,7 +53,7 @@ func (h *UserHandler) Login(c *gin.Context) {
 		return
 	}
 
-	token, err := h.svc.Login(req.Username, req.Password)
+	tokens, err := h.svc.Login(req.Username, req.Password)
 	if err != nil {
 		if errors.Is(err, service.ErrInvalidCredentials) {
 			response.Fail(c, "用户名或密码错误")
@@ -58,5 +63,109 @@ func (h *UserHandler) Login(c *gin.Context) {
 		return
 	}
 
-	response.Ok(c, gin.H{"token": token})
+	response.Ok(c, tokens)
+}
+
+// Refresh 用刷新令牌换取新的访问令牌和刷新令牌
+func (h *UserHandler) Refresh(c *gin.Context) {
+	var req dto.RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	tokens, err := h.svc.Refresh(req.RefreshToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidToken):
+			response.FailWithCode(c, 401, "无效的刷新令牌，请重新登录")
+		case errors.Is(err, service.ErrRefreshTokenReused):
+			response.FailWithCode(c, 401, "刷新令牌已被使用过，为保护账号安全已退出该会话，请重新登录")
+		default:
+			response.ServerError(c)
+		}
+		return
+	}
+	response.Ok(c, tokens)
+}
+
+// Logout 退出当前会话，当前的访问令牌和刷新令牌随即失效
+func (h *UserHandler) Logout(c *gin.Context) {
+	sessionID := c.GetString(middleware.CtxSessionIDKey)
+	if sessionID == "" {
+		response.Fail(c, "无法获取会话信息，请重新登录")
+		return
+	}
+	if err := h.svc.Logout(sessionID); err != nil {
+		response.ServerError(c)
+		return
+	}
+	response.OkWithMessage(c, "已退出登录", nil)
+}
+
+// LogoutAll 退出当前用户在所有设备上的会话
+func (h *UserHandler) LogoutAll(c *gin.Context) {
+	userID, exists := c.Get(middleware.CtxUserIDKey)
+	if !exists {
+		response.Fail(c, "无法获取用户信息，请重新登录")
+		return
+	}
+	if err := h.svc.LogoutAll(userID.(uint)); err != nil {
+		response.ServerError(c)
+		return
+	}
+	response.OkWithMessage(c, "已退出所有设备", nil)
+}
+
+// UpdateUserRole 修改指定用户的角色 (仅管理员)
+func (h *UserHandler) UpdateUserRole(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的用户ID")
+		return
+	}
+
+	var req dto.UpdateUserRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	user, err := h.svc.UpdateRole(uint(userID), model.Role(req.Role))
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			response.NotFound(c)
+		case errors.Is(err, service.ErrInvalidRole):
+			response.BadRequest(c, "无效的角色")
+		default:
+			response.ServerError(c)
+		}
+		return
+	}
+	response.OkWithMessage(c, "角色更新成功", user)
+}
+
+// GetMyTrust 返回当前用户的信誉分及最近的变动记录，可通过 limit 参数指定条数
+func (h *UserHandler) GetMyTrust(c *gin.Context) {
+	userID, exists := c.Get(middleware.CtxUserIDKey)
+	if !exists {
+		response.Fail(c, "无法获取用户信息，请重新登录")
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "0"))
+	if err != nil {
+		response.BadRequest(c, "查询参数错误")
+		return
+	}
+	summary, err := h.trustSvc.GetTrustSummary(userID.(uint), limit)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.NotFound(c)
+		} else {
+			response.ServerError(c)
+		}
+		return
+	}
+	response.Ok(c, summary)
 }
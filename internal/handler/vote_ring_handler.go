@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/novel/internal/dto"
+	"github.com/novel/internal/pkg/response"
+	"github.com/novel/internal/service"
+	"gorm.io/gorm"
+	"strconv"
+)
+
+// VoteRingHandler 处理互赞团体与马甲账号分析报告相关的请求
+type VoteRingHandler struct {
+	svc service.VoteRingService
+}
+
+// NewVoteRingHandler 是 VoteRingHandler 的构造函数
+func NewVoteRingHandler(svc service.VoteRingService) *VoteRingHandler {
+	return &VoteRingHandler{svc: svc}
+}
+
+// ListReports 分页列出历次分析的报告摘要
+func (h *VoteRingHandler) ListReports(c *gin.Context) {
+	var query dto.VoteRingReportQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(c, "查询参数错误")
+		return
+	}
+	result, err := h.svc.ListReports(&query)
+	if err != nil {
+		response.ServerError(c)
+		return
+	}
+	response.Ok(c, result)
+}
+
+// GetReport 返回一次分析的完整报告
+func (h *VoteRingHandler) GetReport(c *gin.Context) {
+	reportID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的报告ID")
+		return
+	}
+	report, err := h.svc.GetReport(uint(reportID))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.NotFound(c)
+		} else {
+			response.ServerError(c)
+		}
+		return
+	}
+	response.Ok(c, report)
+}
@@ -0,0 +1,300 @@
+package jobqueue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/novel/internal/model"
+	"github.com/novel/internal/pkg/config"
+	"github.com/novel/internal/pkg/logger"
+	"github.com/novel/internal/repository"
+	"go.uber.org/zap"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Handler 处理某一类型的任务，返回错误时任务会按退避策略重试
+// ctx 会在队列关闭或任务失去租约时被取消，分批执行的长任务应在每批之间检查它
+type Handler func(ctx context.Context, payload []byte) error
+
+// ErrDiscard 由处理函数返回 (可用 %w 包装)，表示任务已无需执行，例如相关数据已被删除
+var ErrDiscard = errors.New("job discarded")
+
+// Queue 是基于数据库任务表的后台任务队列，负责领取、执行、重试和死信处理
+type Queue struct {
+	repo     repository.JobRepository
+	cfg      config.JobQueueConfig
+	workerID string
+
+	mu       sync.RWMutex
+	handlers map[string]Handler
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	stop    chan struct{}
+	wg      sync.WaitGroup
+	started bool
+}
+
+// New 创建任务队列，未配置的参数使用默认值
+func New(repo repository.JobRepository, cfg *config.JobQueueConfig) *Queue {
+	c := *cfg
+	if c.Workers <= 0 {
+		c.Workers = 4
+	}
+	if c.PollInterval <= 0 {
+		c.PollInterval = time.Second
+	}
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = 5
+	}
+	if c.BaseBackoff <= 0 {
+		c.BaseBackoff = 2 * time.Second
+	}
+	if c.MaxBackoff <= 0 {
+		c.MaxBackoff = 10 * time.Minute
+	}
+	if c.StaleAfter <= 0 {
+		c.StaleAfter = 5 * time.Minute
+	}
+	if c.Retention <= 0 {
+		c.Retention = 7 * 24 * time.Hour
+	}
+
+	hostname, _ := os.Hostname()
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Queue{
+		repo:     repo,
+		cfg:      c,
+		workerID: fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+		handlers: make(map[string]Handler),
+		ctx:      ctx,
+		cancel:   cancel,
+		stop:     make(chan struct{}),
+	}
+}
+
+// NewJob 将 payload 序列化为 JSON，构造一个待入队的任务
+func NewJob(jobType string, payload interface{}) (*model.Job, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal payload for job %s: %w", jobType, err)
+	}
+	return &model.Job{Type: jobType, Payload: string(data)}, nil
+}
+
+// Register 为某一任务类型注册处理函数，必须在 Start 之前调用
+func (q *Queue) Register(jobType string, h Handler) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.handlers[jobType] = h
+}
+
+// Start 启动 worker 与维护协程
+func (q *Queue) Start() {
+	if q.started {
+		return
+	}
+	q.started = true
+	for i := 0; i < q.cfg.Workers; i++ {
+		q.wg.Add(1)
+		go q.runWorker()
+	}
+	q.wg.Add(1)
+	go q.runJanitor()
+	logger.Infof("Job queue started with %d workers (worker id %s)", q.cfg.Workers, q.workerID)
+}
+
+// Shutdown 停止领取新任务，取消正在执行的任务的 context 并等待它们返回
+// 分批执行的长任务在当前批次结束后停止并重新入队；若 ctx 先于任务返回而结束，
+// 未完成的任务会在 StaleAfter 之后被重新入队
+func (q *Queue) Shutdown(ctx context.Context) error {
+	q.cancel()
+	if !q.started {
+		return nil
+	}
+	close(q.stop)
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		logger.InfoRaw("Job queue drained")
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("job queue shutdown: %w", ctx.Err())
+	}
+}
+
+// runWorker 循环领取并执行任务，队列为空时按轮询间隔休眠
+func (q *Queue) runWorker() {
+	defer q.wg.Done()
+	for {
+		select {
+		case <-q.stop:
+			return
+		default:
+		}
+
+		jobs, err := q.repo.ClaimDue(q.workerID, 1)
+		if err != nil {
+			logger.ErrorRaw("Failed to claim jobs", zap.Error(err))
+		}
+		if err != nil || len(jobs) == 0 {
+			select {
+			case <-q.stop:
+				return
+			case <-time.After(q.cfg.PollInterval):
+			}
+			continue
+		}
+
+		for i := range jobs {
+			q.process(&jobs[i])
+		}
+	}
+}
+
+// runJanitor 定期回收失效 worker 遗留的任务，并清理过期的成功任务
+func (q *Queue) runJanitor() {
+	defer q.wg.Done()
+	ticker := time.NewTicker(q.cfg.StaleAfter / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-q.stop:
+			return
+		case <-ticker.C:
+			if n, err := q.repo.RequeueStale(time.Now().Add(-q.cfg.StaleAfter)); err != nil {
+				logger.ErrorRaw("Failed to requeue stale jobs", zap.Error(err))
+			} else if n > 0 {
+				logger.WarnRaw("Requeued stale jobs", zap.Int64("count", n))
+			}
+			if _, err := q.repo.PurgeDone(time.Now().Add(-q.cfg.Retention)); err != nil {
+				logger.ErrorRaw("Failed to purge finished jobs", zap.Error(err))
+			}
+		}
+	}
+}
+
+// process 执行单个任务并根据结果更新其状态
+// 执行期间定期续租，任务在租约丢失后已被重新入队，此时不再更新其状态
+func (q *Queue) process(job *model.Job) {
+	fields := []zap.Field{zap.Uint("job_id", job.ID), zap.String("job_type", job.Type), zap.Int("attempt", job.Attempts)}
+
+	q.mu.RLock()
+	h, ok := q.handlers[job.Type]
+	q.mu.RUnlock()
+	if !ok {
+		logger.ErrorRaw("No handler registered for job, moving to dead letter", fields...)
+		if err := q.repo.MarkDead(job.ID, "no handler registered for job type"); err != nil {
+			logger.ErrorRaw("Failed to mark job dead", append(fields, zap.Error(err))...)
+		}
+		return
+	}
+
+	ctx, cancel := context.WithCancel(q.ctx)
+	var lost atomic.Bool
+	heartbeat := make(chan struct{})
+	go func() {
+		defer close(heartbeat)
+		q.keepLease(ctx, cancel, job, &lost)
+	}()
+	err := q.run(ctx, h, job)
+	cancel()
+	<-heartbeat
+	if lost.Load() {
+		logger.WarnRaw("Job lease lost, leaving it to the worker that claimed it again", fields...)
+		return
+	}
+
+	switch {
+	case err == nil:
+		if err := q.repo.MarkDone(job.ID); err != nil {
+			logger.ErrorRaw("Failed to mark job done", append(fields, zap.Error(err))...)
+		}
+	case q.ctx.Err() != nil:
+		logger.WarnRaw("Job interrupted by shutdown, requeueing", append(fields, zap.Error(err))...)
+		if err := q.repo.MarkRetry(job.ID, err.Error(), time.Now()); err != nil {
+			logger.ErrorRaw("Failed to requeue interrupted job", append(fields, zap.Error(err))...)
+		}
+	case job.Attempts >= q.cfg.MaxAttempts:
+		logger.ErrorRaw("Job exhausted retries, moving to dead letter", append(fields, zap.Error(err))...)
+		if err := q.repo.MarkDead(job.ID, err.Error()); err != nil {
+			logger.ErrorRaw("Failed to mark job dead", append(fields, zap.Error(err))...)
+		}
+	default:
+		delay := Backoff(q.cfg.BaseBackoff, q.cfg.MaxBackoff, job.Attempts)
+		logger.WarnRaw("Job failed, scheduling retry", append(fields, zap.Error(err), zap.Duration("retry_in", delay))...)
+		if err := q.repo.MarkRetry(job.ID, err.Error(), time.Now().Add(delay)); err != nil {
+			logger.ErrorRaw("Failed to schedule job retry", append(fields, zap.Error(err))...)
+		}
+	}
+}
+
+// keepLease 在任务执行期间每隔 StaleAfter/3 刷新一次领取时间，直到 ctx 结束
+// 任务已被重新入队时标记 lost 并取消 ctx，避免与重新领取它的 worker 同时执行
+func (q *Queue) keepLease(ctx context.Context, cancel context.CancelFunc, job *model.Job, lost *atomic.Bool) {
+	ticker := time.NewTicker(q.cfg.StaleAfter / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			held, err := q.repo.ExtendLease(job.ID, q.workerID)
+			if err != nil {
+				logger.ErrorRaw("Failed to extend job lease", zap.Uint("job_id", job.ID), zap.Error(err))
+				continue
+			}
+			if !held {
+				lost.Store(true)
+				cancel()
+				return
+			}
+		}
+	}
+}
+
+// run 调用处理函数，并将 panic 转换为普通错误以便重试
+func (q *Queue) run(ctx context.Context, h Handler, job *model.Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	err = h(ctx, []byte(job.Payload))
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, ErrDiscard) {
+		logger.InfoRaw("Job discarded by handler", zap.Uint("job_id", job.ID), zap.String("job_type", job.Type), zap.Error(err))
+		return nil
+	}
+	return err
+}
+
+// Backoff 计算第 attempt 次失败后的重试等待时间：base * 2^(attempt-1)，不超过 max
+func Backoff(base, max time.Duration, attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	delay := base
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= max || delay <= 0 {
+			return max
+		}
+	}
+	if delay > max {
+		return max
+	}
+	return delay
+}
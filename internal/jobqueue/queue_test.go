@@ -0,0 +1,84 @@
+package jobqueue
+
+import (
+	"context"
+	"github.com/novel/internal/model"
+	"github.com/novel/internal/pkg/config"
+	"github.com/novel/internal/pkg/logger"
+	"github.com/novel/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+// TestBackoff 验证重试等待时间按指数增长并被限制在上限以内
+func TestBackoff(t *testing.T) {
+	base, max := 2*time.Second, time.Minute
+
+	cases := []struct {
+		attempt  int
+		expected time.Duration
+	}{
+		{attempt: 0, expected: 2 * time.Second},
+		{attempt: 1, expected: 2 * time.Second},
+		{attempt: 2, expected: 4 * time.Second},
+		{attempt: 3, expected: 8 * time.Second},
+		{attempt: 5, expected: 32 * time.Second},
+		{attempt: 6, expected: time.Minute},
+		{attempt: 100, expected: time.Minute},
+	}
+	for _, tc := range cases {
+		assert.Equal(t, tc.expected, Backoff(base, max, tc.attempt), "attempt %d", tc.attempt)
+	}
+}
+
+// fakeJobRepo 记录续租与状态更新，held 为 false 时模拟任务已被重新入队
+type fakeJobRepo struct {
+	repository.JobRepository
+	held     bool
+	extended int
+	marked   []string
+}
+
+func (r *fakeJobRepo) ExtendLease(id uint, workerID string) (bool, error) {
+	r.extended++
+	return r.held, nil
+}
+
+func (r *fakeJobRepo) MarkDone(id uint) error {
+	r.marked = append(r.marked, "done")
+	return nil
+}
+
+func (r *fakeJobRepo) MarkRetry(id uint, lastErr string, runAt time.Time) error {
+	r.marked = append(r.marked, "retry")
+	return nil
+}
+
+// TestProcessKeepsLease 验证长任务执行期间会续租，失去租约时取消任务且不再更新其状态
+func TestProcessKeepsLease(t *testing.T) {
+	require.NoError(t, logger.InitLogger(&config.LogConfig{Level: "error"}))
+	longJob := func(ctx context.Context, _ []byte) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+			return nil
+		}
+	}
+
+	repo := &fakeJobRepo{held: true}
+	q := New(repo, &config.JobQueueConfig{StaleAfter: 30 * time.Millisecond})
+	q.Register("long", longJob)
+	q.process(&model.Job{ID: 1, Type: "long", Attempts: 1})
+	assert.Greater(t, repo.extended, 0)
+	assert.Equal(t, []string{"done"}, repo.marked)
+
+	repo = &fakeJobRepo{held: false}
+	q = New(repo, &config.JobQueueConfig{StaleAfter: 30 * time.Millisecond})
+	q.Register("long", longJob)
+	q.process(&model.Job{ID: 1, Type: "long", Attempts: 1})
+	assert.Equal(t, 1, repo.extended)
+	assert.Empty(t, repo.marked)
+}
@@ -3,12 +3,17 @@ package middleware
 import (
 	"errors"
 	"github.com/gin-gonic/gin"
+	"github.com/novel/internal/model"
 	"github.com/novel/internal/pkg/response"
 	"github.com/novel/internal/service"
 	"strings"
 )
 
-const CtxUserIDKey = "userID"
+const (
+	CtxUserIDKey    = "userID"
+	CtxUserRoleKey  = "userRole"
+	CtxSessionIDKey = "sessionID"
+)
 
 // AuthMiddleware 创建一个认证中间件
 func AuthMiddleware(userSvc service.UserService) gin.HandlerFunc {
@@ -27,7 +32,8 @@ func AuthMiddleware(userSvc service.UserService) gin.HandlerFunc {
 			return
 		}
 
-		userID, err := userSvc.ParseToken(parts[1])
+		// ParseToken 同时检查令牌是否已被吊销 (登出、刷新令牌被盗用、角色变更)
+		claims, err := userSvc.ParseToken(parts[1])
 		if err != nil {
 			if errors.Is(err, service.ErrInvalidToken) {
 				response.FailWithCode(c, 401, "无效的token")
@@ -38,8 +44,30 @@ func AuthMiddleware(userSvc service.UserService) gin.HandlerFunc {
 			return
 		}
 
-		// 将解析出的 userID 存入 gin.Context，供后续的 Handler 使用
-		c.Set(CtxUserIDKey, userID)
+		// 将解析出的 userID、角色和会话存入 gin.Context，供后续的 Handler 和中间件使用
+		c.Set(CtxUserIDKey, claims.UserID)
+		c.Set(CtxUserRoleKey, claims.Role)
+		c.Set(CtxSessionIDKey, claims.SessionID)
+		c.Next()
+	}
+}
+
+// RequireRole 创建一个角色校验中间件，要求当前用户至少拥有 required 角色
+// 必须挂载在 AuthMiddleware 之后
+func RequireRole(required model.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		roleVal, exists := c.Get(CtxUserRoleKey)
+		if !exists {
+			response.FailWithCode(c, 401, "无法获取用户信息，请重新登录")
+			c.Abort()
+			return
+		}
+		role, ok := roleVal.(model.Role)
+		if !ok || !role.Includes(required) {
+			response.FailWithCode(c, 403, "权限不足")
+			c.Abort()
+			return
+		}
 		c.Next()
 	}
 }
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/novel/internal/pkg/ratelimit"
+	"github.com/novel/internal/pkg/response"
+	"github.com/novel/internal/service"
+	"time"
+)
+
+// ProbationRateLimit 创建一个只对试用期账号生效的限流中间件，action 用于区分不同操作的计数
+// 必须挂载在 AuthMiddleware 之后；limiter 为 nil 时不做限制
+// 只看试用期，不看角色：被提升为编辑的新账号在试用期内同样受限
+func ProbationRateLimit(probation service.ProbationService, limiter *ratelimit.Limiter, action string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if limiter == nil {
+			c.Next()
+			return
+		}
+		userID, exists := c.Get(CtxUserIDKey)
+		if !exists {
+			response.FailWithCode(c, 401, "无法获取用户信息，请重新登录")
+			c.Abort()
+			return
+		}
+		onProbation, err := probation.IsOnProbation(userID.(uint))
+		if err != nil {
+			response.ServerError(c)
+			c.Abort()
+			return
+		}
+		if onProbation {
+			if ok, retryAfter := limiter.Allow(fmt.Sprintf("%s:%d", action, userID), time.Now()); !ok {
+				response.TooManyRequests(c, "新账号操作过于频繁，请稍后再试", retryAfter)
+				c.Abort()
+				return
+			}
+		}
+		c.Next()
+	}
+}
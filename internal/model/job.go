@@ -0,0 +1,42 @@
+package model
+
+import "time"
+
+// JobStatus 定义了后台任务的状态
+type JobStatus string
+
+const (
+	JobStatusPending JobStatus = "pending" // 等待执行 (包括等待重试)
+	JobStatusRunning JobStatus = "running" // 已被某个 worker 领取，正在执行
+	JobStatusDone    JobStatus = "done"    // 执行成功
+	JobStatusDead    JobStatus = "dead"    // 超过最大重试次数，进入死信，需人工处理
+)
+
+// 后台任务类型
+const (
+	JobTypeRatingCreated   = "rating.created"   // 新评分：计算权重、更新作者信誉与小说分数
+	JobTypeRatingEdited    = "rating.edited"    // 评分被编辑：重新计算权重并修正信誉与小说分数
+	JobTypeRatingWithdrawn = "rating.withdrawn" // 评分被撤回：收回信誉奖励并重算小说分数
+	JobTypeRatingVoted     = "rating.voted"     // 评分收到投票：重新计算权重、信誉与小说分数
+
+	JobTypeProbationEnded = "user.probation_ended" // 新账号结束试用期：为其全部评分写入重新计算权重的任务
+
+	JobTypeNovelsRebuildScores = "novels.rebuild_scores" // 全量重算所有小说的评分聚合值 (修复工具)
+)
+
+// Job 是持久化在数据库中的后台任务 (事务性发件箱)
+// 它与触发它的业务数据在同一个事务中写入，因此不会因进程崩溃或重启而丢失
+type Job struct {
+	ID         uint `gorm:"primarykey"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	Type       string     `gorm:"size:64;not null"`
+	Payload    string     `gorm:"type:jsonb;not null"`
+	Status     JobStatus  `gorm:"size:16;not null;default:pending;index:idx_jobs_status_run_at,priority:1"`
+	RunAt      time.Time  `gorm:"not null;index:idx_jobs_status_run_at,priority:2"` // 最早可执行时间，用于实现重试退避
+	Attempts   int        `gorm:"not null;default:0"`                               // 已尝试执行的次数
+	LastError  string     `gorm:"type:text"`
+	LockedBy   string     `gorm:"size:128"` // 领取该任务的 worker 标识
+	LockedAt   *time.Time `gorm:"index"`
+	FinishedAt *time.Time
+}
@@ -1,6 +1,14 @@
 package model
 
-import "gorm.io/gorm"
+import (
+	"github.com/novel/internal/pkg/search"
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"sync"
+	"time"
+)
 
 // PublicationType 定义了出版类型的枚举
 type PublicationType int
@@ -20,7 +28,11 @@ const (
 
 type Novel struct {
 	gorm.Model
-	Title         string `json:"title"`
+	Title        string `json:"title"`
+	TitleSortKey []byte `json:"-" gorm:"index"` // 标题按拼音排序的排序键，由 BeforeSave 维护
+
+	// 全文检索向量，由标题、作者、简介分词后生成，只通过 SQL 读写 (见 search 包)
+	SearchVector  string `json:"-" gorm:"type:tsvector;index:idx_novels_search_vector,type:gin;->:false;<-:false"`
 	Author        string `json:"author"`
 	Description   string `json:"description"`
 	CoverImageURL string `json:"cover_image_url"`
@@ -29,6 +41,18 @@ type Novel struct {
 	WeightedScore float64  `json:"weighted_score" gorm:"index"` // 加权平均分，并添加索引以备排序
 	RatingsCount  int      `json:"ratings_count"`               // 总评分数
 
+	// 检测到疑似刷分时分数被冻结到该时间，期间 WeightedScore 保持不变，由版主处理后提前解除
+	ScoreFrozenUntil *time.Time `json:"score_frozen_until,omitempty"`
+
+	// --- 评分聚合的累计值，随评分变动增量更新，避免每次重新加载全部评分 ---
+	ScoreWeightSum   float64 `json:"-" gorm:"not null;default:0"` // 所有有效评分的权重之和 (v_w)
+	ScoreWeightedSum float64 `json:"-" gorm:"not null;default:0"` // 所有有效评分的 分值×权重 之和
+
+	// --- 各时间窗口的热度排序键，随评分和投票增量更新 (见 trending 包) ---
+	TrendingDay   float64 `json:"-" gorm:"not null;default:0;index"`
+	TrendingWeek  float64 `json:"-" gorm:"not null;default:0;index"`
+	TrendingMonth float64 `json:"-" gorm:"not null;default:0;index"`
+
 	// --- 新增的核心区分字段 ---
 	PublicationType PublicationType `gorm:"not null;index"`
 
@@ -46,3 +70,38 @@ type Novel struct {
 	Category   Category `json:"category"`
 	Tags       []*Tag   `gorm:"many2many:novel_tags;" json:"tags"`
 }
+
+// ScoreFrozen 判断小说的分数在 now 时刻是否处于冻结状态
+func (n *Novel) ScoreFrozen(now time.Time) bool {
+	return n.ScoreFrozenUntil != nil && now.Before(*n.ScoreFrozenUntil)
+}
+
+// BeforeSave 在保存前根据标题重新生成拼音排序键
+func (n *Novel) BeforeSave(tx *gorm.DB) error {
+	n.TitleSortKey = TitleSortKey(n.Title)
+	return nil
+}
+
+// collators 缓存中文排序器，collate.Collator 不能并发使用
+var collators = sync.Pool{
+	New: func() interface{} { return collate.New(language.Chinese) },
+}
+
+// TitleSortKey 生成标题的排序键，按字节比较排序键即可得到按拼音排列的顺序
+// 拉丁字母排在汉字之前，且不区分大小写
+func TitleSortKey(title string) []byte {
+	c := collators.Get().(*collate.Collator)
+	defer collators.Put(c)
+	var buf collate.Buffer
+	key := c.KeyFromString(&buf, title)
+	return append([]byte(nil), key...)
+}
+
+// NovelSearchVector 返回小说全文检索向量的 SQL 表达式，标题权重最高，其次是作者和简介
+func NovelSearchVector(novel *Novel) clause.Expr {
+	return search.VectorExpr(
+		search.Field{Text: novel.Title, Weight: search.WeightA},
+		search.Field{Text: novel.Author, Weight: search.WeightB},
+		search.Field{Text: novel.Description, Weight: search.WeightC},
+	)
+}
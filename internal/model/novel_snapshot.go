@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// NovelSnapshot 是小说在某一天的得分与排名快照，由每日定时任务记录
+// 只为已有评分的小说记录快照，排名只在这些小说之间计算
+type NovelSnapshot struct {
+	ID            uint      `json:"-" gorm:"primarykey"`
+	NovelID       uint      `json:"novel_id" gorm:"not null;uniqueIndex:idx_snapshot_novel_date"`
+	Date          time.Time `json:"date" gorm:"type:date;not null;uniqueIndex:idx_snapshot_novel_date;index"`
+	WeightedScore float64   `json:"weighted_score"`
+	RatingsCount  int       `json:"ratings_count"`
+	Rank          int       `json:"rank"`                     // 全站排名
+	CategoryID    uint      `json:"category_id" gorm:"index"` // 快照时所属的分类
+	CategoryRank  int       `json:"category_rank"`            // 分类内排名
+	CreatedAt     time.Time `json:"-"`
+}
@@ -16,20 +16,31 @@ type RatingVote struct {
 	UserID   uint     `gorm:"uniqueIndex:idx_user_rating"` // 用户ID
 	RatingID uint     `gorm:"uniqueIndex:idx_user_rating"` // 评分ID
 	Vote     VoteType `gorm:"type:smallint"`               // 投票类型 (1 或 -1)
+	// 投票时投票者的信誉分 (试用期内有折减)，取消或改票时按它从评分的赞同/反对信誉分之和中扣除
+	// 该字段加入之前的投票按默认信誉分 1.0 计入
+	VoterTrust float64 `gorm:"not null;default:1"`
 }
 
 type Rating struct {
 	gorm.Model
-	NovelID        uint   `json:"novel_id"`
-	UserID         uint   `json:"user_id"`
+	// 同一用户对同一本小说只能有一条有效评分 (已软删除的评分不参与唯一约束)
+	NovelID        uint   `json:"novel_id" gorm:"uniqueIndex:idx_rating_user_novel,where:deleted_at IS NULL"`
+	UserID         uint   `json:"user_id" gorm:"uniqueIndex:idx_rating_user_novel,where:deleted_at IS NULL"`
 	Score          int    `json:"score"`
 	Comment        string `json:"comment"`
 	UpvotesCount   int    `json:"upvotes_count"`
 	DownvotesCount int    `json:"downvotes_count"`
 
 	// 内部计算字段
-	Weight         float64 `json:"-"`
-	UserTrustScore float64 `json:"-"`
+	Weight         float64  `json:"-"`
+	UserTrustScore float64  `json:"-"`
+	UpvoteTrust    float64  `json:"-" gorm:"not null;default:0"` // 赞同者投票时的信誉分之和，由 community 因子使用
+	DownvoteTrust  float64  `json:"-" gorm:"not null;default:0"` // 反对者投票时的信誉分之和
+	QualityScore   *float64 `json:"-"`                           // 评论质量得分 (0~1)，在创建和编辑时评估
+	CommentHash    string   `json:"-" gorm:"size:32;index"`      // 评论内容指纹，用于识别复制粘贴的评论
+
+	// 被标记为疑似刷分时指向对应的异常事件，可疑评分由 suspicion 因子降权
+	SuspectedIncidentID *uint `json:"-" gorm:"index"`
 
 	// 关联字段：一条评分属于一个用户
 	User User `json:"-"`
@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// BaselineScope 是评分基准的统计范围
+type BaselineScope string
+
+const (
+	BaselineScopeCategory        BaselineScope = "category"         // 按分类统计，ScopeID 为分类ID
+	BaselineScopePublicationType BaselineScope = "publication_type" // 按出版类型统计，ScopeID 为 PublicationType
+	BaselineScopeGlobal          BaselineScope = "global"           // 配置文件中的全站参数
+)
+
+// ScoreBaseline 存放某一范围内小说的 IMDb 公式参数，由定时任务根据实际评分数据计算
+type ScoreBaseline struct {
+	ID          uint          `json:"-" gorm:"primarykey"`
+	Scope       BaselineScope `json:"scope" gorm:"size:32;not null;uniqueIndex:idx_baseline_scope"`
+	ScopeID     uint          `json:"scope_id" gorm:"not null;uniqueIndex:idx_baseline_scope"`
+	C           float64       `json:"c"`            // 该范围内所有评分的加权平均分
+	M           float64       `json:"m"`            // 该范围内小说权重之和 v_w 的分位数
+	NovelsCount int           `json:"novels_count"` // 参与统计的小说数
+	WeightSum   float64       `json:"weight_sum"`   // 参与统计的评分权重之和
+	ComputedAt  time.Time     `json:"computed_at"`
+}
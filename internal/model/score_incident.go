@@ -0,0 +1,40 @@
+package model
+
+import "time"
+
+// IncidentStatus 是评分异常事件的处理状态
+type IncidentStatus string
+
+const (
+	IncidentStatusOpen      IncidentStatus = "open"      // 待版主处理，小说分数冻结中
+	IncidentStatusConfirmed IncidentStatus = "confirmed" // 确认为刷分，可疑评分保持降权
+	IncidentStatusDismissed IncidentStatus = "dismissed" // 误报，可疑评分恢复正常权重
+	IncidentStatusExpired   IncidentStatus = "expired"   // 冻结到期仍无人处理，分数已解冻；已标记的评分保持降权，版主仍可处理
+)
+
+// ScoreIncident 记录一次疑似集中刷分 (review bombing) 事件
+// 同一本小说同时最多只有一个待处理的事件
+type ScoreIncident struct {
+	ID        uint           `json:"id" gorm:"primarykey"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	NovelID   uint           `json:"novel_id" gorm:"not null;index;uniqueIndex:idx_incident_open_novel,where:status = 'open'"`
+	Status    IncidentStatus `json:"status" gorm:"size:16;not null;index"`
+
+	// --- 检测时的统计数据 ---
+	WindowStart     time.Time `json:"window_start"`     // 检测窗口的起点，窗口终点为 created_at
+	RatingsCount    int       `json:"ratings_count"`    // 窗口内的评分数
+	ExpectedCount   float64   `json:"expected_count"`   // 按历史速度，窗口内应有的评分数
+	WindowMean      float64   `json:"window_mean"`      // 窗口内评分的平均分
+	ReferenceMean   float64   `json:"reference_mean"`   // 用于比较的参考分：小说自身的加权平均分，评分不足时为所属分类的基准分
+	Direction       int       `json:"direction"`        // 偏离方向：-1 集中打低分，1 集中打高分
+	LowTrustShare   float64   `json:"low_trust_share"`  // 窗口内低信誉用户的评分占比
+	SuspiciousCount int       `json:"suspicious_count"` // 被标记为可疑的评分数
+
+	FrozenScore float64   `json:"frozen_score"` // 冻结期间展示的分数
+	FrozenUntil time.Time `json:"frozen_until"` // 无人处理时冻结自动解除的时间
+
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+	ResolvedBy *uint      `json:"resolved_by,omitempty"`
+	Note       string     `json:"note,omitempty" gorm:"type:text"` // 版主的处理说明
+}
@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+// RefreshToken 是服务端保存的刷新令牌，只保存令牌的 SHA-256 哈希
+// 每次刷新都会作废旧令牌并签发新令牌 (轮换)，同一次登录产生的令牌属于同一个会话 SessionID
+type RefreshToken struct {
+	ID              uint `gorm:"primarykey"`
+	CreatedAt       time.Time
+	UserID          uint       `gorm:"not null;index"`
+	SessionID       string     `gorm:"size:36;not null;index"`
+	TokenHash       string     `gorm:"size:64;not null;uniqueIndex"`
+	ExpiresAt       time.Time  `gorm:"not null;index"`
+	AccessJTI       string     `gorm:"size:36;not null"` // 与该刷新令牌一起签发的访问令牌
+	AccessExpiresAt time.Time  `gorm:"not null"`
+	RotatedAt       *time.Time // 已被用于刷新，再次出现即视为被盗用
+	RevokedAt       *time.Time // 登出或检测到重用后作废
+}
+
+// RevokedToken 是被吊销的访问令牌，AuthMiddleware 在令牌过期前拒绝它们
+type RevokedToken struct {
+	JTI       string    `gorm:"size:36;primarykey"`
+	UserID    uint      `gorm:"not null"`
+	ExpiresAt time.Time `gorm:"not null;index"` // 访问令牌本身的过期时间，之后记录可以清理
+	CreatedAt time.Time
+}
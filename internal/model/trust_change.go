@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+// TrustChangeReason 是信誉分变动的原因
+type TrustChangeReason string
+
+const (
+	TrustReasonRatingCreated   TrustChangeReason = "rating_created"   // 发表评分获得的奖励
+	TrustReasonRatingEdited    TrustChangeReason = "rating_edited"    // 评分被编辑或重新评估后，奖励随权重变化
+	TrustReasonRatingWithdrawn TrustChangeReason = "rating_withdrawn" // 撤回评分，收回当初的奖励
+	TrustReasonVoteReceived    TrustChangeReason = "vote_received"    // 自己的评分收到赞同或反对
+	TrustReasonVoteCast        TrustChangeReason = "vote_cast"        // 参与投票的奖励
+	TrustReasonPenalty         TrustChangeReason = "penalty"          // 投票分析判定为刷赞或马甲的惩罚及其解除
+	TrustReasonRecalculation   TrustChangeReason = "recalculation"    // 定时全量重算的修正
+	TrustReasonDecay           TrustChangeReason = "decay"            // 长期不活跃，向中性值回落
+)
+
+// TrustChange 是信誉分变动的流水记录，用于向用户解释信誉分的来源
+type TrustChange struct {
+	ID         uint              `json:"id" gorm:"primarykey"`
+	CreatedAt  time.Time         `json:"created_at"`
+	UserID     uint              `json:"-" gorm:"not null;index"`
+	Reason     TrustChangeReason `json:"reason" gorm:"size:32;not null"`
+	Delta      float64           `json:"delta"`               // 实际变动值 (已计入上下限的截断)
+	ScoreAfter float64           `json:"score_after"`         // 变动后的信誉分
+	RatingID   *uint             `json:"rating_id,omitempty"` // 引起变动的评分
+	VoterID    *uint             `json:"-"`                   // 收到投票时的投票者，不对评分作者公开
+}
@@ -1,11 +1,77 @@
 package model
 
-import "gorm.io/gorm"
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+// Role 定义了用户角色，角色之间按 reader < editor < moderator < admin 逐级包含
+type Role string
+
+const (
+	RoleReader    Role = "reader"    // 普通读者：评分、投票
+	RoleEditor    Role = "editor"    // 编辑：维护小说信息
+	RoleModerator Role = "moderator" // 版主：删除与恢复内容
+	RoleAdmin     Role = "admin"     // 管理员：管理用户角色
+)
+
+// roleLevels 定义了角色的层级，数值越大权限越高
+var roleLevels = map[Role]int{
+	RoleReader:    1,
+	RoleEditor:    2,
+	RoleModerator: 3,
+	RoleAdmin:     4,
+}
+
+// IsValid 判断角色是否是已定义的角色
+func (r Role) IsValid() bool {
+	_, ok := roleLevels[r]
+	return ok
+}
+
+// Includes 判断当前角色是否拥有 required 角色的全部权限
+func (r Role) Includes(required Role) bool {
+	return r.IsValid() && roleLevels[r] >= roleLevels[required]
+}
+
+// TrustTier 是对外展示的信誉等级，避免直接暴露信誉分的具体数值
+type TrustTier string
+
+const (
+	TrustTierLow     TrustTier = "low"     // 信誉偏低
+	TrustTierRegular TrustTier = "regular" // 普通用户
+	TrustTierTrusted TrustTier = "trusted" // 受信任的用户
+	TrustTierExpert  TrustTier = "expert"  // 资深用户
+)
+
+// TrustTierOf 根据信誉分 (取值范围 0.8 ~ 1.5，初始为 1.0) 计算信誉等级
+func TrustTierOf(trustScore float64) TrustTier {
+	switch {
+	case trustScore < 0.95:
+		return TrustTierLow
+	case trustScore < 1.15:
+		return TrustTierRegular
+	case trustScore < 1.35:
+		return TrustTierTrusted
+	default:
+		return TrustTierExpert
+	}
+}
 
 type User struct {
 	gorm.Model
 	Username     string   `gorm:"size:32;unique;not null"`
 	PasswordHash string   `gorm:"size:255;not null"`
 	TrustScore   float64  `gorm:"default:1.0"`
+	TrustPenalty float64  `json:"-" gorm:"not null;default:0"` // 投票分析判定为刷赞或马甲时的信誉分惩罚，全量重算信誉分时同样扣除
+	Role         Role     `gorm:"size:16;not null;default:reader"`
 	Ratings      []Rating `json:"-"`
+
+	// 结束新账号试用期的时间，为空表示仍在试用期 (或尚未按试用期规则评估过)
+	ProbationEndedAt *time.Time `json:"-"`
+	// 最近一次因不活跃而衰减信誉分的时间，下一次衰减从这里开始计算
+	TrustDecayedAt *time.Time `json:"-"`
+	// 用户最近一次主动操作 (发表、编辑、撤回评分或投票) 的时间，为空时以注册时间为准
+	// 他人的投票、权重重算等只修改评分行的流程不会更新它
+	LastActiveAt *time.Time `json:"-"`
 }
@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// VoteRingReport 记录一次互赞团体与马甲账号分析的结果，由定时任务离线生成
+type VoteRingReport struct {
+	ID               uint      `json:"id" gorm:"primarykey"`
+	CreatedAt        time.Time `json:"created_at"`
+	VotesAnalyzed    int       `json:"votes_analyzed"`    // 参与分析的投票数
+	Voters           int       `json:"voters"`            // 参与分析的投票者数
+	FlaggedClusters  int       `json:"flagged_clusters"`  // 判定为刷赞团体的数量
+	SockpuppetGroups int       `json:"sockpuppet_groups"` // 疑似马甲账号组的数量
+	Suspects         int       `json:"suspects"`          // 可疑账号数
+	PenaltiesApplied bool      `json:"penalties_applied"` // 是否已按本次结果调整信誉分
+	Report           string    `json:"-" gorm:"type:jsonb;not null"`
+}
@@ -3,19 +3,35 @@ package config
 import (
 	"fmt"
 	"github.com/spf13/viper"
+	"time"
 )
 
 type Config struct {
-	Database  DatabaseConfig  `mapstructure:"database"`
-	Logger    LogConfig       `mapstructure:"logger"`
-	Server    ServerConfig    `mapstructure:"server"`
-	Algorithm AlgorithmConfig `mapstructure:"algorithm"`
-	JWT       JWTConfig       `mapstructure:"jwt"`
+	Database   DatabaseConfig   `mapstructure:"database"`
+	Logger     LogConfig        `mapstructure:"logger"`
+	Server     ServerConfig     `mapstructure:"server"`
+	Algorithm  AlgorithmConfig  `mapstructure:"algorithm"`
+	JWT        JWTConfig        `mapstructure:"jwt"`
+	JobQueue   JobQueueConfig   `mapstructure:"job_queue"`
+	Scheduler  SchedulerConfig  `mapstructure:"scheduler"`
+	Pagination PaginationConfig `mapstructure:"pagination"`
 }
 
 type JWTConfig struct {
-	SecretKey  string `mapstructure:"secret_key"`
-	ExpiryTime string `mapstructure:"expiry_time"`
+	SecretKey         string         `mapstructure:"secret_key"`          // 未配置 keys 时用于 HS256 签名
+	ExpiryTime        string         `mapstructure:"expiry_time"`         // 访问令牌的有效期
+	RefreshExpiryTime string         `mapstructure:"refresh_expiry_time"` // 刷新令牌的有效期
+	SigningKeyID      string         `mapstructure:"signing_key_id"`      // 签发令牌所用密钥的 kid
+	Keys              []JWTKeyConfig `mapstructure:"keys"`                // 非对称密钥，轮换期间新旧密钥同时存在
+}
+
+// JWTKeyConfig 是一个从本地 PEM 文件加载的签名密钥
+// 只配置公钥的密钥仅用于校验，通常是已轮换下来、仍有未过期令牌的旧密钥
+type JWTKeyConfig struct {
+	ID             string `mapstructure:"kid"`
+	Algorithm      string `mapstructure:"algorithm"` // RS256 或 EdDSA
+	PrivateKeyFile string `mapstructure:"private_key_file"`
+	PublicKeyFile  string `mapstructure:"public_key_file"`
 }
 
 type ServerConfig struct {
@@ -43,8 +59,136 @@ type LogConfig struct {
 
 // AlgorithmConfig 存放算法相关参数
 type AlgorithmConfig struct {
-	ImdbM float64 `mapstructure:"imdb_m"`
-	ImdbC float64 `mapstructure:"imdb_c"`
+	ImdbM         float64              `mapstructure:"imdb_m"`
+	ImdbC         float64              `mapstructure:"imdb_c"`
+	WeightFactors []WeightFactorConfig `mapstructure:"weight_factors"` // 评分权重因子，按顺序相乘；为空时使用默认因子
+	Baselines     BaselineConfig       `mapstructure:"baselines"`      // 按分类、出版类型统计的 IMDb 公式参数
+	Trending      TrendingConfig       `mapstructure:"trending"`       // 热度榜参数
+	ReviewBombing ReviewBombingConfig  `mapstructure:"review_bombing"` // 集中刷分检测参数
+	VoteRings     VoteRingConfig       `mapstructure:"vote_rings"`     // 互赞团体与马甲账号分析参数
+	Probation     ProbationConfig      `mapstructure:"probation"`      // 新账号试用期参数
+	TrustDecay    TrustDecayConfig     `mapstructure:"trust_decay"`    // 不活跃用户的信誉分衰减参数
+}
+
+// TrustDecayConfig 存放信誉分衰减的参数
+// 用户超过 inactive_after 没有评分或投票后，信誉分与中性值的差距按半衰期指数缩小
+type TrustDecayConfig struct {
+	Enabled       bool          `mapstructure:"enabled"`
+	InactiveAfter time.Duration `mapstructure:"inactive_after"` // 无活动多久之后开始衰减
+	HalfLife      time.Duration `mapstructure:"half_life"`      // 与中性值的差距减半所需的时长
+	Neutral       float64       `mapstructure:"neutral"`        // 中性值，即新用户的初始信誉分；有惩罚的用户回落到中性值减去惩罚
+}
+
+// ProbationConfig 存放新账号试用期的参数
+// 账号注册时长、评分数与收到的赞同票数全部达标后结束试用期，之后不再重新进入
+type ProbationConfig struct {
+	Enabled            bool            `mapstructure:"enabled"`
+	MinAccountAge      time.Duration   `mapstructure:"min_account_age"`      // 注册时长下限
+	MinRatings         int             `mapstructure:"min_ratings"`          // 有效评分数下限
+	MinUpvotesReceived int             `mapstructure:"min_upvotes_received"` // 评分收到的赞同票数下限
+	VoteWeight         float64         `mapstructure:"vote_weight"`          // 试用期内投票计入社区认可度时乘以的系数，评分的降权由 probation 权重因子负责
+	RateLimits         RateLimitConfig `mapstructure:"rate_limits"`          // 试用期内的操作频率限制
+}
+
+// RateLimitConfig 存放各操作的频率限制
+type RateLimitConfig struct {
+	Vote        RateLimitRule `mapstructure:"vote"`         // 对评分投票
+	NovelCreate RateLimitRule `mapstructure:"novel_create"` // 创建小说
+}
+
+// RateLimitRule 限制 window 时长内最多执行 limit 次，limit 为 0 表示不限制
+type RateLimitRule struct {
+	Limit  int           `mapstructure:"limit"`
+	Window time.Duration `mapstructure:"window"`
+}
+
+// VoteRingConfig 存放互赞团体与马甲账号离线分析的参数，未设置 (为 0) 的参数使用默认值
+type VoteRingConfig struct {
+	ApplyPenalties      bool    `mapstructure:"apply_penalties"`      // 是否按分析结果扣减可疑账号的信誉分，关闭时只生成报告
+	MinMutualUpvotes    int     `mapstructure:"min_mutual_upvotes"`   // 两个账号互相至少投出多少张赞同票才算互赞
+	MinInternalShare    float64 `mapstructure:"min_internal_share"`   // 团体成员收到的赞同票中来自团体内部的最低占比
+	MinVotesSimilarity  int     `mapstructure:"min_votes_similarity"` // 参与投票相似度比较的账号至少投过多少张票
+	SimilarityThreshold float64 `mapstructure:"similarity_threshold"` // 判定为马甲的投票 Jaccard 相似度下限
+	MaxCoVoters         int     `mapstructure:"max_co_voters"`        // 投票人数超过该值的评分不参与相似度计算
+	ClusterPenalty      float64 `mapstructure:"cluster_penalty"`      // 刷赞团体成员的信誉分惩罚
+	SimilarityPenalty   float64 `mapstructure:"similarity_penalty"`   // 马甲账号的信誉分惩罚
+	MaxPenalty          float64 `mapstructure:"max_penalty"`          // 单个账号惩罚的上限
+}
+
+// ReviewBombingConfig 存放集中刷分检测的参数
+// 窗口内的评分同时满足数量、速度、偏离与低信誉占比四个条件时判定为疑似刷分
+type ReviewBombingConfig struct {
+	Enabled            bool          `mapstructure:"enabled"`
+	Window             time.Duration `mapstructure:"window"`               // 检测窗口
+	MinRatings         int           `mapstructure:"min_ratings"`          // 窗口内至少有多少条评分
+	HistoryPeriod      time.Duration `mapstructure:"history_period"`       // 计算正常评分速度时回溯的时长
+	VelocityMultiplier float64       `mapstructure:"velocity_multiplier"`  // 窗口内评分数至少是正常速度的多少倍
+	SkewThreshold      float64       `mapstructure:"skew_threshold"`       // 窗口平均分与参考分至少相差多少
+	MinReferenceWeight float64       `mapstructure:"min_reference_weight"` // 小说的 v_w 低于该值时以所属分类的基准分为参考分
+	LowTrustScore      float64       `mapstructure:"low_trust_score"`      // 信誉分低于该值视为低信誉用户
+	LowTrustShare      float64       `mapstructure:"low_trust_share"`      // 窗口内低信誉用户评分的最低占比
+	FreezeFor          time.Duration `mapstructure:"freeze_for"`           // 分数冻结的时长，版主处理后提前解除
+}
+
+// TrendingConfig 存放热度计算的参数，热度按半衰期指数衰减
+type TrendingConfig struct {
+	DayHalfLife    time.Duration `mapstructure:"day_half_life"`   // 日榜的半衰期
+	WeekHalfLife   time.Duration `mapstructure:"week_half_life"`  // 周榜的半衰期
+	MonthHalfLife  time.Duration `mapstructure:"month_half_life"` // 月榜的半衰期
+	RatingWeight   float64       `mapstructure:"rating_weight"`   // 一条新评分计入的热度
+	UpvoteWeight   float64       `mapstructure:"upvote_weight"`   // 评分收到一次赞同计入的热度
+	DownvoteWeight float64       `mapstructure:"downvote_weight"` // 评分收到一次反对计入的热度
+}
+
+// BaselineConfig 存放分组评分基准的参数
+type BaselineConfig struct {
+	Enabled      bool          `mapstructure:"enabled"`        // 关闭时所有小说使用全站的 imdb_m / imdb_c
+	MinNovels    int           `mapstructure:"min_novels"`     // 分组内至少需要多少本有评分的小说
+	MinWeightSum float64       `mapstructure:"min_weight_sum"` // 分组内评分权重之和的下限
+	MPercentile  float64       `mapstructure:"m_percentile"`   // 以分组内 v_w 的该分位数作为 m，为 0 时沿用 imdb_m
+	CacheTTL     time.Duration `mapstructure:"cache_ttl"`      // 基准在内存中的缓存时长
+}
+
+// WeightFactorConfig 是单个评分权重因子的配置
+type WeightFactorConfig struct {
+	Name    string             `mapstructure:"name"`
+	Enabled *bool              `mapstructure:"enabled"` // 未设置时视为启用
+	Params  map[string]float64 `mapstructure:"params"`  // 未设置的参数使用因子的默认值
+}
+
+// JobQueueConfig 存放后台任务队列的参数
+type JobQueueConfig struct {
+	Workers      int           `mapstructure:"workers"`       // 并发执行任务的 worker 数量
+	PollInterval time.Duration `mapstructure:"poll_interval"` // 队列为空时的轮询间隔
+	MaxAttempts  int           `mapstructure:"max_attempts"`  // 最大尝试次数，超过后进入死信
+	BaseBackoff  time.Duration `mapstructure:"base_backoff"`  // 首次重试的等待时间，之后指数增长
+	MaxBackoff   time.Duration `mapstructure:"max_backoff"`   // 重试等待时间的上限
+	StaleAfter   time.Duration `mapstructure:"stale_after"`   // 执行中的任务超过该时间未续租，视为 worker 已失效并重新入队
+	Retention    time.Duration `mapstructure:"retention"`     // 成功任务的保留时长
+}
+
+// SchedulerConfig 存放进程内定时任务的参数
+type SchedulerConfig struct {
+	Enabled            bool           `mapstructure:"enabled"`
+	TrustRecalculation CronTaskConfig `mapstructure:"trust_recalculation"` // 全量重算用户信誉分
+	BaselineRefresh    CronTaskConfig `mapstructure:"baseline_refresh"`    // 重新统计评分基准并刷新所有小说的得分
+	NovelSnapshot      CronTaskConfig `mapstructure:"novel_snapshot"`      // 记录每日的得分与排名快照
+	VoteRingAnalysis   CronTaskConfig `mapstructure:"vote_ring_analysis"`  // 分析互赞团体与马甲账号
+	TrustDecay         CronTaskConfig `mapstructure:"trust_decay"`         // 衰减不活跃用户的信誉分
+	TokenCleanup       CronTaskConfig `mapstructure:"token_cleanup"`       // 清理过期的刷新令牌与吊销记录
+	IncidentExpiry     CronTaskConfig `mapstructure:"incident_expiry"`     // 解除冻结已到期的评分异常事件
+}
+
+// CronTaskConfig 是单个定时任务的配置
+type CronTaskConfig struct {
+	Enabled   bool   `mapstructure:"enabled"`
+	Cron      string `mapstructure:"cron"`       // cron 表达式，如 "0 4 * * *" 或 "@every 6h"
+	BatchSize int    `mapstructure:"batch_size"` // 每批处理的记录数
+}
+
+// PaginationConfig 存放分页相关的参数
+type PaginationConfig struct {
+	CursorSecret string `mapstructure:"cursor_secret"` // 分页游标的签名密钥，为空时使用 JWT 密钥
 }
 
 // 全局配置变量
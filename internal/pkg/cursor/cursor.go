@@ -0,0 +1,63 @@
+package cursor
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// ErrInvalidCursor 表示游标格式错误、签名不匹配或已不适用于当前查询
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// signatureLen 是签名截断后的字节数
+const signatureLen = 16
+
+// Codec 负责游标的编码与校验
+// 游标是 base64url(JSON) + "." + base64url(HMAC-SHA256)，对客户端不透明，且无法被篡改
+type Codec struct {
+	secret []byte
+}
+
+// NewCodec 使用给定的密钥创建游标编解码器
+func NewCodec(secret string) *Codec {
+	return &Codec{secret: []byte(secret)}
+}
+
+// Encode 将 v 序列化并签名为游标字符串
+func (c *Codec) Encode(v interface{}) (string, error) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(c.sign(encoded)), nil
+}
+
+// Decode 校验游标签名并将其内容反序列化到 v，任何错误都返回 ErrInvalidCursor
+func (c *Codec) Decode(token string, v interface{}) error {
+	encoded, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return ErrInvalidCursor
+	}
+	gotSig, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(gotSig, c.sign(encoded)) {
+		return ErrInvalidCursor
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return ErrInvalidCursor
+	}
+	if err := json.Unmarshal(payload, v); err != nil {
+		return ErrInvalidCursor
+	}
+	return nil
+}
+
+func (c *Codec) sign(encoded string) []byte {
+	mac := hmac.New(sha256.New, c.secret)
+	mac.Write([]byte(encoded))
+	return mac.Sum(nil)[:signatureLen]
+}
@@ -0,0 +1,36 @@
+package cursor
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+type testPayload struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    uint   `json:"id"`
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	c := NewCodec("secret")
+	token, err := c.Encode(testPayload{Sort: "score", Value: "8.25", ID: 42})
+	require.NoError(t, err)
+
+	var got testPayload
+	require.NoError(t, c.Decode(token, &got))
+	assert.Equal(t, testPayload{Sort: "score", Value: "8.25", ID: 42}, got)
+}
+
+func TestCodecRejectsTampering(t *testing.T) {
+	c := NewCodec("secret")
+	token, err := c.Encode(testPayload{Sort: "score", Value: "8.25", ID: 42})
+	require.NoError(t, err)
+	forged, err := NewCodec("other").Encode(testPayload{Sort: "score", Value: "0", ID: 1})
+	require.NoError(t, err)
+
+	var got testPayload
+	for _, bad := range []string{"", "abc", token + "x", "x" + token, forged} {
+		assert.ErrorIs(t, c.Decode(bad, &got), ErrInvalidCursor, bad)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/novel/internal/pkg/logger"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"time"
 )
 
 var db *gorm.DB
@@ -21,12 +22,22 @@ func InitDB(cfg *config.DatabaseConfig) (*gorm.DB, error) {
 		cfg.Port,
 		cfg.SSLMode,
 	)
-	db, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	// TranslateError 会把唯一约束冲突等数据库错误转换为 gorm.ErrDuplicatedKey 等通用错误
+	db, err = gorm.Open(postgres.Open(dsn), &gorm.Config{TranslateError: true})
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 	logger.InfoRaw("Database connection initialized")
 
+	// 唯一索引要求每个用户对每本小说只有一条有效评分，建索引之前先清理历史数据中的重复评分
+	dedupedRatings, err := dedupeRatings(db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to remove duplicate ratings: %w", err)
+	}
+
+	// 试用期字段加入之前注册的用户不进入试用期
+	grandfatherProbation := db.Migrator().HasTable(&model.User{}) && !db.Migrator().HasColumn(&model.User{}, "ProbationEndedAt")
+
 	err = db.AutoMigrate(
 		&model.Novel{},
 		&model.Rating{},
@@ -34,17 +45,228 @@ func InitDB(cfg *config.DatabaseConfig) (*gorm.DB, error) {
 		&model.User{},
 		&model.Category{},
 		&model.Tag{},
+		&model.Job{},
+		&model.ScoreBaseline{},
+		&model.NovelSnapshot{},
+		&model.ScoreIncident{},
+		&model.VoteRingReport{},
+		&model.TrustChange{},
+		&model.RefreshToken{},
+		&model.RevokedToken{},
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to migrate projects: %w", err)
 	}
 	logger.InfoRaw("Database migration complete")
+
+	if dedupedRatings > 0 {
+		if err := enqueueScoreRebuild(db); err != nil {
+			return nil, fmt.Errorf("failed to schedule score rebuild: %w", err)
+		}
+	}
+	if grandfatherProbation {
+		if err := endProbationForExistingUsers(db); err != nil {
+			return nil, fmt.Errorf("failed to end probation for existing users: %w", err)
+		}
+	}
+
+	if err := backfillTitleSortKeys(db); err != nil {
+		return nil, fmt.Errorf("failed to backfill title sort keys: %w", err)
+	}
+	if err := backfillSearchVectors(db); err != nil {
+		return nil, fmt.Errorf("failed to backfill search vectors: %w", err)
+	}
+	if err := backfillVoteTrustSums(db); err != nil {
+		return nil, fmt.Errorf("failed to backfill vote trust sums: %w", err)
+	}
+	if err := backfillScoreAggregates(db); err != nil {
+		return nil, fmt.Errorf("failed to backfill score aggregates: %w", err)
+	}
+	if err := backfillLastActiveAt(db); err != nil {
+		return nil, fmt.Errorf("failed to backfill user activity: %w", err)
+	}
 	return db, nil
 }
 
+// backfillTitleSortKeys 为新增排序键字段之前创建的小说补齐拼音排序键
+func backfillTitleSortKeys(db *gorm.DB) error {
+	var novels []model.Novel
+	return db.Unscoped().Select("id", "title").Where("title_sort_key IS NULL").
+		FindInBatches(&novels, 200, func(tx *gorm.DB, batch int) error {
+			for _, n := range novels {
+				err := db.Unscoped().Model(&model.Novel{}).Where("id = ?", n.ID).
+					UpdateColumn("title_sort_key", model.TitleSortKey(n.Title)).Error
+				if err != nil {
+					return err
+				}
+			}
+			logger.Infof("Backfilled title sort keys for %d novels", len(novels))
+			return nil
+		}).Error
+}
+
 func GetDB() *gorm.DB {
 	if db == nil {
 		panic("Database is not initialized")
 	}
 	return db
 }
+
+// backfillSearchVectors 为新增全文检索字段之前创建的小说生成检索向量
+func backfillSearchVectors(db *gorm.DB) error {
+	var novels []model.Novel
+	return db.Unscoped().Select("id", "title", "author", "description").Where("search_vector IS NULL").
+		FindInBatches(&novels, 200, func(tx *gorm.DB, batch int) error {
+			for i := range novels {
+				err := db.Unscoped().Model(&model.Novel{}).Where("id = ?", novels[i].ID).
+					UpdateColumn("search_vector", model.NovelSearchVector(&novels[i])).Error
+				if err != nil {
+					return err
+				}
+			}
+			logger.Infof("Backfilled search vectors for %d novels", len(novels))
+			return nil
+		}).Error
+}
+
+// backfillVoteTrustSums 为新增信誉分之和字段之前已有投票的评分补齐赞同/反对信誉分之和
+// 这些投票的 voter_trust 为默认值 1.0，补齐后的值与原来的投票数相同，评分权重不受影响
+func backfillVoteTrustSums(db *gorm.DB) error {
+	result := db.Exec(`
+		UPDATE ratings SET
+			upvote_trust = v.up,
+			downvote_trust = v.down
+		FROM (
+			SELECT rating_id,
+				COALESCE(SUM(voter_trust) FILTER (WHERE vote = 1), 0) AS up,
+				COALESCE(SUM(voter_trust) FILTER (WHERE vote = -1), 0) AS down
+			FROM rating_votes
+			WHERE deleted_at IS NULL
+			GROUP BY rating_id
+		) v
+		WHERE ratings.id = v.rating_id
+			AND ratings.upvote_trust = 0 AND ratings.downvote_trust = 0
+			AND (ratings.upvotes_count > 0 OR ratings.downvotes_count > 0)`)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		logger.Infof("Backfilled vote trust sums for %d ratings", result.RowsAffected)
+	}
+	return nil
+}
+
+// backfillScoreAggregates 为新增评分聚合字段之前已有评分的小说补齐 Σ权重 与 Σ(分值×权重)
+// 最终得分依赖评分基准，由补齐后提交的全量重算任务重新计算；补齐在服务启动前同步完成，
+// 因此之后的增量更新都建立在正确的聚合值之上
+func backfillScoreAggregates(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Exec(`
+			UPDATE novels SET
+				ratings_count = r.count,
+				score_weight_sum = r.weight_sum,
+				score_weighted_sum = r.weighted_sum
+			FROM (
+				SELECT novel_id,
+					COUNT(*) AS count,
+					SUM(weight) AS weight_sum,
+					SUM(score * weight) AS weighted_sum
+				FROM ratings
+				WHERE deleted_at IS NULL
+				GROUP BY novel_id
+				HAVING SUM(weight) > 0
+			) r
+			WHERE novels.id = r.novel_id
+				AND novels.score_weight_sum = 0 AND novels.score_weighted_sum = 0`)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		logger.Infof("Backfilled score aggregates for %d novels", result.RowsAffected)
+		return enqueueScoreRebuild(tx)
+	})
+}
+
+// enqueueScoreRebuild 提交一个对全部小说全量重算评分聚合值与最终得分的后台任务，已有待执行的同类任务时不重复提交
+func enqueueScoreRebuild(db *gorm.DB) error {
+	var pending int64
+	err := db.Model(&model.Job{}).
+		Where("type = ? AND status = ?", model.JobTypeNovelsRebuildScores, model.JobStatusPending).
+		Count(&pending).Error
+	if err != nil || pending > 0 {
+		return err
+	}
+	return db.Create(&model.Job{
+		Type:    model.JobTypeNovelsRebuildScores,
+		Payload: "{}",
+		Status:  model.JobStatusPending,
+		RunAt:   time.Now(),
+	}).Error
+}
+
+// dedupeRatings 在创建 (user_id, novel_id) 唯一索引之前，软删除同一用户对同一本小说的重复评分，只保留最新的一条
+// 被删除评分收到的投票一并软删除，与用户撤回评分的处理一致；返回被删除的评分数
+func dedupeRatings(db *gorm.DB) (int64, error) {
+	m := db.Migrator()
+	if !m.HasTable(&model.Rating{}) || !m.HasTable(&model.RatingVote{}) || m.HasIndex(&model.Rating{}, "idx_rating_user_novel") {
+		return 0, nil
+	}
+	var deleted int64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		deletedAt := time.Now()
+		result := tx.Exec(`
+			UPDATE ratings SET deleted_at = ?
+			WHERE id IN (
+				SELECT id FROM (
+					SELECT id, ROW_NUMBER() OVER (PARTITION BY user_id, novel_id ORDER BY created_at DESC, id DESC) AS rn
+					FROM ratings
+					WHERE deleted_at IS NULL
+				) d
+				WHERE d.rn > 1
+			)`, deletedAt)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		deleted = result.RowsAffected
+		return tx.Exec(`
+			UPDATE rating_votes SET deleted_at = ?
+			WHERE deleted_at IS NULL AND rating_id IN (SELECT id FROM ratings WHERE deleted_at = ?)`,
+			deletedAt, deletedAt).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	if deleted > 0 {
+		logger.Infof("Soft-deleted %d duplicate ratings before creating the unique index", deleted)
+	}
+	return deleted, nil
+}
+
+// backfillLastActiveAt 为新增活跃时间字段之前的用户补齐最近一次活跃的时间
+// 评分以创建时间为准：评分行的 updated_at 还会被他人的投票和权重重算修改，不能代表作者本人的活动
+func backfillLastActiveAt(db *gorm.DB) error {
+	result := db.Exec(`
+		UPDATE users SET last_active_at = GREATEST(
+			users.created_at,
+			(SELECT MAX(created_at) FROM ratings WHERE ratings.user_id = users.id),
+			(SELECT MAX(updated_at) FROM rating_votes WHERE rating_votes.user_id = users.id)
+		)
+		WHERE last_active_at IS NULL`)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		logger.Infof("Backfilled last activity time for %d users", result.RowsAffected)
+	}
+	return nil
+}
+
+// endProbationForExistingUsers 在新增试用期字段时，把已有用户的试用期结束时间记为注册时间
+// 试用期只针对新注册的账号，已有的老用户即使只投票、不评分也不应受试用期的降权和频率限制
+func endProbationForExistingUsers(db *gorm.DB) error {
+	result := db.Exec("UPDATE users SET probation_ended_at = created_at WHERE probation_ended_at IS NULL")
+	if result.Error != nil {
+		return result.Error
+	}
+	logger.Infof("Ended probation for %d existing users", result.RowsAffected)
+	return nil
+}
@@ -0,0 +1,39 @@
+package db
+
+import (
+	"context"
+	"github.com/novel/internal/pkg/logger"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// TryAdvisoryLock 尝试获取一个以 name 标识的 PostgreSQL 会话级 advisory lock
+// 会话级锁与数据库连接绑定，因此这里会独占一个连接直到调用返回的 unlock 函数
+func TryAdvisoryLock(ctx context.Context, gdb *gorm.DB, name string) (unlock func(), acquired bool, err error) {
+	sqlDB, err := gdb.DB()
+	if err != nil {
+		return nil, false, err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock(hashtext($1))", name).Scan(&acquired); err != nil {
+		_ = conn.Close()
+		return nil, false, err
+	}
+	if !acquired {
+		_ = conn.Close()
+		return nil, false, nil
+	}
+
+	unlock = func() {
+		// 使用独立的 context，保证即使任务因关闭被取消也能释放锁
+		if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock(hashtext($1))", name); err != nil {
+			logger.ErrorRaw("Failed to release advisory lock", zap.String("name", name), zap.Error(err))
+		}
+		_ = conn.Close()
+	}
+	return unlock, true, nil
+}
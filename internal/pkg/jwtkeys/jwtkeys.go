@@ -0,0 +1,208 @@
+// Package jwtkeys 管理签发与校验 JWT 所用的密钥
+//
+// 配置了非对称密钥 (RS256 / EdDSA) 时，令牌用 signing_key_id 指定的私钥签名并在头部写入 kid，
+// 校验时按 kid 查找公钥。轮换密钥时先加入新密钥、切换 signing_key_id，旧密钥只保留公钥，
+// 待用它签发的访问令牌全部过期后再移除。公钥通过 JWKS 发布，其他服务无需共享密钥即可校验令牌。
+// 未配置任何密钥时退回到使用 secret_key 的 HS256。
+package jwtkeys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/novel/internal/pkg/config"
+	"math/big"
+	"os"
+	"sort"
+)
+
+// minRSABits 是 RSA 密钥的最小长度
+const minRSABits = 2048
+
+// ErrUnknownKey 表示令牌的 kid 或签名算法与已配置的密钥不符
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// key 是一个已加载的密钥，private 为空时只能用于校验
+type key struct {
+	id      string
+	method  jwt.SigningMethod
+	private crypto.Signer
+	public  crypto.PublicKey
+}
+
+// KeySet 是当前生效的全部密钥
+type KeySet struct {
+	signing *key
+	keys    map[string]*key
+	secret  []byte // 未配置非对称密钥时使用的 HS256 密钥
+}
+
+// Load 按配置加载密钥
+func Load(cfg *config.JWTConfig) (*KeySet, error) {
+	if len(cfg.Keys) == 0 {
+		if cfg.SecretKey == "" {
+			return nil, errors.New("jwt: secret_key or keys must be configured")
+		}
+		return &KeySet{secret: []byte(cfg.SecretKey)}, nil
+	}
+
+	ks := &KeySet{keys: make(map[string]*key, len(cfg.Keys))}
+	for _, kc := range cfg.Keys {
+		if kc.ID == "" {
+			return nil, errors.New("jwt: key without kid")
+		}
+		if _, dup := ks.keys[kc.ID]; dup {
+			return nil, fmt.Errorf("jwt: duplicate kid %q", kc.ID)
+		}
+		k, err := loadKey(kc)
+		if err != nil {
+			return nil, fmt.Errorf("jwt: key %q: %w", kc.ID, err)
+		}
+		ks.keys[kc.ID] = k
+	}
+
+	signing, ok := ks.keys[cfg.SigningKeyID]
+	if !ok {
+		return nil, fmt.Errorf("jwt: signing_key_id %q does not match any key", cfg.SigningKeyID)
+	}
+	if signing.private == nil {
+		return nil, fmt.Errorf("jwt: signing key %q has no private_key_file", cfg.SigningKeyID)
+	}
+	ks.signing = signing
+	return ks, nil
+}
+
+// loadKey 读取 PEM 文件，同时配置私钥与公钥时检查两者是否匹配
+func loadKey(kc config.JWTKeyConfig) (*key, error) {
+	k := &key{id: kc.ID}
+	switch kc.Algorithm {
+	case "RS256":
+		k.method = jwt.SigningMethodRS256
+	case "EdDSA":
+		k.method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q", kc.Algorithm)
+	}
+	if kc.PrivateKeyFile == "" && kc.PublicKeyFile == "" {
+		return nil, errors.New("private_key_file or public_key_file is required")
+	}
+
+	if kc.PrivateKeyFile != "" {
+		data, err := os.ReadFile(kc.PrivateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		var private crypto.PrivateKey
+		if k.method == jwt.SigningMethodRS256 {
+			private, err = jwt.ParseRSAPrivateKeyFromPEM(data)
+		} else {
+			private, err = jwt.ParseEdPrivateKeyFromPEM(data)
+		}
+		if err != nil {
+			return nil, err
+		}
+		k.private = private.(crypto.Signer)
+		k.public = k.private.Public()
+	}
+	if kc.PublicKeyFile != "" {
+		data, err := os.ReadFile(kc.PublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		var public crypto.PublicKey
+		if k.method == jwt.SigningMethodRS256 {
+			public, err = jwt.ParseRSAPublicKeyFromPEM(data)
+		} else {
+			public, err = jwt.ParseEdPublicKeyFromPEM(data)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if k.public != nil && !public.(interface{ Equal(crypto.PublicKey) bool }).Equal(k.public) {
+			return nil, errors.New("public key does not match private key")
+		}
+		k.public = public
+	}
+
+	if pub, ok := k.public.(*rsa.PublicKey); ok && pub.N.BitLen() < minRSABits {
+		return nil, fmt.Errorf("RSA key must be at least %d bits", minRSABits)
+	}
+	return k, nil
+}
+
+// Sign 签名令牌，使用非对称密钥时在头部写入 kid
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	if ks.signing == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(ks.secret)
+	}
+	token := jwt.NewWithClaims(ks.signing.method, claims)
+	token.Header["kid"] = ks.signing.id
+	return token.SignedString(ks.signing.private)
+}
+
+// Keyfunc 供 jwt.Parse 使用，按 kid 返回校验用的公钥
+// 令牌声明的算法必须与该 kid 配置的算法一致，防止算法混淆
+func (ks *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	if ks.signing == nil {
+		if token.Method != jwt.SigningMethodHS256 {
+			return nil, ErrUnknownKey
+		}
+		return ks.secret, nil
+	}
+	kid, _ := token.Header["kid"].(string)
+	k, ok := ks.keys[kid]
+	if !ok || token.Method.Alg() != k.method.Alg() {
+		return nil, ErrUnknownKey
+	}
+	return k.public, nil
+}
+
+// ValidMethods 返回可接受的签名算法
+func (ks *KeySet) ValidMethods() []string {
+	if ks.signing == nil {
+		return []string{jwt.SigningMethodHS256.Alg()}
+	}
+	return []string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}
+}
+
+// JWK 是 RFC 7517 定义的单个公钥
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`   // RSA 模数
+	E   string `json:"e,omitempty"`   // RSA 指数
+	Crv string `json:"crv,omitempty"` // OKP 曲线
+	X   string `json:"x,omitempty"`   // OKP 公钥
+}
+
+// JWKS 是公钥集合
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS 返回全部非对称密钥的公钥，使用 HS256 时为空集合
+func (ks *KeySet) JWKS() JWKS {
+	set := JWKS{Keys: []JWK{}}
+	for _, k := range ks.keys {
+		jwk := JWK{Kid: k.id, Use: "sig", Alg: k.method.Alg()}
+		switch pub := k.public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+	return set
+}
@@ -0,0 +1,193 @@
+package jwtkeys
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/novel/internal/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeKeyPair 生成密钥对并写入 PEM 文件，返回私钥与公钥文件路径
+func writeKeyPair(t *testing.T, name string, private interface{}, public interface{}) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	privDER, err := x509.MarshalPKCS8PrivateKey(private)
+	require.NoError(t, err)
+	pubDER, err := x509.MarshalPKIXPublicKey(public)
+	require.NoError(t, err)
+	privPath := filepath.Join(dir, name+".pem")
+	pubPath := filepath.Join(dir, name+".pub.pem")
+	require.NoError(t, os.WriteFile(privPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER}), 0o600))
+	require.NoError(t, os.WriteFile(pubPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}), 0o644))
+	return privPath, pubPath
+}
+
+func newEdKey(t *testing.T, name string) (string, string) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	return writeKeyPair(t, name, priv, pub)
+}
+
+func newRSAKey(t *testing.T, name string, bits int) (string, string) {
+	priv, err := rsa.GenerateKey(rand.Reader, bits)
+	require.NoError(t, err)
+	return writeKeyPair(t, name, priv, &priv.PublicKey)
+}
+
+func parse(ks *KeySet, token string) error {
+	_, err := jwt.Parse(token, ks.Keyfunc, jwt.WithValidMethods(ks.ValidMethods()))
+	return err
+}
+
+func TestSignAndVerifyWithKid(t *testing.T) {
+	edPriv, _ := newEdKey(t, "ed")
+	rsaPriv, _ := newRSAKey(t, "rsa", 2048)
+	for _, kc := range []config.JWTKeyConfig{
+		{ID: "ed", Algorithm: "EdDSA", PrivateKeyFile: edPriv},
+		{ID: "rsa", Algorithm: "RS256", PrivateKeyFile: rsaPriv},
+	} {
+		ks, err := Load(&config.JWTConfig{SigningKeyID: kc.ID, Keys: []config.JWTKeyConfig{kc}})
+		require.NoError(t, err)
+
+		token, err := ks.Sign(jwt.MapClaims{"user_id": 1})
+		require.NoError(t, err)
+		parsed, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+		require.NoError(t, err)
+		assert.Equal(t, kc.ID, parsed.Header["kid"])
+		assert.Equal(t, kc.Algorithm, parsed.Header["alg"])
+		assert.NoError(t, parse(ks, token), kc.ID)
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	oldPriv, oldPub := newRSAKey(t, "old", 2048)
+	newPriv, _ := newEdKey(t, "new")
+
+	before, err := Load(&config.JWTConfig{SigningKeyID: "old", Keys: []config.JWTKeyConfig{
+		{ID: "old", Algorithm: "RS256", PrivateKeyFile: oldPriv},
+	}})
+	require.NoError(t, err)
+	oldToken, err := before.Sign(jwt.MapClaims{"user_id": 1})
+	require.NoError(t, err)
+
+	// 切换到新密钥后，旧密钥只保留公钥，旧令牌仍然有效
+	after, err := Load(&config.JWTConfig{SigningKeyID: "new", Keys: []config.JWTKeyConfig{
+		{ID: "new", Algorithm: "EdDSA", PrivateKeyFile: newPriv},
+		{ID: "old", Algorithm: "RS256", PublicKeyFile: oldPub},
+	}})
+	require.NoError(t, err)
+	assert.NoError(t, parse(after, oldToken))
+	newToken, err := after.Sign(jwt.MapClaims{"user_id": 1})
+	require.NoError(t, err)
+	assert.NoError(t, parse(after, newToken))
+
+	// 旧密钥移除后，旧令牌失效
+	removed, err := Load(&config.JWTConfig{SigningKeyID: "new", Keys: []config.JWTKeyConfig{
+		{ID: "new", Algorithm: "EdDSA", PrivateKeyFile: newPriv},
+	}})
+	require.NoError(t, err)
+	assert.Error(t, parse(removed, oldToken))
+	assert.NoError(t, parse(removed, newToken))
+}
+
+func TestRejectsAlgorithmConfusion(t *testing.T) {
+	rsaPriv, rsaPub := newRSAKey(t, "rsa", 2048)
+	ks, err := Load(&config.JWTConfig{SigningKeyID: "rsa", Keys: []config.JWTKeyConfig{
+		{ID: "rsa", Algorithm: "RS256", PrivateKeyFile: rsaPriv},
+	}})
+	require.NoError(t, err)
+
+	// 用公开的公钥作为 HS256 密钥伪造的令牌
+	pubPEM, err := os.ReadFile(rsaPub)
+	require.NoError(t, err)
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"user_id": 1})
+	forged.Header["kid"] = "rsa"
+	forgedToken, err := forged.SignedString(pubPEM)
+	require.NoError(t, err)
+	assert.Error(t, parse(ks, forgedToken))
+
+	// 未知的 kid
+	otherPriv, _ := newEdKey(t, "other")
+	other, err := Load(&config.JWTConfig{SigningKeyID: "rsa", Keys: []config.JWTKeyConfig{
+		{ID: "rsa", Algorithm: "EdDSA", PrivateKeyFile: otherPriv},
+	}})
+	require.NoError(t, err)
+	token, err := other.Sign(jwt.MapClaims{"user_id": 1})
+	require.NoError(t, err)
+	assert.Error(t, parse(ks, token))
+
+	// 配置了非对称密钥后不再接受 HS256 令牌
+	hs, err := Load(&config.JWTConfig{SecretKey: "secret"})
+	require.NoError(t, err)
+	hsToken, err := hs.Sign(jwt.MapClaims{"user_id": 1})
+	require.NoError(t, err)
+	assert.NoError(t, parse(hs, hsToken))
+	assert.Error(t, parse(ks, hsToken))
+}
+
+func TestJWKS(t *testing.T) {
+	rsaPriv, _ := newRSAKey(t, "rsa", 2048)
+	edPriv, _ := newEdKey(t, "ed")
+	ks, err := Load(&config.JWTConfig{SigningKeyID: "rsa", Keys: []config.JWTKeyConfig{
+		{ID: "rsa", Algorithm: "RS256", PrivateKeyFile: rsaPriv},
+		{ID: "ed", Algorithm: "EdDSA", PrivateKeyFile: edPriv},
+	}})
+	require.NoError(t, err)
+
+	set := ks.JWKS()
+	require.Len(t, set.Keys, 2)
+	ed, rsaJWK := set.Keys[0], set.Keys[1]
+	assert.Equal(t, JWK{Kty: "OKP", Kid: "ed", Use: "sig", Alg: "EdDSA", Crv: "Ed25519", X: ed.X}, ed)
+	assert.Equal(t, "RSA", rsaJWK.Kty)
+	assert.Equal(t, "AQAB", rsaJWK.E)
+
+	// 仅凭 JWKS 中的公钥即可校验令牌
+	n, err := base64.RawURLEncoding.DecodeString(rsaJWK.N)
+	require.NoError(t, err)
+	pub := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: 65537}
+	token, err := ks.Sign(jwt.MapClaims{"user_id": 1})
+	require.NoError(t, err)
+	_, err = jwt.Parse(token, func(*jwt.Token) (interface{}, error) { return pub, nil })
+	assert.NoError(t, err)
+
+	hs, err := Load(&config.JWTConfig{SecretKey: "secret"})
+	require.NoError(t, err)
+	assert.Empty(t, hs.JWKS().Keys)
+}
+
+func TestLoadErrors(t *testing.T) {
+	edPriv, edPub := newEdKey(t, "ed")
+	_, otherPub := newEdKey(t, "other")
+	smallPriv, _ := newRSAKey(t, "small", 1024)
+	cases := map[string]*config.JWTConfig{
+		"no keys":         {},
+		"unknown signing": {SigningKeyID: "x", Keys: []config.JWTKeyConfig{{ID: "ed", Algorithm: "EdDSA", PrivateKeyFile: edPriv}}},
+		"public only":     {SigningKeyID: "ed", Keys: []config.JWTKeyConfig{{ID: "ed", Algorithm: "EdDSA", PublicKeyFile: edPub}}},
+		"mismatch":        {SigningKeyID: "ed", Keys: []config.JWTKeyConfig{{ID: "ed", Algorithm: "EdDSA", PrivateKeyFile: edPriv, PublicKeyFile: otherPub}}},
+		"wrong algorithm": {SigningKeyID: "ed", Keys: []config.JWTKeyConfig{{ID: "ed", Algorithm: "RS256", PrivateKeyFile: edPriv}}},
+		"unsupported":     {SigningKeyID: "ed", Keys: []config.JWTKeyConfig{{ID: "ed", Algorithm: "HS256", PrivateKeyFile: edPriv}}},
+		"small rsa":       {SigningKeyID: "s", Keys: []config.JWTKeyConfig{{ID: "s", Algorithm: "RS256", PrivateKeyFile: smallPriv}}},
+		"duplicate kid": {SigningKeyID: "ed", Keys: []config.JWTKeyConfig{
+			{ID: "ed", Algorithm: "EdDSA", PrivateKeyFile: edPriv},
+			{ID: "ed", Algorithm: "EdDSA", PublicKeyFile: edPub},
+		}},
+	}
+	for name, cfg := range cases {
+		_, err := Load(cfg)
+		assert.Error(t, err, name)
+	}
+
+	ks, err := Load(&config.JWTConfig{SigningKeyID: "ed", Keys: []config.JWTKeyConfig{{ID: "ed", Algorithm: "EdDSA", PrivateKeyFile: edPriv, PublicKeyFile: edPub}}})
+	require.NoError(t, err)
+	assert.NotNil(t, ks)
+}
@@ -0,0 +1,230 @@
+package quality
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"golang.org/x/text/width"
+	"math"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// 离线的评论质量评估：只依据文本本身的统计特征打分，不依赖外部服务
+// 得分在 [0, 1] 之间，由长度得分乘以各项扣分系数得到
+
+// Penalty 标识触发的扣分项
+type Penalty string
+
+const (
+	PenaltyTooShort     Penalty = "too_short"     // 有效内容过少
+	PenaltyLowDiversity Penalty = "low_diversity" // 用词单一
+	PenaltyRepetition   Penalty = "repetition"    // 大量重复的字符或片段
+	PenaltyAllCaps      Penalty = "all_caps"      // 英文几乎全部大写
+	PenaltyEmojiSpam    Penalty = "emoji_spam"    // 表情符号过多
+	PenaltyPunctuation  Penalty = "punctuation"   // 标点过多或连续堆叠
+	PenaltyLink         Penalty = "link"          // 含有链接或联系方式
+	PenaltyDuplicate    Penalty = "duplicate"     // 与其他评分的评论内容相同
+)
+
+// Result 是一条评论的质量评估结果
+type Result struct {
+	Score     float64   `json:"score"`
+	Penalties []Penalty `json:"penalties,omitempty"`
+}
+
+// 长度得分：有效单元数 (一个汉字或一个英文单词为一个单元) 从 minUnits 到 fullUnits 线性增长
+const (
+	minUnits  = 3
+	fullUnits = 30
+	// 计算哈希所需的最少有效字符数，过短的评论 (如“好看”) 天然会重复，不视为复制粘贴
+	minHashRunes = 12
+)
+
+// linkPattern 匹配网址和常见的引流联系方式
+var (
+	linkPattern = regexp.MustCompile(`(?i)(https?://|www\.|\.com\b|\.cn\b|加(微|v|vx|qq)|(微信|qq|vx)[:：号]?\s*\d{5,})`)
+)
+
+// stats 是评论文本的统计特征
+type stats struct {
+	units      []string // 有效单元：汉字逐字、其他文字按单词
+	letters    int      // 拉丁字母数
+	upper      int      // 大写拉丁字母数
+	emoji      int      // 表情符号数
+	punct      int      // 标点符号数
+	visible    int      // 非空白字符数
+	longestRun int      // 同一个文字连续出现的最大次数
+	punctRun   int      // 同一个标点或表情连续出现的最大次数
+}
+
+// analyze 统计评论文本的特征
+func analyze(text string) *stats {
+	st := &stats{}
+	var word []rune
+	flush := func() {
+		if len(word) > 0 {
+			st.units = append(st.units, strings.ToLower(string(word)))
+			word = word[:0]
+		}
+	}
+
+	var prev rune
+	run := 0
+	for _, r := range width.Fold.String(text) {
+		if unicode.IsSpace(r) {
+			flush()
+			prev, run = 0, 0
+			continue
+		}
+		st.visible++
+		if r == prev {
+			run++
+		} else {
+			prev, run = r, 1
+		}
+		if isPunctOrEmoji(r) {
+			st.punctRun = max(st.punctRun, run)
+		} else {
+			st.longestRun = max(st.longestRun, run)
+		}
+
+		switch {
+		case unicode.Is(unicode.Han, r):
+			flush()
+			st.units = append(st.units, string(r))
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if r < unicode.MaxLatin1 && unicode.IsLetter(r) {
+				st.letters++
+				if unicode.IsUpper(r) {
+					st.upper++
+				}
+			}
+			word = append(word, r)
+		case isEmoji(r):
+			flush()
+			st.emoji++
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			flush()
+			st.punct++
+		default:
+			flush()
+		}
+	}
+	flush()
+	return st
+}
+
+// isEmoji 判断字符是否为常见的表情符号
+func isEmoji(r rune) bool {
+	return (r >= 0x1F300 && r <= 0x1FAFF) || (r >= 0x2600 && r <= 0x27BF) || (r >= 0x1F000 && r <= 0x1F2FF)
+}
+
+// Score 评估评论质量，duplicate 表示该评论与其他评分的评论内容相同
+// 空评论返回 1 分且不计任何扣分项，是否附带评论由其他因子处理
+func Score(comment string, duplicate bool) Result {
+	if strings.TrimSpace(comment) == "" {
+		return Result{Score: 1}
+	}
+	st := analyze(comment)
+	var penalties []Penalty
+	score := lengthScore(len(st.units))
+	if len(st.units) < minUnits {
+		penalties = append(penalties, PenaltyTooShort)
+	}
+
+	apply := func(p Penalty, factor float64) {
+		penalties = append(penalties, p)
+		score *= factor
+	}
+
+	n := len(st.units)
+	if n >= 10 {
+		if d := diversity(st.units); d < 0.35 {
+			apply(PenaltyLowDiversity, 0.3+d)
+		}
+	}
+	if rep := repetition(st.units); rep > 0.5 || st.longestRun >= 6 {
+		apply(PenaltyRepetition, 0.4)
+	}
+	if st.letters >= 12 && float64(st.upper)/float64(st.letters) > 0.7 {
+		apply(PenaltyAllCaps, 0.6)
+	}
+	if st.emoji >= 5 && float64(st.emoji)/float64(st.visible) > 0.2 {
+		apply(PenaltyEmojiSpam, 0.5)
+	}
+	// 标点过多、同一标点连续堆叠，或者长篇大论却没有任何标点
+	if float64(st.punct)/float64(st.visible) > 0.4 || st.punctRun >= 4 {
+		apply(PenaltyPunctuation, 0.7)
+	} else if n >= 80 && st.punct == 0 {
+		apply(PenaltyPunctuation, 0.85)
+	}
+	if linkPattern.MatchString(comment) {
+		apply(PenaltyLink, 0.3)
+	}
+	if duplicate {
+		apply(PenaltyDuplicate, 0.2)
+	}
+	return Result{Score: math.Max(0, math.Min(1, score)), Penalties: penalties}
+}
+
+// lengthScore 按有效单元数计算长度得分
+func lengthScore(units int) float64 {
+	switch {
+	case units < minUnits:
+		return 0.2
+	case units >= fullUnits:
+		return 1
+	}
+	return 0.4 + 0.6*float64(units-minUnits)/float64(fullUnits-minUnits)
+}
+
+// diversity 计算不同单元数占总单元数的比例 (type-token ratio)
+// 长文本天然会重复常用字，按 sqrt 修正，使不同长度的文本可以共用同一阈值
+func diversity(units []string) float64 {
+	distinct := make(map[string]bool, len(units))
+	for _, u := range units {
+		distinct[u] = true
+	}
+	return math.Min(1, float64(len(distinct))/math.Sqrt(float64(len(units)))/3)
+}
+
+// repetition 计算相邻两单元组成的片段中重复出现的比例，用于识别“好看好看好看”这类复读
+func repetition(units []string) float64 {
+	if len(units) < 8 {
+		return 0
+	}
+	counts := make(map[string]int)
+	for i := 0; i+1 < len(units); i++ {
+		counts[units[i]+"\x00"+units[i+1]]++
+	}
+	repeated := 0
+	for _, c := range counts {
+		if c > 1 {
+			repeated += c - 1
+		}
+	}
+	return float64(repeated) / float64(len(units)-1)
+}
+
+func isPunctOrEmoji(r rune) bool {
+	return unicode.IsPunct(r) || unicode.IsSymbol(r) || isEmoji(r)
+}
+
+// Hash 计算评论内容的指纹，用于识别复制粘贴的评论
+// 忽略大小写、全半角、空白和标点；有效内容过短时返回空字符串
+func Hash(comment string) string {
+	var b strings.Builder
+	n := 0
+	for _, r := range strings.ToLower(width.Fold.String(comment)) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			n++
+		}
+	}
+	if n < minHashRunes {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(b.String()))
+	return hex.EncodeToString(sum[:16])
+}
@@ -0,0 +1,62 @@
+package quality
+
+import (
+	"bufio"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"strings"
+	"testing"
+)
+
+// loadCorpus 读取测试语料，每行一条评论，忽略空行和以 # 开头的注释行
+func loadCorpus(t *testing.T, path string) []string {
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	require.NoError(t, scanner.Err())
+	require.NotEmpty(t, lines)
+	return lines
+}
+
+func TestScoreCorpus(t *testing.T) {
+	for _, c := range loadCorpus(t, "testdata/good.txt") {
+		r := Score(c, false)
+		assert.GreaterOrEqual(t, r.Score, 0.8, "good comment scored too low: %q %v", c, r.Penalties)
+	}
+	for _, c := range loadCorpus(t, "testdata/bad.txt") {
+		r := Score(c, false)
+		assert.LessOrEqual(t, r.Score, 0.35, "bad comment scored too high: %q %v", c, r.Penalties)
+	}
+}
+
+func TestScoreDuplicate(t *testing.T) {
+	comment := loadCorpus(t, "testdata/good.txt")[0]
+	original := Score(comment, false)
+	copied := Score(comment, true)
+	assert.Less(t, copied.Score, original.Score)
+	assert.Contains(t, copied.Penalties, PenaltyDuplicate)
+}
+
+func TestScoreEmpty(t *testing.T) {
+	assert.Equal(t, Result{Score: 1}, Score("  ", false))
+}
+
+func TestHash(t *testing.T) {
+	a := Hash("这本书的悬疑部分写得很好，每一卷结尾都留下钩子")
+	b := Hash("这本书的悬疑部分写得很好 每一卷结尾都留下钩子！")
+	assert.NotEmpty(t, a)
+	assert.Equal(t, a, b)
+	assert.Equal(t, Hash("Great Book, really GREAT book"), Hash("great book really great book"))
+	assert.Empty(t, Hash("好看"))
+}
@@ -0,0 +1,67 @@
+// Package ratelimit 提供进程内的固定窗口限流器
+//
+// 计数保存在内存中，多实例部署时每个实例分别计数，实际允许的次数最多为 limit × 实例数
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter 限制每个 key 在 window 时长内最多通过 limit 次
+type Limiter struct {
+	limit  int
+	window time.Duration
+
+	mu        sync.Mutex
+	windows   map[string]*fixedWindow
+	lastSweep time.Time
+}
+
+type fixedWindow struct {
+	start time.Time
+	count int
+}
+
+// New 创建一个限流器，limit 或 window 不大于 0 时返回 nil，表示不限制
+func New(limit int, window time.Duration) *Limiter {
+	if limit <= 0 || window <= 0 {
+		return nil
+	}
+	return &Limiter{limit: limit, window: window, windows: make(map[string]*fixedWindow)}
+}
+
+// Allow 记录 key 的一次请求并判断是否放行，不放行时同时返回距离当前窗口结束的时长
+// nil 的 Limiter 总是放行
+func (l *Limiter) Allow(key string, now time.Time) (bool, time.Duration) {
+	if l == nil {
+		return true, 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(now)
+
+	w, ok := l.windows[key]
+	if !ok || now.Sub(w.start) >= l.window {
+		w = &fixedWindow{start: now}
+		l.windows[key] = w
+	}
+	if w.count >= l.limit {
+		return false, w.start.Add(l.window).Sub(now)
+	}
+	w.count++
+	return true, 0
+}
+
+// sweep 每隔一个窗口清理一次已过期的计数，避免 key 无限增长
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.window {
+		return
+	}
+	for key, w := range l.windows {
+		if now.Sub(w.start) >= l.window {
+			delete(l.windows, key)
+		}
+	}
+	l.lastSweep = now
+}
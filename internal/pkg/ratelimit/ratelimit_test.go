@@ -0,0 +1,46 @@
+package ratelimit
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestLimiterAllow(t *testing.T) {
+	l := New(2, time.Minute)
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	ok, _ := l.Allow("a", now)
+	assert.True(t, ok)
+	ok, _ = l.Allow("a", now.Add(10*time.Second))
+	assert.True(t, ok)
+	ok, retryAfter := l.Allow("a", now.Add(20*time.Second))
+	assert.False(t, ok)
+	assert.Equal(t, 40*time.Second, retryAfter)
+
+	// 不同的 key 分别计数
+	ok, _ = l.Allow("b", now.Add(20*time.Second))
+	assert.True(t, ok)
+
+	// 窗口结束后重新计数
+	ok, _ = l.Allow("a", now.Add(time.Minute))
+	assert.True(t, ok)
+}
+
+func TestLimiterSweep(t *testing.T) {
+	l := New(1, time.Minute)
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	l.Allow("a", now)
+	l.Allow("b", now.Add(30*time.Second))
+	l.Allow("c", now.Add(2*time.Minute))
+	assert.Len(t, l.windows, 1)
+}
+
+func TestNilLimiterAllowsEverything(t *testing.T) {
+	l := New(0, time.Minute)
+	assert.Nil(t, l)
+	for i := 0; i < 10; i++ {
+		ok, _ := l.Allow("a", time.Now())
+		assert.True(t, ok)
+	}
+}
@@ -2,7 +2,10 @@ package response
 
 import (
 	"github.com/gin-gonic/gin"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 )
 
 // Response 是我们统一的JSON响应结构体
@@ -75,3 +78,11 @@ func NotFound(c *gin.Context) {
 func ServerError(c *gin.Context) {
 	errorResponse(c, http.StatusInternalServerError, ErrorCode, "服务器内部错误")
 }
+
+// TooManyRequests 用于处理请求过于频繁的响应 (HTTP 429)，retryAfter 为建议的重试等待时间
+func TooManyRequests(c *gin.Context, msg string, retryAfter time.Duration) {
+	if retryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	}
+	errorResponse(c, http.StatusTooManyRequests, ErrorCode, msg)
+}
@@ -0,0 +1,55 @@
+package search
+
+import (
+	"gorm.io/gorm/clause"
+	"strings"
+)
+
+// Weight 是 tsvector 中词的权重等级，A 最高，D 最低
+type Weight string
+
+const (
+	WeightA Weight = "A"
+	WeightB Weight = "B"
+	WeightC Weight = "C"
+	WeightD Weight = "D"
+)
+
+// Field 是参与索引的一段文本及其权重
+type Field struct {
+	Text   string
+	Weight Weight
+}
+
+// VectorExpr 生成计算 tsvector 的 SQL 表达式
+// 分好的词以空格拼接后传入数据库，再转换为 tsvector，不经过 PostgreSQL 的分词器
+func VectorExpr(fields ...Field) clause.Expr {
+	if len(fields) == 0 {
+		return clause.Expr{SQL: "''::tsvector"}
+	}
+	parts := make([]string, len(fields))
+	vars := make([]interface{}, len(fields))
+	for i, f := range fields {
+		parts[i] = "setweight(array_to_tsvector(string_to_array(?, ' ')), '" + string(f.Weight) + "')"
+		vars[i] = strings.Join(Tokenize(f.Text), " ")
+	}
+	return clause.Expr{SQL: strings.Join(parts, " || "), Vars: vars}
+}
+
+// TSQuery 将搜索词转换为 tsquery 文本，所有词之间为 AND 关系，非中文的词按前缀匹配
+// 搜索词中没有可用的词时返回 false
+func TSQuery(text string) (string, bool) {
+	terms := QueryTerms(text)
+	if len(terms) == 0 {
+		return "", false
+	}
+	parts := make([]string, len(terms))
+	for i, t := range terms {
+		// 词中只包含字母和数字，不需要额外转义
+		parts[i] = "'" + t + "'"
+		if r := []rune(t); !isCJK(r[0]) {
+			parts[i] += ":*"
+		}
+	}
+	return strings.Join(parts, " & "), true
+}
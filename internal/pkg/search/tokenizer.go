@@ -0,0 +1,108 @@
+package search
+
+import (
+	"golang.org/x/text/width"
+	"strings"
+	"unicode"
+)
+
+// PostgreSQL 自带的分词器不能切分中文，这里在 Go 中完成分词：
+// 中日韩文字按 N-gram 切分 (单字 + 相邻两字)，其他文字按连续的字母数字切分为单词
+// 索引和查询使用同一套规则，数据库只负责存储和匹配已经切好的词
+
+// maxTokenLen 是单词的最大长度，超长的单词会被截断
+const maxTokenLen = 64
+
+// segment 是文本中连续的同类字符
+type segment struct {
+	runes []rune
+	cjk   bool
+}
+
+// isCJK 判断字符是否需要按 N-gram 切分
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// normalize 将全角字符转换为半角并统一为小写
+func normalize(text string) string {
+	return strings.ToLower(width.Fold.String(text))
+}
+
+// segments 将文本切分为连续的中日韩文字片段和字母数字片段，其余字符视为分隔符
+func segments(text string) []segment {
+	var segs []segment
+	var cur *segment
+	for _, r := range normalize(text) {
+		switch {
+		case isCJK(r):
+			if cur == nil || !cur.cjk {
+				segs = append(segs, segment{cjk: true})
+				cur = &segs[len(segs)-1]
+			}
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if cur == nil || cur.cjk {
+				segs = append(segs, segment{})
+				cur = &segs[len(segs)-1]
+			}
+		default:
+			cur = nil
+			continue
+		}
+		cur.runes = append(cur.runes, r)
+	}
+	return segs
+}
+
+// word 返回截断后的单词
+func word(runes []rune) string {
+	if len(runes) > maxTokenLen {
+		runes = runes[:maxTokenLen]
+	}
+	return string(runes)
+}
+
+// Tokenize 将文本切分为用于建立索引的词：中文同时产生单字和两字词，保证单字和多字查询都能命中
+func Tokenize(text string) []string {
+	var tokens []string
+	for _, seg := range segments(text) {
+		if !seg.cjk {
+			tokens = append(tokens, word(seg.runes))
+			continue
+		}
+		for i := range seg.runes {
+			tokens = append(tokens, string(seg.runes[i]))
+			if i+1 < len(seg.runes) {
+				tokens = append(tokens, string(seg.runes[i:i+2]))
+			}
+		}
+	}
+	return tokens
+}
+
+// QueryTerms 将搜索词切分为查询用的词：中文只使用两字词 (单字查询时使用单字)，
+// 所有词都必须命中，效果近似于在文本中查找连续的短语
+func QueryTerms(text string) []string {
+	var terms []string
+	seen := make(map[string]bool)
+	add := func(t string) {
+		if !seen[t] {
+			seen[t] = true
+			terms = append(terms, t)
+		}
+	}
+	for _, seg := range segments(text) {
+		if !seg.cjk {
+			add(word(seg.runes))
+			continue
+		}
+		if len(seg.runes) == 1 {
+			add(string(seg.runes))
+			continue
+		}
+		for i := 0; i+1 < len(seg.runes); i++ {
+			add(string(seg.runes[i : i+2]))
+		}
+	}
+	return terms
+}
@@ -0,0 +1,43 @@
+package search
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	assert.Equal(t, []string{"三", "三体", "体"}, Tokenize("三体"))
+	assert.Equal(t,
+		[]string{"刘", "刘慈", "慈", "慈欣", "欣", "the", "three", "body", "problem", "2008"},
+		Tokenize("刘慈欣：The Three-Body Problem（２００８）"))
+	assert.Equal(t, []string{"harry", "哈", "哈利", "利"}, Tokenize("Harry哈利"))
+	assert.Empty(t, Tokenize(" ，。!? "))
+}
+
+func TestQueryTerms(t *testing.T) {
+	assert.Equal(t, []string{"刘慈", "慈欣"}, QueryTerms("刘慈欣"))
+	assert.Equal(t, []string{"三"}, QueryTerms("三"))
+	assert.Equal(t, []string{"哈哈"}, QueryTerms("哈哈哈"))
+	assert.Equal(t, []string{"harry", "potter"}, QueryTerms("HARRY potter harry"))
+}
+
+func TestQueryTermsMatchIndexedTokens(t *testing.T) {
+	indexed := make(map[string]bool)
+	for _, tok := range Tokenize("诡秘之主 Lord of the Mysteries") {
+		indexed[tok] = true
+	}
+	for _, q := range []string{"诡秘", "秘之主", "诡", "mysteries", "之主 lord"} {
+		for _, term := range QueryTerms(q) {
+			assert.True(t, indexed[term], "query %q term %q", q, term)
+		}
+	}
+}
+
+func TestTSQuery(t *testing.T) {
+	q, ok := TSQuery("三体 Liu")
+	assert.True(t, ok)
+	assert.Equal(t, "'三体' & 'liu':*", q)
+
+	_, ok = TSQuery("  ！！ ")
+	assert.False(t, ok)
+}
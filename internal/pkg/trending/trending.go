@@ -0,0 +1,73 @@
+// Package trending 实现按指数衰减计算的热度
+//
+// 一次强度为 w 的事件在 Δt 之后的贡献为 w × 2^(-Δt / 半衰期)。所有小说的热度以同样的速率衰减，
+// 因此热度的排序与时间无关，可以把热度换算为与时间无关的排序键保存在数据库中：
+//
+//	key = log2(热度) + (now - epoch) / 半衰期
+//
+// 排序键越大热度越高；事件发生时增量更新排序键，列表查询直接按排序键排序，不需要定期衰减。
+package trending
+
+import (
+	"math"
+	"time"
+)
+
+// Window 是热度榜的时间窗口，每个窗口使用各自的半衰期
+type Window string
+
+const (
+	Day   Window = "day"
+	Week  Window = "week"
+	Month Window = "month"
+)
+
+// Windows 是全部时间窗口
+var Windows = []Window{Day, Week, Month}
+
+// ParseWindow 解析时间窗口，为空时返回 Week
+func ParseWindow(s string) (Window, bool) {
+	if s == "" {
+		return Week, true
+	}
+	for _, w := range Windows {
+		if string(w) == s {
+			return w, true
+		}
+	}
+	return "", false
+}
+
+// epoch 是排序键的时间原点，半衰期应远小于当前时间与 epoch 的间隔，否则排序键可能为负
+var epoch = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// minHeat 以下的热度视为没有热度，排序键记为 0
+const minHeat = 1e-6
+
+// Key 把 now 时刻的热度换算为排序键
+func Key(heat float64, now time.Time, halfLife time.Duration) float64 {
+	if heat < minHeat {
+		return 0
+	}
+	return math.Log2(heat) + halfLives(now.Sub(epoch), halfLife)
+}
+
+// Heat 由排序键计算 now 时刻的热度
+func Heat(key float64, now time.Time, halfLife time.Duration) float64 {
+	if key <= 0 {
+		return 0
+	}
+	return math.Exp2(key - halfLives(now.Sub(epoch), halfLife))
+}
+
+// Add 把一次发生在 at 时刻、强度为 weight 的事件计入排序键，返回新的排序键
+// weight 为负表示撤销此前计入的事件，at 应为该事件当初发生的时刻
+func Add(key, weight float64, at, now time.Time, halfLife time.Duration) float64 {
+	decayed := weight * math.Exp2(-halfLives(now.Sub(at), halfLife))
+	return Key(Heat(key, now, halfLife)+decayed, now, halfLife)
+}
+
+// halfLives 计算 d 相当于多少个半衰期
+func halfLives(d, halfLife time.Duration) float64 {
+	return d.Seconds() / halfLife.Seconds()
+}
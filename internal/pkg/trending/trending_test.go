@@ -0,0 +1,58 @@
+package trending
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestHeatDecaysByHalfLife(t *testing.T) {
+	halfLife := 6 * time.Hour
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	key := Add(0, 4, now, now, halfLife)
+	assert.InDelta(t, 4, Heat(key, now, halfLife), 1e-9)
+	assert.InDelta(t, 2, Heat(key, now.Add(halfLife), halfLife), 1e-9)
+	assert.InDelta(t, 1, Heat(key, now.Add(2*halfLife), halfLife), 1e-9)
+
+	// 事件按发生时刻衰减后再累加
+	later := now.Add(halfLife)
+	key = Add(key, 1, later, later, halfLife)
+	assert.InDelta(t, 3, Heat(key, later, halfLife), 1e-9)
+
+	// 撤销第一次事件后只剩第二次事件的贡献
+	key = Add(key, -4, now, later, halfLife)
+	assert.InDelta(t, 1, Heat(key, later, halfLife), 1e-9)
+
+	// 全部撤销后没有热度
+	key = Add(key, -1, later, later, halfLife)
+	assert.Equal(t, 0.0, key)
+}
+
+func TestKeyOrderIsTimeIndependent(t *testing.T) {
+	halfLife := 48 * time.Hour
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	// 三天前的 10 次评分不如刚刚的 5 次评分
+	old := Add(0, 10, now.Add(-72*time.Hour), now, halfLife)
+	fresh := Add(0, 5, now, now, halfLife)
+	assert.Greater(t, fresh, old)
+	assert.Greater(t, Heat(fresh, now, halfLife), Heat(old, now, halfLife))
+
+	// 在不同时刻计算的排序键可以直接比较
+	oldAtThatTime := Add(0, 10, now.Add(-72*time.Hour), now.Add(-72*time.Hour), halfLife)
+	assert.InDelta(t, old, oldAtThatTime, 1e-9)
+}
+
+func TestParseWindow(t *testing.T) {
+	w, ok := ParseWindow("")
+	assert.True(t, ok)
+	assert.Equal(t, Week, w)
+
+	w, ok = ParseWindow("month")
+	assert.True(t, ok)
+	assert.Equal(t, Month, w)
+
+	_, ok = ParseWindow("year")
+	assert.False(t, ok)
+}
@@ -0,0 +1,318 @@
+// Package votering 离线分析评分投票，识别互相刷赞的小团体 (vote ring) 与投票记录高度相同的马甲账号
+//
+// 分析基于投票图：每张投票是从投票者指向评分作者的一条边。
+//   - 互赞团体：两个账号互相给对方的评分投了足够多的赞同票即构成互赞关系，互赞关系的连通分量是候选团体；
+//     团体成员收到的赞同票大部分来自团体内部时判定为刷赞团体
+//   - 马甲账号：两个账号对同一批评分投出了相同的票，投票集合的 Jaccard 相似度超过阈值
+package votering
+
+import (
+	"sort"
+)
+
+// Vote 是一张有效投票
+type Vote struct {
+	VoterID  uint
+	AuthorID uint // 被投票评分的作者
+	RatingID uint
+	Value    int // 1 赞同，-1 反对
+}
+
+// Options 是分析的参数
+type Options struct {
+	MinMutualUpvotes    int     // 两个账号互相至少投出多少张赞同票才算互赞
+	MinInternalShare    float64 // 团体成员收到的赞同票中来自团体内部的最低占比
+	MinVotesSimilarity  int     // 参与相似度比较的账号至少投过多少张票
+	SimilarityThreshold float64 // 判定为马甲的 Jaccard 相似度下限
+	MaxCoVoters         int     // 投票人数超过该值的评分不参与相似度计算，避免热门评分造成平方级开销
+	ClusterPenalty      float64 // 刷赞团体成员的信誉分惩罚
+	SimilarityPenalty   float64 // 马甲账号的信誉分惩罚
+	MaxPenalty          float64 // 单个账号惩罚的上限
+}
+
+// DefaultOptions 返回默认参数
+func DefaultOptions() Options {
+	return Options{
+		MinMutualUpvotes:    3,
+		MinInternalShare:    0.6,
+		MinVotesSimilarity:  10,
+		SimilarityThreshold: 0.8,
+		MaxCoVoters:         500,
+		ClusterPenalty:      0.15,
+		SimilarityPenalty:   0.1,
+		MaxPenalty:          0.3,
+	}
+}
+
+// Cluster 是一个互赞团体
+type Cluster struct {
+	Members         []uint  `json:"members"`
+	MutualPairs     int     `json:"mutual_pairs"`     // 团体内互赞关系的数量
+	InternalUpvotes int     `json:"internal_upvotes"` // 成员之间的赞同票
+	ExternalUpvotes int     `json:"external_upvotes"` // 成员从团体外收到的赞同票
+	InternalShare   float64 `json:"internal_share"`   // 成员收到的赞同票中来自团体内部的占比
+	Flagged         bool    `json:"flagged"`          // 是否判定为刷赞团体
+}
+
+// SimilarPair 是投票记录高度相同的两个账号
+type SimilarPair struct {
+	A       uint    `json:"a"`
+	B       uint    `json:"b"`
+	Shared  int     `json:"shared"` // 完全相同的投票数
+	Jaccard float64 `json:"jaccard"`
+}
+
+// Suspect 是被判定为可疑的账号及建议的信誉分惩罚
+type Suspect struct {
+	UserID  uint     `json:"user_id"`
+	Reasons []string `json:"reasons"` // vote_ring、sockpuppet
+	Penalty float64  `json:"penalty"`
+}
+
+// Report 是一次分析的结果
+type Report struct {
+	VotesAnalyzed    int           `json:"votes_analyzed"`
+	Voters           int           `json:"voters"`
+	Clusters         []Cluster     `json:"clusters"`
+	SimilarPairs     []SimilarPair `json:"similar_pairs"`
+	SockpuppetGroups [][]uint      `json:"sockpuppet_groups"` // 由相似账号对连接而成的账号组
+	Suspects         []Suspect     `json:"suspects"`
+}
+
+const (
+	ReasonVoteRing   = "vote_ring"
+	ReasonSockpuppet = "sockpuppet"
+)
+
+// Analyze 分析全部投票并生成报告，自己给自己的投票不计入
+func Analyze(votes []Vote, opts Options) *Report {
+	report := &Report{
+		VotesAnalyzed:    len(votes),
+		Clusters:         []Cluster{},
+		SimilarPairs:     []SimilarPair{},
+		SockpuppetGroups: [][]uint{},
+		Suspects:         []Suspect{},
+	}
+	voters := make(map[uint]bool)
+	for _, v := range votes {
+		voters[v.VoterID] = true
+	}
+	report.Voters = len(voters)
+
+	report.Clusters = findClusters(votes, opts)
+	report.SimilarPairs = findSimilarPairs(votes, opts)
+	report.SockpuppetGroups = groupPairs(report.SimilarPairs)
+	report.Suspects = suspects(report, opts)
+	return report
+}
+
+// pair 是无序的账号对，a < b
+type pair struct{ a, b uint }
+
+func newPair(x, y uint) pair {
+	if x > y {
+		x, y = y, x
+	}
+	return pair{x, y}
+}
+
+// findClusters 找出互赞关系的连通分量，并计算每个分量的内部赞同票占比
+func findClusters(votes []Vote, opts Options) []Cluster {
+	upvotes := make(map[[2]uint]int) // [投票者, 作者] -> 赞同票数
+	received := make(map[uint]int)   // 作者 -> 收到的赞同票总数
+	for _, v := range votes {
+		if v.Value <= 0 || v.VoterID == v.AuthorID {
+			continue
+		}
+		upvotes[[2]uint{v.VoterID, v.AuthorID}]++
+		received[v.AuthorID]++
+	}
+
+	uf := newUnionFind()
+	mutual := make(map[pair]bool)
+	for edge, n := range upvotes {
+		voter, author := edge[0], edge[1]
+		if n < opts.MinMutualUpvotes || upvotes[[2]uint{author, voter}] < opts.MinMutualUpvotes {
+			continue
+		}
+		mutual[newPair(voter, author)] = true
+		uf.union(voter, author)
+	}
+
+	clusters := []Cluster{}
+	for _, members := range uf.groups() {
+		in := make(map[uint]bool, len(members))
+		for _, m := range members {
+			in[m] = true
+		}
+		c := Cluster{Members: members}
+		for p := range mutual {
+			if in[p.a] {
+				c.MutualPairs++
+			}
+		}
+		var total int
+		for _, m := range members {
+			total += received[m]
+		}
+		for edge, n := range upvotes {
+			if in[edge[0]] && in[edge[1]] {
+				c.InternalUpvotes += n
+			}
+		}
+		c.ExternalUpvotes = total - c.InternalUpvotes
+		if total > 0 {
+			c.InternalShare = float64(c.InternalUpvotes) / float64(total)
+		}
+		c.Flagged = c.InternalShare >= opts.MinInternalShare
+		clusters = append(clusters, c)
+	}
+	sort.Slice(clusters, func(i, j int) bool {
+		if len(clusters[i].Members) != len(clusters[j].Members) {
+			return len(clusters[i].Members) > len(clusters[j].Members)
+		}
+		return clusters[i].Members[0] < clusters[j].Members[0]
+	})
+	return clusters
+}
+
+// findSimilarPairs 找出投票集合 Jaccard 相似度超过阈值的账号对
+// 只比较至少共同投过一张相同票的账号，通过按评分倒排的方式统计交集
+func findSimilarPairs(votes []Vote, opts Options) []SimilarPair {
+	type ballot struct {
+		ratingID uint
+		value    int
+	}
+	counts := make(map[uint]int)        // 投票者 -> 投票数
+	byBallot := make(map[ballot][]uint) // 相同的一张票 -> 投出这张票的账号
+	for _, v := range votes {
+		if v.VoterID == v.AuthorID {
+			continue
+		}
+		counts[v.VoterID]++
+		b := ballot{v.RatingID, v.Value}
+		byBallot[b] = append(byBallot[b], v.VoterID)
+	}
+
+	shared := make(map[pair]int)
+	for _, voters := range byBallot {
+		if len(voters) > opts.MaxCoVoters {
+			continue
+		}
+		for i := 0; i < len(voters); i++ {
+			if counts[voters[i]] < opts.MinVotesSimilarity {
+				continue
+			}
+			for j := i + 1; j < len(voters); j++ {
+				if counts[voters[j]] < opts.MinVotesSimilarity {
+					continue
+				}
+				shared[newPair(voters[i], voters[j])]++
+			}
+		}
+	}
+
+	pairs := []SimilarPair{}
+	for p, n := range shared {
+		jaccard := float64(n) / float64(counts[p.a]+counts[p.b]-n)
+		if jaccard >= opts.SimilarityThreshold {
+			pairs = append(pairs, SimilarPair{A: p.a, B: p.b, Shared: n, Jaccard: jaccard})
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].A != pairs[j].A {
+			return pairs[i].A < pairs[j].A
+		}
+		return pairs[i].B < pairs[j].B
+	})
+	return pairs
+}
+
+// groupPairs 把相似账号对按连通性合并为账号组
+func groupPairs(pairs []SimilarPair) [][]uint {
+	uf := newUnionFind()
+	for _, p := range pairs {
+		uf.union(p.A, p.B)
+	}
+	return uf.groups()
+}
+
+// suspects 汇总可疑账号并计算建议的惩罚，同时命中两类问题的账号惩罚累加，但不超过上限
+func suspects(r *Report, opts Options) []Suspect {
+	byUser := make(map[uint]*Suspect)
+	add := func(id uint, reason string, penalty float64) {
+		s, ok := byUser[id]
+		if !ok {
+			s = &Suspect{UserID: id}
+			byUser[id] = s
+		}
+		s.Reasons = append(s.Reasons, reason)
+		s.Penalty += penalty
+		if s.Penalty > opts.MaxPenalty {
+			s.Penalty = opts.MaxPenalty
+		}
+	}
+	for _, c := range r.Clusters {
+		if !c.Flagged {
+			continue
+		}
+		for _, m := range c.Members {
+			add(m, ReasonVoteRing, opts.ClusterPenalty)
+		}
+	}
+	for _, g := range r.SockpuppetGroups {
+		for _, m := range g {
+			add(m, ReasonSockpuppet, opts.SimilarityPenalty)
+		}
+	}
+
+	list := make([]Suspect, 0, len(byUser))
+	for _, s := range byUser {
+		list = append(list, *s)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].UserID < list[j].UserID })
+	return list
+}
+
+// unionFind 是按账号ID合并集合的并查集
+type unionFind struct {
+	parent map[uint]uint
+}
+
+func newUnionFind() *unionFind {
+	return &unionFind{parent: make(map[uint]uint)}
+}
+
+func (u *unionFind) find(x uint) uint {
+	if _, ok := u.parent[x]; !ok {
+		u.parent[x] = x
+	}
+	for u.parent[x] != x {
+		u.parent[x] = u.parent[u.parent[x]]
+		x = u.parent[x]
+	}
+	return x
+}
+
+func (u *unionFind) union(x, y uint) {
+	rx, ry := u.find(x), u.find(y)
+	if rx != ry {
+		u.parent[ry] = rx
+	}
+}
+
+// groups 返回全部集合，集合内与集合之间均按ID升序排列
+func (u *unionFind) groups() [][]uint {
+	byRoot := make(map[uint][]uint)
+	for x := range u.parent {
+		root := u.find(x)
+		byRoot[root] = append(byRoot[root], x)
+	}
+	groups := make([][]uint, 0, len(byRoot))
+	for _, g := range byRoot {
+		sort.Slice(g, func(i, j int) bool { return g[i] < g[j] })
+		groups = append(groups, g)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i][0] < groups[j][0] })
+	return groups
+}
@@ -0,0 +1,185 @@
+package votering
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// graph 用于构造合成的投票图，每个作者拥有若干条评分
+type graph struct {
+	votes   []Vote
+	ratings map[uint][]uint // 作者 -> 评分ID
+	next    uint
+}
+
+func newGraph() *graph {
+	return &graph{ratings: make(map[uint][]uint), next: 1}
+}
+
+// rating 返回作者的第 i 条评分，不存在时创建
+func (g *graph) rating(author uint, i int) uint {
+	for len(g.ratings[author]) <= i {
+		g.ratings[author] = append(g.ratings[author], g.next)
+		g.next++
+	}
+	return g.ratings[author][i]
+}
+
+func (g *graph) vote(voter, author uint, i, value int) {
+	g.votes = append(g.votes, Vote{VoterID: voter, AuthorID: author, RatingID: g.rating(author, i), Value: value})
+}
+
+// organic 生成自然的投票：voters 个账号随机给 authors 个作者的评分投票，多数为赞同
+func (g *graph) organic(rng *rand.Rand, firstVoter, voters, firstAuthor, authors, votesEach int) {
+	for v := 0; v < voters; v++ {
+		voter := uint(firstVoter + v)
+		for k := 0; k < votesEach; k++ {
+			author := uint(firstAuthor + rng.Intn(authors))
+			value := 1
+			if rng.Float64() < 0.2 {
+				value = -1
+			}
+			g.vote(voter, author, rng.Intn(20), value)
+		}
+	}
+}
+
+func flaggedClusters(r *Report) [][]uint {
+	var flagged [][]uint
+	for _, c := range r.Clusters {
+		if c.Flagged {
+			flagged = append(flagged, c.Members)
+		}
+	}
+	return flagged
+}
+
+func TestDetectsVoteRing(t *testing.T) {
+	g := newGraph()
+	rng := rand.New(rand.NewSource(1))
+	g.organic(rng, 1, 200, 1, 60, 15)
+
+	// 1001~1004 互相给对方的每条评分投赞同票
+	ring := []uint{1001, 1002, 1003, 1004}
+	for _, voter := range ring {
+		for _, author := range ring {
+			if voter == author {
+				continue
+			}
+			for i := 0; i < 5; i++ {
+				g.vote(voter, author, i, 1)
+			}
+		}
+	}
+
+	r := Analyze(g.votes, DefaultOptions())
+	assert.Equal(t, [][]uint{ring}, flaggedClusters(r))
+	for _, c := range r.Clusters {
+		if c.Flagged {
+			assert.Equal(t, 6, c.MutualPairs)
+			assert.Equal(t, 60, c.InternalUpvotes)
+			assert.InDelta(t, 1.0, c.InternalShare, 1e-9)
+		}
+	}
+	for _, s := range r.Suspects {
+		assert.Contains(t, ring, s.UserID)
+		assert.Equal(t, []string{ReasonVoteRing}, s.Reasons)
+	}
+}
+
+func TestPopularMutualFansAreNotFlagged(t *testing.T) {
+	g := newGraph()
+	rng := rand.New(rand.NewSource(2))
+	// 两位热门作者互相欣赏，但他们收到的赞同票绝大多数来自其他读者
+	for i := 0; i < 4; i++ {
+		g.vote(1, 2, i, 1)
+		g.vote(2, 1, i, 1)
+	}
+	for voter := uint(100); voter < 300; voter++ {
+		g.vote(voter, uint(1+rng.Intn(2)), rng.Intn(10), 1)
+	}
+
+	r := Analyze(g.votes, DefaultOptions())
+	require.Len(t, r.Clusters, 1)
+	assert.Equal(t, []uint{1, 2}, r.Clusters[0].Members)
+	assert.False(t, r.Clusters[0].Flagged)
+	assert.Less(t, r.Clusters[0].InternalShare, 0.1)
+	assert.Empty(t, r.Suspects)
+}
+
+func TestDetectsSockpuppets(t *testing.T) {
+	g := newGraph()
+	rng := rand.New(rand.NewSource(3))
+	g.organic(rng, 1, 100, 500, 40, 20)
+
+	// 2001~2003 对同一批评分投出完全相同的票，2003 多投了一张
+	for _, puppet := range []uint{2001, 2002, 2003} {
+		for i := 0; i < 15; i++ {
+			value := 1
+			if i%4 == 0 {
+				value = -1
+			}
+			g.vote(puppet, uint(900+i%3), i, value)
+		}
+	}
+	g.vote(2003, 901, 30, 1)
+
+	// 2004 与它们有一半的投票相同
+	for i := 0; i < 15; i++ {
+		if i%2 == 0 {
+			g.vote(2004, uint(900+i%3), i, map[bool]int{true: -1, false: 1}[i%4 == 0])
+		} else {
+			g.vote(2004, uint(950+i), 0, 1)
+		}
+	}
+
+	r := Analyze(g.votes, DefaultOptions())
+	assert.Equal(t, [][]uint{{2001, 2002, 2003}}, r.SockpuppetGroups)
+	require.Len(t, r.SimilarPairs, 3)
+	assert.Equal(t, SimilarPair{A: 2001, B: 2002, Shared: 15, Jaccard: 1}, r.SimilarPairs[0])
+	assert.InDelta(t, 15.0/16.0, r.SimilarPairs[1].Jaccard, 1e-9)
+
+	require.Len(t, r.Suspects, 3)
+	for _, s := range r.Suspects {
+		assert.Equal(t, []string{ReasonSockpuppet}, s.Reasons)
+		assert.InDelta(t, 0.1, s.Penalty, 1e-9)
+	}
+}
+
+func TestPenaltiesAreCapped(t *testing.T) {
+	g := newGraph()
+	// 1、2 互相刷赞，同时给 3 的评分投出相同的票，Jaccard 相似度为 10/30
+	for i := 0; i < 10; i++ {
+		g.vote(1, 2, i, 1)
+		g.vote(2, 1, i, 1)
+		g.vote(1, 3, i, 1)
+		g.vote(2, 3, i, 1)
+	}
+	opts := DefaultOptions()
+	opts.MinVotesSimilarity = 5
+	opts.SimilarityThreshold = 0.3
+	opts.ClusterPenalty = 0.2
+	opts.SimilarityPenalty = 0.2
+	opts.MaxPenalty = 0.3
+
+	r := Analyze(g.votes, opts)
+	require.Len(t, r.Suspects, 2)
+	for _, s := range r.Suspects {
+		assert.Equal(t, []string{ReasonVoteRing, ReasonSockpuppet}, s.Reasons)
+		assert.InDelta(t, 0.3, s.Penalty, 1e-9)
+	}
+}
+
+func TestSelfVotesAreIgnored(t *testing.T) {
+	g := newGraph()
+	for i := 0; i < 20; i++ {
+		g.vote(1, 1, i, 1)
+	}
+	r := Analyze(g.votes, DefaultOptions())
+	assert.Empty(t, r.Clusters)
+	assert.Empty(t, r.SimilarPairs)
+	assert.Equal(t, 20, r.VotesAnalyzed)
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"github.com/novel/internal/model"
+	"gorm.io/gorm"
+	"time"
+)
+
+// BaselineStats 是某一范围内已有评分的小说的汇总数据
+type BaselineStats struct {
+	ScopeID     uint
+	NovelsCount int
+	WeightSum   float64
+	WeightedSum float64
+	M           float64 // v_w 的分位数
+}
+
+// BaselineRepository 定义了评分基准的统计与存取
+type BaselineRepository interface {
+	StatsByCategory(mPercentile float64) ([]BaselineStats, error)
+	StatsByPublicationType(mPercentile float64) ([]BaselineStats, error)
+	ReplaceAll(baselines []model.ScoreBaseline) error
+	FindAll() ([]model.ScoreBaseline, error)
+}
+
+type baselineRepository struct {
+	db *gorm.DB
+}
+
+// NewBaselineRepository 是 baselineRepository 的构造函数
+func NewBaselineRepository(db *gorm.DB) BaselineRepository {
+	return &baselineRepository{db: db}
+}
+
+// StatsByCategory 按分类汇总已有评分的小说，mPercentile 为计算 m 时使用的 v_w 分位数
+func (r *baselineRepository) StatsByCategory(mPercentile float64) ([]BaselineStats, error) {
+	return r.stats("category_id", mPercentile)
+}
+
+// StatsByPublicationType 按出版类型汇总已有评分的小说
+func (r *baselineRepository) StatsByPublicationType(mPercentile float64) ([]BaselineStats, error) {
+	return r.stats("publication_type", mPercentile)
+}
+
+// stats 按 column 分组汇总，column 只由本文件内部传入
+func (r *baselineRepository) stats(column string, mPercentile float64) ([]BaselineStats, error) {
+	var stats []BaselineStats
+	err := r.db.Model(&model.Novel{}).
+		Select(column+` AS scope_id,
+			COUNT(*) AS novels_count,
+			SUM(score_weight_sum) AS weight_sum,
+			SUM(score_weighted_sum) AS weighted_sum,
+			percentile_cont(?) WITHIN GROUP (ORDER BY score_weight_sum) AS m`, mPercentile).
+		Where("score_weight_sum > 0").
+		Group(column).
+		Scan(&stats).Error
+	return stats, err
+}
+
+// ReplaceAll 用新计算的结果整体替换已有的评分基准
+func (r *baselineRepository) ReplaceAll(baselines []model.ScoreBaseline) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&model.ScoreBaseline{}).Error; err != nil {
+			return err
+		}
+		if len(baselines) == 0 {
+			return nil
+		}
+		now := time.Now()
+		for i := range baselines {
+			baselines[i].ComputedAt = now
+		}
+		return tx.Create(&baselines).Error
+	})
+}
+
+// FindAll 返回全部评分基准
+func (r *baselineRepository) FindAll() ([]model.ScoreBaseline, error) {
+	var baselines []model.ScoreBaseline
+	err := r.db.Find(&baselines).Error
+	return baselines, err
+}
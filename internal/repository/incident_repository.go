@@ -0,0 +1,202 @@
+package repository
+
+import (
+	"github.com/novel/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+// RatingWindowStats 是小说在一段时间内新增评分的统计
+type RatingWindowStats struct {
+	Count         int
+	MeanScore     float64
+	LowTrustCount int // 作者信誉分低于阈值的评分数
+}
+
+// SuspicionCriteria 描述异常事件中哪些评分被视为可疑：
+// 窗口内由低信誉用户给出、且相对参考分向事件方向偏离至少 MinDeviation 的评分
+type SuspicionCriteria struct {
+	NovelID       uint
+	Since         time.Time
+	LowTrustScore float64
+	Reference     float64
+	Direction     int
+	MinDeviation  float64
+}
+
+// IncidentRepository 定义了评分异常事件的数据库操作
+type IncidentRepository interface {
+	WithTx(tx *gorm.DB) IncidentRepository
+	Create(incident *model.ScoreIncident) error
+	Save(incident *model.ScoreIncident) error
+	FindByID(id uint) (*model.ScoreIncident, error)
+	FindByIDForUpdate(id uint) (*model.ScoreIncident, error)
+	FindOpenByNovel(novelID uint) (*model.ScoreIncident, error)
+	FindExpired(now time.Time, limit int) ([]model.ScoreIncident, error)
+	FindAll(status model.IncidentStatus, page, pageSize int) ([]model.ScoreIncident, int64, error)
+	RatingWindowStats(novelID uint, since time.Time, lowTrustScore float64) (*RatingWindowStats, error)
+	CountRatingsBetween(novelID uint, from, to time.Time) (int64, error)
+	ScoreBefore(novelID uint, before time.Time) (*float64, error)
+	MarkSuspicious(incidentID uint, criteria *SuspicionCriteria) ([]model.Rating, error)
+	MarkRatingSuspicious(incidentID, ratingID uint) error
+	ClearSuspicious(incidentID uint) ([]model.Rating, error)
+	FindSuspiciousRatings(incidentID uint) ([]model.Rating, error)
+}
+
+type incidentRepository struct {
+	db *gorm.DB
+}
+
+// NewIncidentRepository 是 incidentRepository 的构造函数
+func NewIncidentRepository(db *gorm.DB) IncidentRepository {
+	return &incidentRepository{db: db}
+}
+
+// WithTx 返回一个绑定到给定事务的 IncidentRepository
+func (r *incidentRepository) WithTx(tx *gorm.DB) IncidentRepository {
+	return &incidentRepository{db: tx}
+}
+
+// Create 创建异常事件，小说已有待处理事件时返回 gorm.ErrDuplicatedKey
+func (r *incidentRepository) Create(incident *model.ScoreIncident) error {
+	return r.db.Create(incident).Error
+}
+
+// Save 保存异常事件的全部字段
+func (r *incidentRepository) Save(incident *model.ScoreIncident) error {
+	return r.db.Save(incident).Error
+}
+
+func (r *incidentRepository) FindByID(id uint) (*model.ScoreIncident, error) {
+	var incident model.ScoreIncident
+	err := r.db.First(&incident, id).Error
+	return &incident, err
+}
+
+// FindByIDForUpdate 查找并锁定异常事件，防止同一事件被重复处理
+func (r *incidentRepository) FindByIDForUpdate(id uint) (*model.ScoreIncident, error) {
+	var incident model.ScoreIncident
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&incident, id).Error
+	return &incident, err
+}
+
+// FindOpenByNovel 查找小说待处理的异常事件
+func (r *incidentRepository) FindOpenByNovel(novelID uint) (*model.ScoreIncident, error) {
+	var incident model.ScoreIncident
+	err := r.db.Where("novel_id = ? AND status = ?", novelID, model.IncidentStatusOpen).First(&incident).Error
+	return &incident, err
+}
+
+// FindExpired 返回冻结已到期但仍待处理的事件，最早到期的在前
+func (r *incidentRepository) FindExpired(now time.Time, limit int) ([]model.ScoreIncident, error) {
+	var incidents []model.ScoreIncident
+	err := r.db.Where("status = ? AND frozen_until <= ?", model.IncidentStatusOpen, now).
+		Order("frozen_until").Limit(limit).
+		Find(&incidents).Error
+	return incidents, err
+}
+
+// FindAll 按创建时间倒序分页查询异常事件，status 为空时查询全部
+func (r *incidentRepository) FindAll(status model.IncidentStatus, page, pageSize int) ([]model.ScoreIncident, int64, error) {
+	db := r.db.Model(&model.ScoreIncident{})
+	if status != "" {
+		db = db.Where("status = ?", status)
+	}
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	incidents := []model.ScoreIncident{}
+	err := db.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&incidents).Error
+	return incidents, total, err
+}
+
+// RatingWindowStats 统计小说自 since 以来新增的有效评分
+func (r *incidentRepository) RatingWindowStats(novelID uint, since time.Time, lowTrustScore float64) (*RatingWindowStats, error) {
+	var stats RatingWindowStats
+	err := r.db.Model(&model.Rating{}).
+		Select(`COUNT(*) AS count,
+			COALESCE(AVG(ratings.score), 0) AS mean_score,
+			COUNT(*) FILTER (WHERE users.trust_score < ?) AS low_trust_count`, lowTrustScore).
+		Joins("JOIN users ON users.id = ratings.user_id").
+		Where("ratings.novel_id = ? AND ratings.created_at > ?", novelID, since).
+		Scan(&stats).Error
+	return &stats, err
+}
+
+// CountRatingsBetween 统计小说在 [from, to) 之间新增的有效评分数
+func (r *incidentRepository) CountRatingsBetween(novelID uint, from, to time.Time) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.Rating{}).
+		Where("novel_id = ? AND created_at >= ? AND created_at < ?", novelID, from, to).
+		Count(&count).Error
+	return count, err
+}
+
+// ScoreBefore 返回小说在 before 之前最近一次快照中的分数，没有快照时返回 nil
+func (r *incidentRepository) ScoreBefore(novelID uint, before time.Time) (*float64, error) {
+	var snapshots []model.NovelSnapshot
+	err := r.db.Where("novel_id = ? AND date < ?::date", novelID, before.Format(time.DateOnly)).
+		Order("date DESC").Limit(1).
+		Find(&snapshots).Error
+	if err != nil || len(snapshots) == 0 {
+		return nil, err
+	}
+	return &snapshots[0].WeightedScore, nil
+}
+
+// MarkSuspicious 把符合条件、且尚未被标记的评分标记为属于该事件，返回被标记的评分 (含标记前的权重)
+func (r *incidentRepository) MarkSuspicious(incidentID uint, c *SuspicionCriteria) ([]model.Rating, error) {
+	var ratings []model.Rating
+	err := r.db.Model(&model.Rating{}).
+		Select("ratings.id, ratings.user_id, ratings.weight").
+		Joins("JOIN users ON users.id = ratings.user_id").
+		Where("ratings.novel_id = ? AND ratings.created_at > ? AND ratings.suspected_incident_id IS NULL", c.NovelID, c.Since).
+		Where("users.trust_score < ?", c.LowTrustScore).
+		Where("(ratings.score - ?) * ? >= ?", c.Reference, c.Direction, c.MinDeviation).
+		Find(&ratings).Error
+	if err != nil || len(ratings) == 0 {
+		return ratings, err
+	}
+	ids := make([]uint, len(ratings))
+	for i, rating := range ratings {
+		ids[i] = rating.ID
+	}
+	err = r.db.Model(&model.Rating{}).Where("id IN ?", ids).Update("suspected_incident_id", incidentID).Error
+	return ratings, err
+}
+
+// MarkRatingSuspicious 把单条评分标记为属于该事件，并累加事件的可疑评分数
+func (r *incidentRepository) MarkRatingSuspicious(incidentID, ratingID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.Rating{}).
+			Where("id = ? AND suspected_incident_id IS NULL", ratingID).
+			Update("suspected_incident_id", incidentID)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return tx.Model(&model.ScoreIncident{}).Where("id = ?", incidentID).
+			Update("suspicious_count", gorm.Expr("suspicious_count + 1")).Error
+	})
+}
+
+// ClearSuspicious 清除属于该事件的可疑标记，返回被清除标记的评分 (含清除前的权重)
+func (r *incidentRepository) ClearSuspicious(incidentID uint) ([]model.Rating, error) {
+	ratings, err := r.FindSuspiciousRatings(incidentID)
+	if err != nil || len(ratings) == 0 {
+		return ratings, err
+	}
+	err = r.db.Model(&model.Rating{}).Where("suspected_incident_id = ?", incidentID).Update("suspected_incident_id", nil).Error
+	return ratings, err
+}
+
+// FindSuspiciousRatings 返回属于该事件的全部有效评分，并预加载作者
+func (r *incidentRepository) FindSuspiciousRatings(incidentID uint) ([]model.Rating, error) {
+	ratings := []model.Rating{}
+	err := r.db.Preload("User", func(tx *gorm.DB) *gorm.DB { return tx.Unscoped().Select("id", "username", "trust_score") }).
+		Where("suspected_incident_id = ?", incidentID).
+		Order("id").
+		Find(&ratings).Error
+	return ratings, err
+}
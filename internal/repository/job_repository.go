@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"github.com/novel/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+// JobRepository 定义了后台任务表的数据库操作接口
+type JobRepository interface {
+	WithTx(tx *gorm.DB) JobRepository
+	Enqueue(jobs ...*model.Job) error
+	ClaimDue(workerID string, limit int) ([]model.Job, error)
+	MarkDone(id uint) error
+	MarkRetry(id uint, lastErr string, runAt time.Time) error
+	MarkDead(id uint, lastErr string) error
+	ExtendLease(id uint, workerID string) (bool, error)
+	RequeueStale(lockedBefore time.Time) (int64, error)
+	PurgeDone(finishedBefore time.Time) (int64, error)
+}
+
+type jobRepository struct {
+	db *gorm.DB
+}
+
+func NewJobRepository(db *gorm.DB) JobRepository {
+	return &jobRepository{db: db}
+}
+
+// WithTx 返回一个绑定到给定事务的 JobRepository，用于与业务数据在同一事务中写入任务
+func (r *jobRepository) WithTx(tx *gorm.DB) JobRepository {
+	return &jobRepository{db: tx}
+}
+
+// Enqueue 写入待执行的任务
+func (r *jobRepository) Enqueue(jobs ...*model.Job) error {
+	if len(jobs) == 0 {
+		return nil
+	}
+	now := time.Now()
+	for _, job := range jobs {
+		job.Status = model.JobStatusPending
+		if job.RunAt.IsZero() {
+			job.RunAt = now
+		}
+	}
+	return r.db.Create(jobs).Error
+}
+
+// ClaimDue 领取最多 limit 个已到期的任务，并将其标记为执行中
+// 使用 FOR UPDATE SKIP LOCKED，多个 worker (包括多个进程) 可以安全地并发领取
+func (r *jobRepository) ClaimDue(workerID string, limit int) ([]model.Job, error) {
+	var jobs []model.Job
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND run_at <= ?", model.JobStatusPending, now).
+			Order("run_at, id").
+			Limit(limit).
+			Find(&jobs).Error
+		if err != nil || len(jobs) == 0 {
+			return err
+		}
+
+		ids := make([]uint, len(jobs))
+		for i := range jobs {
+			ids[i] = jobs[i].ID
+			jobs[i].Status = model.JobStatusRunning
+			jobs[i].Attempts++
+			jobs[i].LockedBy = workerID
+			jobs[i].LockedAt = &now
+		}
+		return tx.Model(&model.Job{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+			"status":    model.JobStatusRunning,
+			"attempts":  gorm.Expr("attempts + 1"),
+			"locked_by": workerID,
+			"locked_at": now,
+		}).Error
+	})
+	return jobs, err
+}
+
+// MarkDone 将任务标记为执行成功
+func (r *jobRepository) MarkDone(id uint) error {
+	return r.db.Model(&model.Job{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":      model.JobStatusDone,
+		"last_error":  "",
+		"finished_at": time.Now(),
+	}).Error
+}
+
+// MarkRetry 记录失败原因，并将任务放回队列在 runAt 之后重试
+func (r *jobRepository) MarkRetry(id uint, lastErr string, runAt time.Time) error {
+	return r.db.Model(&model.Job{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":     model.JobStatusPending,
+		"last_error": lastErr,
+		"run_at":     runAt,
+		"locked_by":  "",
+		"locked_at":  nil,
+	}).Error
+}
+
+// MarkDead 将任务移入死信状态，不再自动重试
+func (r *jobRepository) MarkDead(id uint, lastErr string) error {
+	return r.db.Model(&model.Job{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":      model.JobStatusDead,
+		"last_error":  lastErr,
+		"finished_at": time.Now(),
+	}).Error
+}
+
+// ExtendLease 刷新 worker 仍在执行的任务的领取时间，避免执行时间较长的任务被当作失效而重新入队
+// 任务已不再由该 worker 持有 (例如已被重新入队) 时返回 false
+func (r *jobRepository) ExtendLease(id uint, workerID string) (bool, error) {
+	result := r.db.Model(&model.Job{}).
+		Where("id = ? AND status = ? AND locked_by = ?", id, model.JobStatusRunning, workerID).
+		Update("locked_at", time.Now())
+	return result.RowsAffected > 0, result.Error
+}
+
+// RequeueStale 将领取时间早于 lockedBefore 仍未完成的任务放回队列
+// 这些任务通常属于已经崩溃或未能在关闭前完成的 worker
+func (r *jobRepository) RequeueStale(lockedBefore time.Time) (int64, error) {
+	result := r.db.Model(&model.Job{}).
+		Where("status = ? AND locked_at < ?", model.JobStatusRunning, lockedBefore).
+		Updates(map[string]interface{}{
+			"status":    model.JobStatusPending,
+			"run_at":    time.Now(),
+			"locked_by": "",
+			"locked_at": nil,
+		})
+	return result.RowsAffected, result.Error
+}
+
+// PurgeDone 删除完成时间早于 finishedBefore 的成功任务，死信任务会被保留
+func (r *jobRepository) PurgeDone(finishedBefore time.Time) (int64, error) {
+	result := r.db.Where("status = ? AND finished_at < ?", model.JobStatusDone, finishedBefore).
+		Delete(&model.Job{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"strings"
+)
+
+var (
+	// ErrUnknownSortKey 表示客户端请求了未声明的排序方式
+	ErrUnknownSortKey = errors.New("unknown sort key")
+	// ErrKeysetMismatch 表示键集分页的位置与本次查询的排序方式不一致
+	ErrKeysetMismatch = errors.New("keyset does not match sort order")
+)
+
+// Keyset 记录键集 (游标) 分页中上一页最后一行的位置
+type Keyset struct {
+	Sort  string `json:"s"`
+	Desc  bool   `json:"d"`
+	Value string `json:"v,omitempty"` // 该行排序表达式的值，以 PostgreSQL 的文本形式保存，保证比较时精度不丢失；不公开的排序值为空
+	ID    uint   `json:"id"`
+}
+
+// sortOption 描述一种对外暴露的排序方式
+// expr 是服务端写死的 SQL 表达式，客户端只能通过排序键选择，不能直接传入列名
+type sortOption struct {
+	expr     string
+	sqlType  string // 表达式的类型，用于把 Keyset 中的文本值转换回来
+	desc     bool   // 未指定 order 时的默认方向
+	useQuery bool   // 表达式中的 ? 需要用本次查询的 tsquery 填充
+	// 排序值不能暴露给客户端 (例如评分权重)：游标只记录主键，翻页时从数据库重新读取该行的排序值
+	// 游标只签名、不加密，客户端可以解码其中的内容
+	hidden bool
+}
+
+// resolvedSort 是一次查询最终确定的排序方式
+type resolvedSort struct {
+	sortOption
+	key      string
+	desc     bool
+	idColumn string // 用于打破平局的主键列
+	tsQuery  string
+}
+
+// resolveSort 在 options 中查找排序键并确定排序方向，order 为空时使用排序方式的默认方向
+func resolveSort(options map[string]sortOption, idColumn, key, order, tsQuery string) (*resolvedSort, error) {
+	opt, ok := options[key]
+	if !ok || (opt.useQuery && tsQuery == "") {
+		return nil, ErrUnknownSortKey
+	}
+	desc := opt.desc
+	switch order {
+	case "asc":
+		desc = false
+	case "desc":
+		desc = true
+	}
+	return &resolvedSort{sortOption: opt, key: key, desc: desc, idColumn: idColumn, tsQuery: tsQuery}, nil
+}
+
+// exprVars 返回排序表达式需要的参数
+func (s *resolvedSort) exprVars() []interface{} {
+	if s.useQuery {
+		return []interface{}{s.tsQuery}
+	}
+	return nil
+}
+
+// orderBy 生成 ORDER BY 子句，排序值相同时按主键以相同方向排序，保证分页结果稳定
+func (s *resolvedSort) orderBy() clause.OrderBy {
+	direction := " ASC"
+	if s.desc {
+		direction = " DESC"
+	}
+	return clause.OrderBy{Expression: clause.Expr{
+		SQL:                s.expr + direction + ", " + s.idColumn + direction,
+		Vars:               s.exprVars(),
+		WithoutParentheses: true,
+	}}
+}
+
+// after 生成只保留 keyset 之后各行的条件，利用行比较同时比较排序值和主键
+func (s *resolvedSort) after(keyset *Keyset) (clause.Expr, error) {
+	if keyset.Sort != s.key || keyset.Desc != s.desc {
+		return clause.Expr{}, ErrKeysetMismatch
+	}
+	op := ">"
+	if s.desc {
+		op = "<"
+	}
+	if s.hidden {
+		// 子查询中的表名遮蔽外层查询的同名表，s.expr 引用的是 keyset 所在的行；软删除的行仍可读取到排序值
+		table, _, _ := strings.Cut(s.idColumn, ".")
+		return clause.Expr{
+			SQL:  "(" + s.expr + ", " + s.idColumn + ") " + op + " ((SELECT " + s.expr + " FROM " + table + " WHERE " + s.idColumn + " = ?), ?)",
+			Vars: []interface{}{keyset.ID, keyset.ID},
+		}, nil
+	}
+	return clause.Expr{
+		SQL:  "(" + s.expr + ", " + s.idColumn + ") " + op + " (CAST(? AS " + s.sqlType + "), ?)",
+		Vars: append(s.exprVars(), keyset.Value, keyset.ID),
+	}, nil
+}
+
+// nextKeyset 读取 lastID 所在行的排序值，返回指向该行的位置，作为下一页的起点
+func (s *resolvedSort) nextKeyset(db *gorm.DB, model interface{}, lastID uint) (*Keyset, error) {
+	if s.hidden {
+		return &Keyset{Sort: s.key, Desc: s.desc, ID: lastID}, nil
+	}
+	var value string
+	err := db.Model(model).Select("CAST("+s.expr+" AS text)", s.exprVars()...).
+		Where(s.idColumn+" = ?", lastID).Scan(&value).Error
+	if err != nil {
+		return nil, err
+	}
+	return &Keyset{Sort: s.key, Desc: s.desc, Value: value, ID: lastID}, nil
+}
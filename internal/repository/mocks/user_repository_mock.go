@@ -4,6 +4,9 @@ import (
 	"github.com/novel/internal/model"
 	"github.com/novel/internal/repository"
 	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+	"sort"
+	"time"
 )
 
 // UserRepositoryMock 是一个 UserRepository 的模拟实现
@@ -16,6 +19,11 @@ var _ repository.UserRepository = (*UserRepositoryMock)(nil)
 
 // --- 为接口中的每一个方法，都创建一个对应的模拟方法 ---
 
+// WithTx 返回 mock 自身，事务内外的调用记录在同一处
+func (m *UserRepositoryMock) WithTx(tx *gorm.DB) repository.UserRepository {
+	return m
+}
+
 func (m *UserRepositoryMock) Create(user *model.User) error {
 	// m.Called 会记录这次调用，并返回我们预设的结果
 	args := m.Called(user)
@@ -27,6 +35,11 @@ func (m *UserRepositoryMock) Update(user *model.User) error {
 	return args.Error(0)
 }
 
+func (m *UserRepositoryMock) UpdateRole(userID uint, role model.Role) error {
+	args := m.Called(userID, role)
+	return args.Error(0)
+}
+
 func (m *UserRepositoryMock) FindByID(id uint) (*model.User, error) {
 	args := m.Called(id)
 	// 如果第一个返回值不是nil，则进行类型断言
@@ -51,3 +64,74 @@ func (m *UserRepositoryMock) FindByIDWithRatings(id uint) (*model.User, error) {
 	}
 	return args.Get(0).(*model.User), args.Error(1)
 }
+
+func (m *UserRepositoryMock) FindIDsAfter(lastID uint, limit int) ([]uint, error) {
+	args := m.Called(lastID, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]uint), args.Error(1)
+}
+
+func (m *UserRepositoryMock) FindPenalizedIDs() ([]uint, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]uint), args.Error(1)
+}
+
+func (m *UserRepositoryMock) FindActivityStats(userID uint) (*repository.UserActivityStats, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*repository.UserActivityStats), args.Error(1)
+}
+
+func (m *UserRepositoryMock) EndProbation(userID uint, at time.Time) (bool, error) {
+	args := m.Called(userID, at)
+	return args.Bool(0), args.Error(1)
+}
+
+// UpdateTrustScores 按 ID 顺序用 FindByID 的预设返回值模拟加锁读取，执行 update 后
+// 以 "UpdateTrustScore" 为方法名记录每个被保存的用户与变动记录
+func (m *UserRepositoryMock) UpdateTrustScores(updates map[uint]repository.TrustUpdate) error {
+	ids := make([]uint, 0, len(updates))
+	for id := range updates {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for _, id := range ids {
+		user, err := m.FindByID(id)
+		if err != nil {
+			return err
+		}
+		change := updates[id](user)
+		if err := m.MethodCalled("UpdateTrustScore", user, change).Error(0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *UserRepositoryMock) FindTrustChanges(userID uint, limit int) ([]model.TrustChange, error) {
+	args := m.Called(userID, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.TrustChange), args.Error(1)
+}
+
+func (m *UserRepositoryMock) FindLastActivityAt(userID uint) (time.Time, error) {
+	args := m.Called(userID)
+	return args.Get(0).(time.Time), args.Error(1)
+}
+
+func (m *UserRepositoryMock) FindInactiveIDsAfter(lastID uint, activeSince time.Time, limit int) ([]uint, error) {
+	args := m.Called(lastID, activeSince, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]uint), args.Error(1)
+}
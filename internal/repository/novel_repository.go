@@ -1,24 +1,89 @@
 package repository
 
 import (
-	"fmt"
+	"errors"
 	"github.com/novel/internal/dto"
 	"github.com/novel/internal/model"
+	"github.com/novel/internal/pkg/search"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"strings"
+	"time"
 )
 
 // NovelRepository 定义了与小说相关的数据库操作接口
 type NovelRepository interface {
-	FindAll(query *dto.ListQuery) ([]model.Novel, int64, error)
+	WithTx(tx *gorm.DB) NovelRepository
+	FindAll(query *dto.ListQuery, after *Keyset) (*NovelPage, error)
 	FindByID(id uint) (*model.Novel, error)
 	CreateRating(rating *model.Rating) error
-	FindByIDWithRatings(id uint) (*model.Novel, error)
-	Update(novel *model.Novel) error
+	FindIDsAfter(lastID uint, limit int) ([]uint, error)
 	FindRatingByID(id uint) (*model.Rating, error)
-	FindUserVote(userID, ratingID uint) (*model.RatingVote, error)
-	UpdateRatingVote(rating *model.Rating, oldVote, newVote *model.RatingVote) error
-	UpdateRating(rating *model.Rating) error
+	FindRatingWeightsByUser(userID uint) ([]model.Rating, error)
+	FindRatingsByNovel(novelID uint, query *dto.RatingListQuery, after *Keyset) (*RatingPage, error)
+	RatingStats(novelID uint) (*dto.RatingStats, error)
+	FindTopWeightedRatings(novelID uint, limit int) ([]model.Rating, error)
+	FindRatingByIDForUpdate(id uint) (*model.Rating, error)
+	ApplyRatingVote(userID, ratingID uint, voteType model.VoteType, voterWeight float64) (*VoteOutcome, error)
+	SetRatingWeight(ratingID uint, weight float64) (*model.Rating, error)
+	ApplyScoreDelta(novelID uint, delta ScoreDelta, score ScoreFunc) (*model.Novel, error)
+	RebuildScoreAggregates(novelID uint, score ScoreFunc) (*model.Novel, error)
+	ApplyTrending(novelID uint, update func(novel *model.Novel)) error
+	FreezeScore(novelID uint, score float64, until time.Time) error
+	UnfreezeScore(novelID uint, score ScoreFunc) (*model.Novel, error)
+	FindTrendingEvents(novelID uint, since time.Time) ([]TrendingEvent, error)
+	FindUserRatingForNovel(userID, novelID uint) (*model.Rating, error)
+	UpdateRatingContent(rating *model.Rating) error
+	CountEarlierRatingsWithCommentHash(hash string, beforeID uint) (int64, error)
+	DeleteRatingWithVotes(rating *model.Rating) error
 	CreateInTx(novel *model.Novel) error
+	UpdateInTx(novel *model.Novel, tags []*model.Tag) error
+	DeleteWithRatings(id uint) error
+	RestoreWithRatings(id uint) (*model.Novel, error)
+}
+
+// NovelPage 是一页小说列表的查询结果
+type NovelPage struct {
+	Novels []model.Novel
+	Total  *int64  // 键集分页时不统计总数，为 nil
+	Next   *Keyset // 还有下一页时指向本页的最后一行，否则为 nil
+}
+
+// RatingPage 是一页评分列表的查询结果，各字段含义与 NovelPage 相同
+type RatingPage struct {
+	Ratings []model.Rating
+	Total   *int64
+	Next    *Keyset
+}
+
+// ScoreDelta 描述一次评分变动对小说评分聚合值的增量
+type ScoreDelta struct {
+	Count       int     // 有效评分数的变化
+	WeightSum   float64 // Σ权重 的变化
+	WeightedSum float64 // Σ(分值×权重) 的变化
+}
+
+// ScoreFunc 根据小说当前的评分聚合值计算最终的加权分
+type ScoreFunc func(novel *model.Novel) float64
+
+// TrendingEvent 是计入热度的一次事件：一条有效评分，或评分收到的一张有效投票
+type TrendingEvent struct {
+	Vote model.VoteType // 为 0 表示评分本身
+	At   time.Time      // 事件发生的时刻，投票以最后一次投出或改票的时刻为准
+}
+
+// trendingColumns 是热度排序键字段，只能通过 ApplyTrending 修改
+var trendingColumns = []string{"trending_day", "trending_week", "trending_month"}
+
+// scoreAggregateColumns 是评分聚合相关的字段，只能通过 ApplyScoreDelta / RebuildScoreAggregates 修改
+var scoreAggregateColumns = []string{"ratings_count", "score_weight_sum", "score_weighted_sum", "weighted_score"}
+
+// novelEditableColumns 是允许通过编辑接口修改的小说字段
+// 评分聚合字段只由评分流程和后台计算任务维护，不在此列
+var novelEditableColumns = []string{
+	"title", "author", "description", "cover_image_url", "publication_type",
+	"publisher", "isbn", "word_count", "publication_site", "serialization_status",
+	"category_id", "title_sort_key",
 }
 
 // novelRepository 结构体实现了 NovelRepository 接口
@@ -31,9 +96,15 @@ func NewNovelRepository(db *gorm.DB) NovelRepository {
 	return &novelRepository{db: db}
 }
 
-func (r *novelRepository) FindAll(query *dto.ListQuery) ([]model.Novel, int64, error) {
+// WithTx 返回一个绑定到给定事务的 NovelRepository
+func (r *novelRepository) WithTx(tx *gorm.DB) NovelRepository {
+	return &novelRepository{db: tx}
+}
+
+// FindAll 查询一页小说列表
+// after 为 nil 时按 page/page_size 偏移分页并统计总数；否则从 after 指向的位置之后继续 (键集分页)，不统计总数
+func (r *novelRepository) FindAll(query *dto.ListQuery, after *Keyset) (*NovelPage, error) {
 	var novels []model.Novel
-	var total int64
 
 	// 1. 构建基础查询，并预加载关联数据以备前端展示
 	db := r.db.Model(&model.Novel{}).Preload("Category").Preload("Tags")
@@ -55,23 +126,161 @@ func (r *novelRepository) FindAll(query *dto.ListQuery) ([]model.Novel, int64, e
 			Having("COUNT(novels.id) = ?", len(query.TagIDs))
 	}
 
-	// 3. 计算总数 (在应用分页之前)
-	if err := db.Count(&total).Error; err != nil {
-		// 注意：对于复杂的JOIN查询，Count可能会变慢或不准，需要特别注意
-		// 暂时我们先这样实现，后续可优化
-		return nil, 0, err
+	// 全文检索
+	tsQuery, hasQuery := "", false
+	if strings.TrimSpace(query.Q) != "" {
+		tsQuery, hasQuery = search.TSQuery(query.Q)
+		db = db.Where("novels.search_vector @@ ?::tsquery", tsQuery)
+	}
+
+	// 排序键在查询数据库之前校验，未知的排序方式直接返回错误
+	sort, err := resolveNovelSort(query.SortBy, query.Order, tsQuery)
+	if err != nil {
+		return nil, err
+	}
+	page := &NovelPage{Novels: []model.Novel{}}
+	// 搜索词中没有可用的词时不返回任何结果
+	if strings.TrimSpace(query.Q) != "" && !hasQuery {
+		if after == nil {
+			page.Total = new(int64)
+		}
+		return page, nil
+	}
+
+	if after == nil {
+		// 3. 计算总数 (在应用分页之前)
+		var total int64
+		if err := db.Count(&total).Error; err != nil {
+			// 注意：对于复杂的JOIN查询，Count可能会变慢或不准，需要特别注意
+			// 暂时我们先这样实现，后续可优化
+			return nil, err
+		}
+		page.Total = &total
+		db = db.Offset((query.Page - 1) * query.PageSize)
+	} else {
+		cond, err := sort.after(after)
+		if err != nil {
+			return nil, err
+		}
+		db = db.Where(cond)
+	}
+
+	// 4. 应用排序和分页，多取一行用于判断是否还有下一页
+	err = db.Clauses(sort.orderBy()).Limit(query.PageSize + 1).Find(&novels).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(novels) <= query.PageSize {
+		page.Novels = novels
+		return page, nil
+	}
+	page.Novels = novels[:query.PageSize]
+
+	// 5. 以本页最后一行作为下一页的起点
+	page.Next, err = sort.nextKeyset(r.db, &model.Novel{}, page.Novels[len(page.Novels)-1].ID)
+	if err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// FindRatingsByNovel 查询一页小说的有效评分，并预加载评分作者的公开信息
+// 分页方式与 FindAll 相同
+func (r *novelRepository) FindRatingsByNovel(novelID uint, query *dto.RatingListQuery, after *Keyset) (*RatingPage, error) {
+	sort, err := resolveRatingSort(query.SortBy, query.Order)
+	if err != nil {
+		return nil, err
 	}
+	db := r.db.Model(&model.Rating{}).Where("ratings.novel_id = ?", novelID)
+	page := &RatingPage{Ratings: []model.Rating{}}
+
+	if after == nil {
+		var total int64
+		if err := db.Count(&total).Error; err != nil {
+			return nil, err
+		}
+		page.Total = &total
+		db = db.Offset((query.Page - 1) * query.PageSize)
+	} else {
+		cond, err := sort.after(after)
+		if err != nil {
+			return nil, err
+		}
+		db = db.Where(cond)
+	}
+
+	var ratings []model.Rating
+	err = db.Preload("User", func(tx *gorm.DB) *gorm.DB {
+		// 作者注销后仍展示其用户名
+		return tx.Unscoped().Select("id", "username", "trust_score")
+	}).Clauses(sort.orderBy()).Limit(query.PageSize + 1).Find(&ratings).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(ratings) <= query.PageSize {
+		page.Ratings = ratings
+		return page, nil
+	}
+	page.Ratings = ratings[:query.PageSize]
+	page.Next, err = sort.nextKeyset(r.db, &model.Rating{}, page.Ratings[len(page.Ratings)-1].ID)
+	if err != nil {
+		return nil, err
+	}
+	return page, nil
+}
 
-	// 4. 应用排序和分页
-	orderClause := fmt.Sprintf("%s %s", query.SortBy, query.Order)
-	offset := (query.Page - 1) * query.PageSize
+// RatingStats 使用 SQL 聚合计算小说有效评分的分布与统计值，不加载评分记录
+func (r *novelRepository) RatingStats(novelID uint) (*dto.RatingStats, error) {
+	// 聚合结果先读入不含切片字段的结构体，GORM 无法解析 dto.RatingStats 中的 Histogram
+	var agg struct {
+		Count        int64
+		Mean         *float64
+		Median       *float64
+		WeightedMean *float64
+		StdDev       *float64
+	}
+	err := r.db.Model(&model.Rating{}).
+		Select(`COUNT(*) AS count,
+			AVG(score)::float8 AS mean,
+			percentile_cont(0.5) WITHIN GROUP (ORDER BY score) AS median,
+			(SUM(score * weight) / NULLIF(SUM(weight), 0))::float8 AS weighted_mean,
+			stddev_pop(score)::float8 AS std_dev`).
+		Where("novel_id = ?", novelID).
+		Scan(&agg).Error
+	if err != nil {
+		return nil, err
+	}
+	stats := &dto.RatingStats{
+		Count:        agg.Count,
+		Mean:         agg.Mean,
+		Median:       agg.Median,
+		WeightedMean: agg.WeightedMean,
+		StdDev:       agg.StdDev,
+	}
 
-	err := db.Order(orderClause).Limit(query.PageSize).Offset(offset).Find(&novels).Error
+	var buckets []dto.ScoreBucket
+	err = r.db.Model(&model.Rating{}).Select("score, COUNT(*) AS count").
+		Where("novel_id = ?", novelID).Group("score").Scan(&buckets).Error
 	if err != nil {
-		return nil, 0, err
+		return nil, err
+	}
+	stats.Histogram = make([]dto.ScoreBucket, 10)
+	for i := range stats.Histogram {
+		stats.Histogram[i].Score = i + 1
+	}
+	for _, b := range buckets {
+		if b.Score >= 1 && b.Score <= 10 {
+			stats.Histogram[b.Score-1].Count = b.Count
+		}
 	}
+	return stats, nil
+}
 
-	return novels, total, nil
+// FindTopWeightedRatings 查询小说中权重最高的若干条有效评分，并预加载评分作者
+func (r *novelRepository) FindTopWeightedRatings(novelID uint, limit int) ([]model.Rating, error) {
+	var ratings []model.Rating
+	err := r.db.Preload("User", func(tx *gorm.DB) *gorm.DB { return tx.Unscoped() }).Where("novel_id = ?", novelID).Order("weight DESC, id DESC").Limit(limit).Find(&ratings).Error
+	return ratings, err
 }
 
 // FindByID 实现根据ID查找小说的方法
@@ -81,67 +290,318 @@ func (r *novelRepository) FindByID(id uint) (*model.Novel, error) {
 	return &novel, err
 }
 
-// CreateRating 实现创建评分的方法
+// CreateRating 实现创建评分的方法，需在事务中调用
 func (r *novelRepository) CreateRating(rating *model.Rating) error {
-	return r.db.Create(rating).Error
+	if err := r.db.Create(rating).Error; err != nil {
+		return err
+	}
+	return touchUserActivity(r.db, rating.UserID)
 }
 
-// FindByIDWithRatings 实现根据ID查找小说，并预加载其所有评分
-func (r *novelRepository) FindByIDWithRatings(id uint) (*model.Novel, error) {
+// touchUserActivity 记录用户主动操作的时间，供信誉分衰减判断用户是否活跃
+// 使用 UpdateColumn，不修改 users.updated_at
+func touchUserActivity(tx *gorm.DB, userID uint) error {
+	return tx.Model(&model.User{}).Where("id = ?", userID).UpdateColumn("last_active_at", time.Now()).Error
+}
+
+// FindIDsAfter 按 ID 升序返回大于 lastID 的最多 limit 个小说ID，用于分批遍历全部小说
+func (r *novelRepository) FindIDsAfter(lastID uint, limit int) ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&model.Novel{}).Where("id > ?", lastID).Order("id").Limit(limit).Pluck("id", &ids).Error
+	return ids, err
+}
+
+// ApplyScoreDelta 锁定小说行，把一次评分变动的增量累加到评分聚合值上，并用 score 重新计算加权分
+// 调用方通常已在同一事务中锁定了相关评分行，加锁顺序始终是 评分 -> 小说
+func (r *novelRepository) ApplyScoreDelta(novelID uint, delta ScoreDelta, score ScoreFunc) (*model.Novel, error) {
 	var novel model.Novel
-	// 使用 GORM 的 Preload 功能来避免 N+1 查询问题
-	err := r.db.Preload("Ratings").First(&novel, id).Error
-	return &novel, err
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&novel, novelID).Error; err != nil {
+			return err
+		}
+		novel.RatingsCount += delta.Count
+		novel.ScoreWeightSum += delta.WeightSum
+		novel.ScoreWeightedSum += delta.WeightedSum
+		novel.WeightedScore = score(&novel)
+		return tx.Model(&novel).Select(scoreAggregateColumns).Updates(&novel).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &novel, nil
+}
+
+// RebuildScoreAggregates 用 SQL 聚合重新统计小说的全部有效评分，覆盖增量累计的结果
+// 这是在累计值出现偏差 (例如浮点误差或历史数据) 时使用的修复工具，不在日常评分流程中调用
+func (r *novelRepository) RebuildScoreAggregates(novelID uint, score ScoreFunc) (*model.Novel, error) {
+	var novel model.Novel
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// 先锁定小说行，与增量更新互斥；尚未提交的增量会在本事务结束后正确地叠加上来
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&novel, novelID).Error; err != nil {
+			return err
+		}
+		var agg struct {
+			Count       int
+			WeightSum   float64
+			WeightedSum float64
+		}
+		err := tx.Model(&model.Rating{}).
+			Select("COUNT(*) AS count, COALESCE(SUM(weight), 0) AS weight_sum, COALESCE(SUM(score * weight), 0) AS weighted_sum").
+			Where("novel_id = ?", novelID).
+			Scan(&agg).Error
+		if err != nil {
+			return err
+		}
+		novel.RatingsCount = agg.Count
+		novel.ScoreWeightSum = agg.WeightSum
+		novel.ScoreWeightedSum = agg.WeightedSum
+		novel.WeightedScore = score(&novel)
+		return tx.Model(&novel).Select(scoreAggregateColumns).Updates(&novel).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &novel, nil
+}
+
+// FreezeScore 冻结小说的分数：在 until 之前 WeightedScore 固定为 score，评分聚合值仍正常累加
+func (r *novelRepository) FreezeScore(novelID uint, score float64, until time.Time) error {
+	return r.db.Model(&model.Novel{}).Where("id = ?", novelID).Updates(map[string]interface{}{
+		"weighted_score":     score,
+		"score_frozen_until": until,
+	}).Error
+}
+
+// UnfreezeScore 解除小说分数的冻结，并用 score 按当前的评分聚合值重新计算加权分
+func (r *novelRepository) UnfreezeScore(novelID uint, score ScoreFunc) (*model.Novel, error) {
+	var novel model.Novel
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&novel, novelID).Error; err != nil {
+			return err
+		}
+		novel.ScoreFrozenUntil = nil
+		novel.WeightedScore = score(&novel)
+		return tx.Model(&novel).Select("score_frozen_until", "weighted_score").Updates(&novel).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &novel, nil
+}
+
+// ApplyTrending 锁定小说行，由 update 修改热度排序键后保存
+func (r *novelRepository) ApplyTrending(novelID uint, update func(novel *model.Novel)) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var novel model.Novel
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&novel, novelID).Error; err != nil {
+			return err
+		}
+		update(&novel)
+		return tx.Model(&novel).Select(trendingColumns).Updates(&novel).Error
+	})
 }
 
-// Update 实现更新小说记录的方法
-func (r *novelRepository) Update(novel *model.Novel) error {
-	// GORM 的 Save 会更新所有字段，即使是零值
-	return r.db.Save(novel).Error
+// FindTrendingEvents 查找小说在 since 之后发生的全部热度事件，用于全量重算热度
+func (r *novelRepository) FindTrendingEvents(novelID uint, since time.Time) ([]TrendingEvent, error) {
+	var events []TrendingEvent
+	err := r.db.Model(&model.Rating{}).
+		Select("0 AS vote, created_at AS at").
+		Where("novel_id = ? AND created_at > ?", novelID, since).
+		Scan(&events).Error
+	if err != nil {
+		return nil, err
+	}
+	var votes []TrendingEvent
+	err = r.db.Model(&model.RatingVote{}).
+		Select("rating_votes.vote AS vote, rating_votes.updated_at AS at").
+		Joins("JOIN ratings ON ratings.id = rating_votes.rating_id AND ratings.deleted_at IS NULL").
+		Where("ratings.novel_id = ? AND rating_votes.updated_at > ?", novelID, since).
+		Scan(&votes).Error
+	if err != nil {
+		return nil, err
+	}
+	return append(events, votes...), nil
 }
 
-// FindRatingByID 根据ID查找评分
+// FindRatingByID 根据ID查找评分，并预加载评分作者
 func (r *novelRepository) FindRatingByID(id uint) (*model.Rating, error) {
 	var rating model.Rating
-	err := r.db.First(&rating, id).Error
+	err := r.db.Preload("User", func(tx *gorm.DB) *gorm.DB { return tx.Unscoped() }).First(&rating, id).Error
 	return &rating, err
 }
 
-// FindUserVote 查找特定用户对特定评分的投票记录
-func (r *novelRepository) FindUserVote(userID, ratingID uint) (*model.RatingVote, error) {
-	var vote model.RatingVote
-	err := r.db.Where("user_id = ? AND rating_id = ?", userID, ratingID).First(&vote).Error
-	return &vote, err
+// FindRatingWeightsByUser 返回用户的全部评分，只加载 ID 与当前权重
+func (r *novelRepository) FindRatingWeightsByUser(userID uint) ([]model.Rating, error) {
+	var ratings []model.Rating
+	err := r.db.Select("id", "weight").Where("user_id = ?", userID).Order("id").Find(&ratings).Error
+	return ratings, err
 }
 
-// UpdateRatingVote 使用数据库事务来更新投票
-func (r *novelRepository) UpdateRatingVote(rating *model.Rating, oldVote, newVote *model.RatingVote) error {
-	// 使用事务来保证数据一致性：对 vote 表的修改和对 rating 表计数的修改，必须同时成功或失败
-	return r.db.Transaction(func(tx *gorm.DB) error {
-		// --- 处理 vote 表 ---
-		if oldVote != nil { // 如果存在旧投票，先删除
-			if err := tx.Delete(oldVote).Error; err != nil {
-				return err
-			}
+// VoteOutcome 描述一次投票操作的结果
+type VoteOutcome struct {
+	Rating     *model.Rating     // 计数更新之后的评分
+	VoteChange int               // 本次操作对评分净赞同数的影响：首次投票 ±1，取消投票 ∓1，改票 ±2
+	Previous   *model.RatingVote // 本次操作之前该用户的有效投票，没有时为 nil
+	Cancelled  bool              // 本次操作是否为取消投票
+}
+
+// ApplyRatingVote 在一个事务中完成投票的切换与评分计数的更新
+// 评分行在事务期间被 SELECT ... FOR UPDATE 锁定，同一评分上的并发投票会串行执行，
+// 计数使用 counter = counter + delta 的方式更新，因此不会相互覆盖
+// 赞同/反对信誉分之和同样增量维护：投票时计入 voterWeight (投票者的信誉分，试用期内有折减)，
+// 取消或改票时扣除投票时记录的值
+func (r *novelRepository) ApplyRatingVote(userID, ratingID uint, voteType model.VoteType, voterWeight float64) (*VoteOutcome, error) {
+	var outcome *VoteOutcome
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var rating model.Rating
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&rating, ratingID).Error; err != nil {
+			return err
+		}
+
+		// 唯一索引 (user_id, rating_id) 同样覆盖被软删除的旧投票，所以这里连同它们一起查找
+		var oldVote model.RatingVote
+		err := tx.Unscoped().Where("user_id = ? AND rating_id = ?", userID, ratingID).First(&oldVote).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
 		}
-		if newVote != nil { // 如果存在新投票，创建它
-			if err := tx.Create(newVote).Error; err != nil {
-				return err
+		hasOldVote := err == nil && !oldVote.DeletedAt.Valid
+		// 下面的更新会修改 oldVote，先保留一份更新前的投票
+		var previous *model.RatingVote
+		if hasOldVote {
+			snapshot := oldVote
+			previous = &snapshot
+		}
+
+		var upDelta, downDelta, voteChange int
+		var upTrustDelta, downTrustDelta float64
+		switch {
+		case !hasOldVote: // 首次投票 (或复用一条被软删除的旧记录)
+			voteChange = int(voteType)
+			if err == nil {
+				err = tx.Unscoped().Model(&oldVote).Updates(map[string]interface{}{"vote": voteType, "voter_trust": voterWeight, "deleted_at": nil}).Error
+			} else {
+				err = tx.Create(&model.RatingVote{UserID: userID, RatingID: ratingID, Vote: voteType, VoterTrust: voterWeight}).Error
+			}
+			if voteType == model.VoteTypeUp {
+				upDelta, upTrustDelta = 1, voterWeight
+			} else {
+				downDelta, downTrustDelta = 1, voterWeight
+			}
+		case oldVote.Vote == voteType: // 再次投出相同的票，视为取消投票
+			voteChange = -int(voteType)
+			err = tx.Unscoped().Delete(&oldVote).Error
+			if voteType == model.VoteTypeUp {
+				upDelta, upTrustDelta = -1, -previous.VoterTrust
+			} else {
+				downDelta, downTrustDelta = -1, -previous.VoterTrust
+			}
+		default: // 改票
+			voteChange = 2 * int(voteType)
+			err = tx.Model(&oldVote).Updates(map[string]interface{}{"vote": voteType, "voter_trust": voterWeight}).Error
+			if voteType == model.VoteTypeUp {
+				upDelta, downDelta = 1, -1
+				upTrustDelta, downTrustDelta = voterWeight, -previous.VoterTrust
+			} else {
+				upDelta, downDelta = -1, 1
+				upTrustDelta, downTrustDelta = -previous.VoterTrust, voterWeight
 			}
 		}
+		if err != nil {
+			return err
+		}
+		if err := touchUserActivity(tx, userID); err != nil {
+			return err
+		}
 
-		// --- 更新 rating 表的计数 ---
-		if err := tx.Save(rating).Error; err != nil {
+		err = tx.Model(&model.Rating{}).Where("id = ?", ratingID).Updates(map[string]interface{}{
+			"upvotes_count":   gorm.Expr("upvotes_count + ?", upDelta),
+			"downvotes_count": gorm.Expr("downvotes_count + ?", downDelta),
+			"upvote_trust":    gorm.Expr("upvote_trust + ?", upTrustDelta),
+			"downvote_trust":  gorm.Expr("downvote_trust + ?", downTrustDelta),
+		}).Error
+		if err != nil {
 			return err
 		}
 
+		rating.UpvotesCount += upDelta
+		rating.DownvotesCount += downDelta
+		rating.UpvoteTrust += upTrustDelta
+		rating.DownvoteTrust += downTrustDelta
+		outcome = &VoteOutcome{
+			Rating:     &rating,
+			VoteChange: voteChange,
+			Previous:   previous,
+			Cancelled:  previous != nil && previous.Vote == voteType,
+		}
 		return nil
 	})
+	if err != nil {
+		return nil, err
+	}
+	return outcome, nil
+}
+
+// SetRatingWeight 锁定评分并只更新其权重 (避免覆盖并发更新的投票计数)，返回更新前的评分
+func (r *novelRepository) SetRatingWeight(ratingID uint, weight float64) (*model.Rating, error) {
+	var previous model.Rating
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&previous, ratingID).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.Rating{}).Where("id = ?", ratingID).Update("weight", weight).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &previous, nil
+}
+
+// FindRatingByIDForUpdate 在事务中查找并锁定一条评分，用于需要读取旧值再更新的场景
+func (r *novelRepository) FindRatingByIDForUpdate(id uint) (*model.Rating, error) {
+	var rating model.Rating
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&rating, id).Error
+	return &rating, err
 }
 
-func (r *novelRepository) UpdateRating(rating *model.Rating) error {
-	// GORM 的 Save 会根据主键(ID)来执行更新，更新所有字段
-	return r.db.Save(rating).Error
+// FindUserRatingForNovel 查找用户对某本小说的有效评分
+func (r *novelRepository) FindUserRatingForNovel(userID, novelID uint) (*model.Rating, error) {
+	var rating model.Rating
+	err := r.db.Where("user_id = ? AND novel_id = ?", userID, novelID).First(&rating).Error
+	return &rating, err
+}
+
+// UpdateRatingContent 只更新评分的分值与评论，不触碰投票计数和权重等由其他流程维护的字段，需在事务中调用
+func (r *novelRepository) UpdateRatingContent(rating *model.Rating) error {
+	if err := r.db.Model(rating).Select("score", "comment", "quality_score", "comment_hash").Updates(rating).Error; err != nil {
+		return err
+	}
+	return touchUserActivity(r.db, rating.UserID)
+}
+
+// CountEarlierRatingsWithCommentHash 统计评论指纹相同、且比 beforeID 更早的有效评分数，beforeID 为 0 时统计全部
+func (r *novelRepository) CountEarlierRatingsWithCommentHash(hash string, beforeID uint) (int64, error) {
+	db := r.db.Model(&model.Rating{}).Where("comment_hash = ?", hash)
+	if beforeID != 0 {
+		db = db.Where("id < ?", beforeID)
+	}
+	var count int64
+	err := db.Count(&count).Error
+	return count, err
+}
+
+// DeleteRatingWithVotes 软删除一条评分及其收到的所有投票，评分作者自己撤回时调用
+func (r *novelRepository) DeleteRatingWithVotes(rating *model.Rating) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		deletedAt := time.Now()
+		if err := tx.Model(&model.RatingVote{}).Where("rating_id = ?", rating.ID).
+			Update("deleted_at", deletedAt).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(rating).Update("deleted_at", deletedAt).Error; err != nil {
+			return err
+		}
+		return touchUserActivity(tx, rating.UserID)
+	})
 }
 
 func (r *novelRepository) CreateInTx(novel *model.Novel) error {
@@ -153,6 +613,9 @@ func (r *novelRepository) CreateInTx(novel *model.Novel) error {
 		if err := tx.Create(novel).Error; err != nil {
 			return err
 		}
+		if err := refreshSearchVector(tx, novel); err != nil {
+			return err
+		}
 
 		// 2. GORM 会自动处理 `novel.Tags` 的多对多关联关系
 		//    因为它在模型中已经通过 `gorm:"many2many:novel_tags;"` 定义好了
@@ -161,3 +624,85 @@ func (r *novelRepository) CreateInTx(novel *model.Novel) error {
 		return nil
 	})
 }
+
+// UpdateInTx 在事务中更新小说的可编辑字段，tags 不为 nil 时同时替换其标签关联
+func (r *novelRepository) UpdateInTx(novel *model.Novel, tags []*model.Tag) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// 只更新白名单中的字段，避免覆盖后台任务刚刚写入的评分数据
+		if err := tx.Model(novel).Select(novelEditableColumns).Updates(novel).Error; err != nil {
+			return err
+		}
+		if err := refreshSearchVector(tx, novel); err != nil {
+			return err
+		}
+		if tags == nil {
+			return nil
+		}
+		if len(tags) == 0 {
+			return tx.Model(novel).Association("Tags").Clear()
+		}
+		return tx.Model(novel).Association("Tags").Replace(tags)
+	})
+}
+
+// refreshSearchVector 根据小说当前的标题、作者和简介重新生成全文检索向量
+func refreshSearchVector(tx *gorm.DB, novel *model.Novel) error {
+	return tx.Model(&model.Novel{}).Where("id = ?", novel.ID).
+		UpdateColumn("search_vector", model.NovelSearchVector(novel)).Error
+}
+
+// DeleteWithRatings 软删除小说，并级联软删除其下的评分与评分投票
+// 三者使用同一个删除时间戳，恢复时据此只还原随小说一起被删除的记录
+func (r *novelRepository) DeleteWithRatings(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var novel model.Novel
+		if err := tx.First(&novel, id).Error; err != nil {
+			return err
+		}
+		deletedAt := time.Now()
+
+		ratingIDs := tx.Model(&model.Rating{}).Select("id").Where("novel_id = ?", id)
+		if err := tx.Model(&model.RatingVote{}).Where("rating_id IN (?)", ratingIDs).
+			Update("deleted_at", deletedAt).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.Rating{}).Where("novel_id = ?", id).
+			Update("deleted_at", deletedAt).Error; err != nil {
+			return err
+		}
+		return tx.Model(&novel).Update("deleted_at", deletedAt).Error
+	})
+}
+
+// RestoreWithRatings 恢复一本已被软删除的小说，以及与它同时被删除的评分和投票
+// 在小说删除之前就已被单独删除的评分不会被恢复
+func (r *novelRepository) RestoreWithRatings(id uint) (*model.Novel, error) {
+	var novel model.Novel
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("deleted_at IS NOT NULL").First(&novel, id).Error; err != nil {
+			return err
+		}
+		deletedAt := novel.DeletedAt.Time
+
+		ratingIDs := tx.Unscoped().Model(&model.Rating{}).Select("id").Where("novel_id = ?", id)
+		if err := tx.Unscoped().Model(&model.RatingVote{}).
+			Where("deleted_at = ? AND rating_id IN (?)", deletedAt, ratingIDs).
+			Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&model.Rating{}).
+			Where("novel_id = ? AND deleted_at = ?", id, deletedAt).
+			Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&novel).Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		novel.DeletedAt = gorm.DeletedAt{}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &novel, nil
+}
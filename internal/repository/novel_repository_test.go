@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"fmt"
+	"github.com/novel/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"math/rand"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+// openTestDB 连接到 NOVEL_TEST_DSN 指定的 PostgreSQL 数据库，未设置时跳过测试
+// 例如：NOVEL_TEST_DSN="host=localhost user=novel password=123.com dbname=novel_test port=5432 sslmode=disable"
+func openTestDB(t *testing.T) *gorm.DB {
+	dsn := os.Getenv("NOVEL_TEST_DSN")
+	if dsn == "" {
+		t.Skip("NOVEL_TEST_DSN is not set, skipping database test")
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+		TranslateError: true,
+		Logger:         logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.User{}, &model.Category{}, &model.Novel{}, &model.Rating{}, &model.RatingVote{}))
+	return db
+}
+
+// TestApplyRatingVoteConcurrent 让大量用户并发地对同一条评分反复投票、改票和取消投票，
+// 最终评分上的计数必须与 rating_votes 表中的实际记录一致
+func TestApplyRatingVoteConcurrent(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewNovelRepository(db)
+
+	const voters = 40
+	const votesPerVoter = 10
+	suffix := time.Now().UnixNano()
+
+	novel := &model.Novel{Title: fmt.Sprintf("concurrency-%d", suffix), PublicationType: model.TypeWebNovel}
+	require.NoError(t, db.Create(novel).Error)
+	author := &model.User{Username: fmt.Sprintf("author-%d", suffix), PasswordHash: "x"}
+	require.NoError(t, db.Create(author).Error)
+	rating := &model.Rating{NovelID: novel.ID, UserID: author.ID, Score: 8}
+	require.NoError(t, db.Create(rating).Error)
+
+	users := make([]model.User, voters)
+	for i := range users {
+		users[i] = model.User{Username: fmt.Sprintf("voter-%d-%d", suffix, i), PasswordHash: "x"}
+	}
+	require.NoError(t, db.Create(&users).Error)
+
+	t.Cleanup(func() {
+		db.Unscoped().Where("rating_id = ?", rating.ID).Delete(&model.RatingVote{})
+		db.Unscoped().Delete(rating)
+		db.Unscoped().Delete(novel)
+		db.Unscoped().Delete(author)
+		db.Unscoped().Delete(&users)
+	})
+
+	var wg sync.WaitGroup
+	errs := make(chan error, voters*votesPerVoter)
+	for i := range users {
+		wg.Add(1)
+		go func(userID uint, seed int64) {
+			defer wg.Done()
+			rnd := rand.New(rand.NewSource(seed))
+			for j := 0; j < votesPerVoter; j++ {
+				voteType := model.VoteTypeUp
+				if rnd.Intn(2) == 0 {
+					voteType = model.VoteTypeDown
+				}
+				if _, err := repo.ApplyRatingVote(userID, rating.ID, voteType, 1.0); err != nil {
+					errs <- err
+				}
+			}
+		}(users[i].ID, suffix+int64(i))
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	var expectedUp, expectedDown int64
+	require.NoError(t, db.Model(&model.RatingVote{}).Where("rating_id = ? AND vote = ?", rating.ID, model.VoteTypeUp).Count(&expectedUp).Error)
+	require.NoError(t, db.Model(&model.RatingVote{}).Where("rating_id = ? AND vote = ?", rating.ID, model.VoteTypeDown).Count(&expectedDown).Error)
+
+	var stored model.Rating
+	require.NoError(t, db.First(&stored, rating.ID).Error)
+	assert.Equal(t, int(expectedUp), stored.UpvotesCount)
+	assert.Equal(t, int(expectedDown), stored.DownvotesCount)
+	assert.LessOrEqual(t, expectedUp+expectedDown, int64(voters))
+
+	// 每张票都按 1.0 计入，信誉分之和应与投票数相同
+	assert.InDelta(t, float64(expectedUp), stored.UpvoteTrust, 1e-6)
+	assert.InDelta(t, float64(expectedDown), stored.DownvoteTrust, 1e-6)
+}
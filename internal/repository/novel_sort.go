@@ -0,0 +1,36 @@
+package repository
+
+const (
+	// DefaultNovelSortKey 是未指定排序方式时使用的排序键
+	DefaultNovelSortKey = "newest"
+	// RelevanceSortKey 按全文检索相关度排序，带有搜索词且未指定排序方式时默认使用
+	RelevanceSortKey = "relevance"
+)
+
+// novelSorts 声明了小说列表支持的全部排序方式，新增排序只需在此登记
+var novelSorts = map[string]sortOption{
+	"score":         {expr: "novels.weighted_score", sqlType: "double precision", desc: true},
+	"ratings_count": {expr: "novels.ratings_count", sqlType: "bigint", desc: true},
+	"newest":        {expr: "novels.created_at", sqlType: "timestamptz", desc: true},
+	"word_count":    {expr: "novels.word_count", sqlType: "bigint", desc: true},
+	// 按热度排序，热度排序键见 trending 包；trending 等同于周榜
+	"trending":       {expr: "novels.trending_week", sqlType: "double precision", desc: true},
+	"trending_day":   {expr: "novels.trending_day", sqlType: "double precision", desc: true},
+	"trending_week":  {expr: "novels.trending_week", sqlType: "double precision", desc: true},
+	"trending_month": {expr: "novels.trending_month", sqlType: "double precision", desc: true},
+	// 按标题的拼音顺序排序，title_sort_key 是标题的排序键 (见 model.TitleSortKey)
+	"title_pinyin": {expr: "novels.title_sort_key", sqlType: "bytea", desc: false},
+	// 全文检索的相关度，只有带搜索词时可用
+	RelevanceSortKey: {expr: "ts_rank(novels.search_vector, ?::tsquery)", sqlType: "real", desc: true, useQuery: true},
+}
+
+// resolveNovelSort 确定小说列表的排序方式，tsQuery 为本次查询的全文检索条件 (可为空)
+func resolveNovelSort(key, order, tsQuery string) (*resolvedSort, error) {
+	if key == "" {
+		key = DefaultNovelSortKey
+		if tsQuery != "" {
+			key = RelevanceSortKey
+		}
+	}
+	return resolveSort(novelSorts, "novels.id", key, order, tsQuery)
+}
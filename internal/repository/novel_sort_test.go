@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/clause"
+	"testing"
+)
+
+func TestResolveNovelSort(t *testing.T) {
+	s, err := resolveNovelSort("title_pinyin", "", "")
+	require.NoError(t, err)
+	assert.Equal(t, "novels.title_sort_key ASC, novels.id ASC", s.orderBy().Expression.(clause.Expr).SQL)
+
+	s, err = resolveNovelSort("", "asc", "")
+	require.NoError(t, err)
+	assert.Equal(t, "newest", s.key)
+	assert.Equal(t, "novels.created_at ASC, novels.id ASC", s.orderBy().Expression.(clause.Expr).SQL)
+
+	s, err = resolveNovelSort("", "", "'三体'")
+	require.NoError(t, err)
+	expr := s.orderBy().Expression.(clause.Expr)
+	assert.Equal(t, "ts_rank(novels.search_vector, ?::tsquery) DESC, novels.id DESC", expr.SQL)
+	assert.Equal(t, []interface{}{"'三体'"}, expr.Vars)
+
+	for _, key := range []string{"created_at", "weighted_score desc; DROP TABLE novels", "id", RelevanceSortKey} {
+		_, err = resolveNovelSort(key, "desc", "")
+		assert.ErrorIs(t, err, ErrUnknownSortKey, key)
+	}
+}
+
+func TestResolvedSortAfter(t *testing.T) {
+	s, err := resolveNovelSort("score", "", "")
+	require.NoError(t, err)
+
+	expr, err := s.after(&Keyset{Sort: "score", Desc: true, Value: "8.25", ID: 42})
+	require.NoError(t, err)
+	assert.Equal(t, "(novels.weighted_score, novels.id) < (CAST(? AS double precision), ?)", expr.SQL)
+	assert.Equal(t, []interface{}{"8.25", uint(42)}, expr.Vars)
+
+	_, err = s.after(&Keyset{Sort: "score", Desc: false, Value: "8.25", ID: 42})
+	assert.ErrorIs(t, err, ErrKeysetMismatch)
+	_, err = s.after(&Keyset{Sort: "newest", Desc: true, Value: "8.25", ID: 42})
+	assert.ErrorIs(t, err, ErrKeysetMismatch)
+}
+
+func TestHiddenSortKeyset(t *testing.T) {
+	s, err := resolveRatingSort("weight", "")
+	require.NoError(t, err)
+
+	// 游标中不保存权重，只保存主键
+	next, err := s.nextKeyset(nil, nil, 42)
+	require.NoError(t, err)
+	assert.Equal(t, &Keyset{Sort: "weight", Desc: true, ID: 42}, next)
+
+	expr, err := s.after(&Keyset{Sort: "weight", Desc: true, Value: "0.9", ID: 42})
+	require.NoError(t, err)
+	assert.Equal(t, "(ratings.weight, ratings.id) < ((SELECT ratings.weight FROM ratings WHERE ratings.id = ?), ?)", expr.SQL)
+	assert.Equal(t, []interface{}{uint(42), uint(42)}, expr.Vars)
+}
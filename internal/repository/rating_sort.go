@@ -0,0 +1,22 @@
+package repository
+
+// DefaultRatingSortKey 是评分列表未指定排序方式时使用的排序键
+const DefaultRatingSortKey = "helpful"
+
+// ratingSorts 声明了小说评分列表支持的全部排序方式
+var ratingSorts = map[string]sortOption{
+	// 有用程度：赞同数减去反对数
+	"helpful": {expr: "(ratings.upvotes_count - ratings.downvotes_count)", sqlType: "bigint", desc: true},
+	// 权重属于内部计算字段，游标中不保存权重的值
+	"weight": {expr: "ratings.weight", sqlType: "double precision", desc: true, hidden: true},
+	"newest": {expr: "ratings.created_at", sqlType: "timestamptz", desc: true},
+	"score":  {expr: "ratings.score", sqlType: "bigint", desc: true},
+}
+
+// resolveRatingSort 确定评分列表的排序方式
+func resolveRatingSort(key, order string) (*resolvedSort, error) {
+	if key == "" {
+		key = DefaultRatingSortKey
+	}
+	return resolveSort(ratingSorts, "ratings.id", key, order, "")
+}
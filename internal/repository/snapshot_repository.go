@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"github.com/novel/internal/model"
+	"gorm.io/gorm"
+	"time"
+)
+
+// ChartRow 是榜单对比中的一行：小说在本期与上一期快照中的排名和得分
+// 某一期没有快照 (或不在比较范围内) 时对应的排名为 nil；本期没有快照时得分与评分数取小说当前的值
+type ChartRow struct {
+	NovelID       uint
+	Title         string
+	Rank          *int
+	PreviousRank  *int
+	WeightedScore float64
+	PreviousScore *float64
+	RatingsCount  int
+	PreviousCount *int
+}
+
+// SnapshotRepository 定义了得分快照的记录与查询
+type SnapshotRepository interface {
+	CaptureDaily(date time.Time) (int64, error)
+	FindByNovel(novelID uint, since time.Time) ([]model.NovelSnapshot, error)
+	LatestDate(onOrBefore time.Time) (*time.Time, error)
+	CompareRanks(date, previous time.Time, categoryID *uint, chartSize int) ([]ChartRow, error)
+}
+
+type snapshotRepository struct {
+	db *gorm.DB
+}
+
+// NewSnapshotRepository 是 snapshotRepository 的构造函数
+func NewSnapshotRepository(db *gorm.DB) SnapshotRepository {
+	return &snapshotRepository{db: db}
+}
+
+// CaptureDaily 为所有已有评分的小说记录 date 当天的快照，返回记录的条数
+// 同一天重复执行时覆盖当天已有的快照
+func (r *snapshotRepository) CaptureDaily(date time.Time) (int64, error) {
+	result := r.db.Exec(`
+		INSERT INTO novel_snapshots (novel_id, date, weighted_score, ratings_count, rank, category_id, category_rank, created_at)
+		SELECT id, ?::date, weighted_score, ratings_count,
+			ROW_NUMBER() OVER (ORDER BY weighted_score DESC, id),
+			category_id,
+			ROW_NUMBER() OVER (PARTITION BY category_id ORDER BY weighted_score DESC, id),
+			NOW()
+		FROM novels
+		WHERE deleted_at IS NULL AND ratings_count > 0
+		ON CONFLICT (novel_id, date) DO UPDATE SET
+			weighted_score = EXCLUDED.weighted_score,
+			ratings_count = EXCLUDED.ratings_count,
+			rank = EXCLUDED.rank,
+			category_id = EXCLUDED.category_id,
+			category_rank = EXCLUDED.category_rank,
+			created_at = EXCLUDED.created_at`,
+		date.Format(time.DateOnly))
+	return result.RowsAffected, result.Error
+}
+
+// FindByNovel 按日期顺序返回小说自 since 以来的快照
+func (r *snapshotRepository) FindByNovel(novelID uint, since time.Time) ([]model.NovelSnapshot, error) {
+	var snapshots []model.NovelSnapshot
+	err := r.db.Where("novel_id = ? AND date >= ?::date", novelID, since.Format(time.DateOnly)).
+		Order("date").
+		Find(&snapshots).Error
+	return snapshots, err
+}
+
+// LatestDate 返回不晚于 onOrBefore 的最近一次快照日期，没有快照时返回 nil
+func (r *snapshotRepository) LatestDate(onOrBefore time.Time) (*time.Time, error) {
+	var date *time.Time
+	err := r.db.Model(&model.NovelSnapshot{}).
+		Select("MAX(date)").
+		Where("date <= ?::date", onOrBefore.Format(time.DateOnly)).
+		Scan(&date).Error
+	return date, err
+}
+
+// CompareRanks 对比两期快照，返回在任意一期中进入前 chartSize 名的小说
+// 两期快照做全外连接，上一期在榜、本期已没有快照的小说 (评分被全部撤回或移出了该分类) 同样返回
+// categoryID 不为 nil 时使用分类内排名，并只比较两期都属于该分类的快照
+func (r *snapshotRepository) CompareRanks(date, previous time.Time, categoryID *uint, chartSize int) ([]ChartRow, error) {
+	rankColumn := "rank"
+	if categoryID != nil {
+		rankColumn = "category_rank"
+	}
+	period := "SELECT novel_id, " + rankColumn + " AS rank, weighted_score, ratings_count FROM novel_snapshots WHERE date = ?::date"
+	curArgs := []interface{}{date.Format(time.DateOnly)}
+	prevArgs := []interface{}{previous.Format(time.DateOnly)}
+	if categoryID != nil {
+		period += " AND category_id = ?"
+		curArgs = append(curArgs, *categoryID)
+		prevArgs = append(prevArgs, *categoryID)
+	}
+
+	args := append(append(curArgs, prevArgs...), chartSize, chartSize)
+	var rows []ChartRow
+	err := r.db.Raw(`
+		SELECT novels.id AS novel_id, novels.title,
+			cur.rank, prev.rank AS previous_rank,
+			COALESCE(cur.weighted_score, novels.weighted_score) AS weighted_score, prev.weighted_score AS previous_score,
+			COALESCE(cur.ratings_count, novels.ratings_count) AS ratings_count, prev.ratings_count AS previous_count
+		FROM (`+period+`) cur
+		FULL OUTER JOIN (`+period+`) prev ON prev.novel_id = cur.novel_id
+		JOIN novels ON novels.id = COALESCE(cur.novel_id, prev.novel_id) AND novels.deleted_at IS NULL
+		WHERE cur.rank <= ? OR prev.rank <= ?
+		ORDER BY cur.rank NULLS LAST, prev.rank`, args...).
+		Scan(&rows).Error
+	return rows, err
+}
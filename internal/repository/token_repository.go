@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"github.com/novel/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+// TokenRepository 定义了刷新令牌与访问令牌吊销列表的存储
+type TokenRepository interface {
+	WithTx(tx *gorm.DB) TokenRepository
+	CreateRefreshToken(token *model.RefreshToken) error
+	FindRefreshTokenByHashForUpdate(hash string) (*model.RefreshToken, error)
+	MarkRefreshTokenRotated(id uint, at time.Time) error
+	RevokeSession(sessionID string, at time.Time) error
+	RevokeUserSessions(userID uint, at time.Time) error
+	RevokeUserAccessTokens(userID uint, now time.Time) error
+	IsAccessTokenRevoked(jti string) (bool, error)
+	DeleteExpired(now time.Time) (int64, error)
+}
+
+type tokenRepository struct {
+	db *gorm.DB
+}
+
+// NewTokenRepository 是 tokenRepository 的构造函数
+func NewTokenRepository(db *gorm.DB) TokenRepository {
+	return &tokenRepository{db: db}
+}
+
+// WithTx 返回一个绑定到给定事务的 TokenRepository
+func (r *tokenRepository) WithTx(tx *gorm.DB) TokenRepository {
+	return &tokenRepository{db: tx}
+}
+
+// CreateRefreshToken 保存新签发的刷新令牌
+func (r *tokenRepository) CreateRefreshToken(token *model.RefreshToken) error {
+	return r.db.Create(token).Error
+}
+
+// FindRefreshTokenByHashForUpdate 根据哈希查找并锁定刷新令牌，防止同一令牌被并发地重复使用
+func (r *tokenRepository) FindRefreshTokenByHashForUpdate(hash string) (*model.RefreshToken, error) {
+	var token model.RefreshToken
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("token_hash = ?", hash).First(&token).Error
+	return &token, err
+}
+
+// MarkRefreshTokenRotated 记录刷新令牌已被用于刷新
+func (r *tokenRepository) MarkRefreshTokenRotated(id uint, at time.Time) error {
+	return r.db.Model(&model.RefreshToken{}).Where("id = ?", id).Update("rotated_at", at).Error
+}
+
+// RevokeSession 作废会话中的全部刷新令牌，并吊销该会话签发的、尚未过期的访问令牌
+func (r *tokenRepository) RevokeSession(sessionID string, at time.Time) error {
+	return r.revoke("session_id", sessionID, at)
+}
+
+// RevokeUserSessions 作废用户所有会话的刷新令牌，并吊销其尚未过期的访问令牌
+func (r *tokenRepository) RevokeUserSessions(userID uint, at time.Time) error {
+	return r.revoke("user_id", userID, at)
+}
+
+// revoke 作废 column = value 的全部刷新令牌及其访问令牌，column 只能是 session_id 或 user_id
+func (r *tokenRepository) revoke(column string, value interface{}, at time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&model.RefreshToken{}).Where(column+" = ? AND revoked_at IS NULL", value).
+			Update("revoked_at", at).Error
+		if err != nil {
+			return err
+		}
+		return revokeAccessTokens(tx, column, value, at)
+	})
+}
+
+// RevokeUserAccessTokens 只吊销用户尚未过期的访问令牌，刷新令牌保持有效
+// 用于角色变更等场景：客户端刷新后按最新的用户信息签发访问令牌
+func (r *tokenRepository) RevokeUserAccessTokens(userID uint, now time.Time) error {
+	return revokeAccessTokens(r.db, "user_id", userID, now)
+}
+
+// revokeAccessTokens 把 column = value 的刷新令牌对应的、在 now 之后才过期的访问令牌加入吊销列表
+func revokeAccessTokens(db *gorm.DB, column string, value interface{}, now time.Time) error {
+	var live []model.RefreshToken
+	err := db.Model(&model.RefreshToken{}).Select("access_jti", "user_id", "access_expires_at").
+		Where(column+" = ? AND access_expires_at > ?", value, now).Find(&live).Error
+	if err != nil || len(live) == 0 {
+		return err
+	}
+	revoked := make([]model.RevokedToken, len(live))
+	for i, t := range live {
+		revoked[i] = model.RevokedToken{JTI: t.AccessJTI, UserID: t.UserID, ExpiresAt: t.AccessExpiresAt}
+	}
+	return db.Clauses(clause.OnConflict{DoNothing: true}).Create(&revoked).Error
+}
+
+// IsAccessTokenRevoked 判断访问令牌是否已被吊销
+func (r *tokenRepository) IsAccessTokenRevoked(jti string) (bool, error) {
+	var count int64
+	err := r.db.Model(&model.RevokedToken{}).Where("jti = ?", jti).Count(&count).Error
+	return count > 0, err
+}
+
+// DeleteExpired 删除已过期的刷新令牌与吊销记录，返回删除的总行数
+func (r *tokenRepository) DeleteExpired(now time.Time) (int64, error) {
+	var deleted int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("expires_at < ?", now).Delete(&model.RevokedToken{})
+		if result.Error != nil {
+			return result.Error
+		}
+		deleted += result.RowsAffected
+		result = tx.Where("expires_at < ?", now).Delete(&model.RefreshToken{})
+		deleted += result.RowsAffected
+		return result.Error
+	})
+	return deleted, err
+}
@@ -0,0 +1,21 @@
+package repository
+
+import "gorm.io/gorm"
+
+// TxManager 用于在同一个数据库事务中组合多个 Repository 的操作
+// 在回调中通过各 Repository 的 WithTx(tx) 获得绑定到该事务的实例
+type TxManager interface {
+	Transaction(fn func(tx *gorm.DB) error) error
+}
+
+type txManager struct {
+	db *gorm.DB
+}
+
+func NewTxManager(db *gorm.DB) TxManager {
+	return &txManager{db: db}
+}
+
+func (m *txManager) Transaction(fn func(tx *gorm.DB) error) error {
+	return m.db.Transaction(fn)
+}
@@ -3,16 +3,38 @@ package repository
 import (
 	"github.com/novel/internal/model"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"sort"
+	"time"
 )
 
 type UserRepository interface {
+	WithTx(tx *gorm.DB) UserRepository
 	Create(user *model.User) error
 	FindByUsername(username string) (*model.User, error)
 	FindByID(userID uint) (*model.User, error)
 	Update(user *model.User) error
 	FindByIDWithRatings(userID uint) (*model.User, error)
+	FindIDsAfter(lastID uint, limit int) ([]uint, error)
+	FindPenalizedIDs() ([]uint, error)
+	FindActivityStats(userID uint) (*UserActivityStats, error)
+	EndProbation(userID uint, at time.Time) (bool, error)
+	UpdateRole(userID uint, role model.Role) error
+	UpdateTrustScores(updates map[uint]TrustUpdate) error
+	FindTrustChanges(userID uint, limit int) ([]model.TrustChange, error)
+	FindLastActivityAt(userID uint) (time.Time, error)
+	FindInactiveIDsAfter(lastID uint, activeSince time.Time, limit int) ([]uint, error)
 }
 
+// UserActivityStats 是判断新账号能否结束试用期所需的活跃度统计
+type UserActivityStats struct {
+	RatingsCount    int64 // 有效评分数
+	UpvotesReceived int64 // 有效评分收到的赞同票数
+}
+
+// TrustUpdate 基于加锁读取到的最新用户修改其信誉相关字段，返回要写入的变动记录，返回 nil 时只保存用户
+type TrustUpdate func(user *model.User) *model.TrustChange
+
 type userRepository struct {
 	db *gorm.DB
 }
@@ -21,6 +43,11 @@ func NewUserRepository(db *gorm.DB) UserRepository {
 	return &userRepository{db: db}
 }
 
+// WithTx 返回一个绑定到给定事务的 UserRepository
+func (r *userRepository) WithTx(tx *gorm.DB) UserRepository {
+	return &userRepository{db: tx}
+}
+
 func (r *userRepository) Create(user *model.User) error {
 	return r.db.Create(user).Error
 }
@@ -46,3 +73,95 @@ func (r *userRepository) FindByIDWithRatings(userID uint) (*model.User, error) {
 	err := r.db.Preload("Ratings").First(&user, userID).Error
 	return &user, err
 }
+
+// FindIDsAfter 按 ID 升序返回大于 lastID 的最多 limit 个用户ID，用于分批遍历全部用户
+func (r *userRepository) FindIDsAfter(lastID uint, limit int) ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&model.User{}).Where("id > ?", lastID).Order("id").Limit(limit).Pluck("id", &ids).Error
+	return ids, err
+}
+
+// FindPenalizedIDs 返回当前带有信誉分惩罚的全部用户ID
+func (r *userRepository) FindPenalizedIDs() ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&model.User{}).Where("trust_penalty > 0").Order("id").Pluck("id", &ids).Error
+	return ids, err
+}
+
+// FindActivityStats 统计用户的有效评分数及这些评分收到的赞同票数
+func (r *userRepository) FindActivityStats(userID uint) (*UserActivityStats, error) {
+	var stats UserActivityStats
+	err := r.db.Model(&model.Rating{}).
+		Select("COUNT(*) AS ratings_count, COALESCE(SUM(upvotes_count), 0) AS upvotes_received").
+		Where("user_id = ?", userID).
+		Scan(&stats).Error
+	return &stats, err
+}
+
+// EndProbation 记录用户结束试用期的时间，只更新这一列，避免覆盖并发更新的信誉分
+// 返回是否由这次调用结束了试用期，并发的检查中只有一个会返回 true
+func (r *userRepository) EndProbation(userID uint, at time.Time) (bool, error) {
+	result := r.db.Model(&model.User{}).Where("id = ? AND probation_ended_at IS NULL", userID).
+		UpdateColumn("probation_ended_at", at)
+	return result.RowsAffected > 0, result.Error
+}
+
+// UpdateRole 只更新用户的角色，避免覆盖并发更新的信誉分、试用期等字段
+func (r *userRepository) UpdateRole(userID uint, role model.Role) error {
+	return r.db.Model(&model.User{}).Where("id = ?", userID).Update("role", role).Error
+}
+
+// UpdateTrustScores 在一个事务中按 ID 顺序加锁读取 updates 中的用户并执行对应的 update，
+// 只保存信誉相关的字段并写入变动记录，避免并发的任务基于过期的信誉分互相覆盖
+func (r *userRepository) UpdateTrustScores(updates map[uint]TrustUpdate) error {
+	ids := make([]uint, 0, len(updates))
+	for id := range updates {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for _, id := range ids {
+			var user model.User
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, id).Error; err != nil {
+				return err
+			}
+			change := updates[id](&user)
+			err := tx.Model(&user).Select("trust_score", "trust_penalty", "trust_decayed_at").Updates(&user).Error
+			if err != nil {
+				return err
+			}
+			if change == nil {
+				continue
+			}
+			change.UserID = user.ID
+			if err := tx.Create(change).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// FindTrustChanges 按时间倒序返回用户最近的 limit 条信誉分变动
+func (r *userRepository) FindTrustChanges(userID uint, limit int) ([]model.TrustChange, error) {
+	changes := []model.TrustChange{}
+	err := r.db.Where("user_id = ?", userID).Order("id DESC").Limit(limit).Find(&changes).Error
+	return changes, err
+}
+
+// FindLastActivityAt 返回用户最近一次活跃的时间，从未主动操作过时为注册时间
+func (r *userRepository) FindLastActivityAt(userID uint) (time.Time, error) {
+	var last time.Time
+	err := r.db.Model(&model.User{}).Where("id = ?", userID).
+		Select("COALESCE(last_active_at, created_at)").Scan(&last).Error
+	return last, err
+}
+
+// FindInactiveIDsAfter 按ID顺序返回 lastID 之后、自 activeSince 起没有任何活跃记录的至多 limit 个用户ID
+func (r *userRepository) FindInactiveIDsAfter(lastID uint, activeSince time.Time, limit int) ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&model.User{}).
+		Where("id > ? AND COALESCE(last_active_at, created_at) < ?", lastID, activeSince).
+		Order("id").Limit(limit).Pluck("id", &ids).Error
+	return ids, err
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"github.com/novel/internal/model"
+	"gorm.io/gorm"
+)
+
+// VoteEdge 是投票图中的一条边：投票者对某位作者的评分投出的一张票
+type VoteEdge struct {
+	ID       uint // 投票ID，用于分批读取
+	VoterID  uint
+	AuthorID uint
+	RatingID uint
+	Value    int
+}
+
+// VoteRingRepository 定义了互赞团体分析所需的投票读取与报告存储
+type VoteRingRepository interface {
+	FindVotesAfter(lastID uint, limit int) ([]VoteEdge, error)
+	CreateReport(report *model.VoteRingReport) error
+	FindReports(page, pageSize int) ([]model.VoteRingReport, int64, error)
+	FindReportByID(id uint) (*model.VoteRingReport, error)
+}
+
+type voteRingRepository struct {
+	db *gorm.DB
+}
+
+// NewVoteRingRepository 是 voteRingRepository 的构造函数
+func NewVoteRingRepository(db *gorm.DB) VoteRingRepository {
+	return &voteRingRepository{db: db}
+}
+
+// FindVotesAfter 按ID顺序返回 lastID 之后的至多 limit 张有效投票，已删除评分上的投票不计入
+func (r *voteRingRepository) FindVotesAfter(lastID uint, limit int) ([]VoteEdge, error) {
+	var edges []VoteEdge
+	err := r.db.Model(&model.RatingVote{}).
+		Select("rating_votes.id, rating_votes.user_id AS voter_id, ratings.user_id AS author_id, rating_votes.rating_id, rating_votes.vote AS value").
+		Joins("JOIN ratings ON ratings.id = rating_votes.rating_id AND ratings.deleted_at IS NULL").
+		Where("rating_votes.id > ?", lastID).
+		Order("rating_votes.id").
+		Limit(limit).
+		Scan(&edges).Error
+	return edges, err
+}
+
+// CreateReport 保存一次分析的报告
+func (r *voteRingRepository) CreateReport(report *model.VoteRingReport) error {
+	return r.db.Create(report).Error
+}
+
+// FindReports 按生成时间倒序分页查询分析报告
+func (r *voteRingRepository) FindReports(page, pageSize int) ([]model.VoteRingReport, int64, error) {
+	var total int64
+	if err := r.db.Model(&model.VoteRingReport{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	reports := []model.VoteRingReport{}
+	err := r.db.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&reports).Error
+	return reports, total, err
+}
+
+// FindReportByID 根据ID查找分析报告
+func (r *voteRingRepository) FindReportByID(id uint) (*model.VoteRingReport, error) {
+	var report model.VoteRingReport
+	err := r.db.First(&report, id).Error
+	return &report, err
+}
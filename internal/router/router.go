@@ -1,17 +1,26 @@
 package router
 
 import (
+	"context"
+	"errors"
 	"github.com/gin-gonic/gin"
 	"github.com/novel/internal/handler"
+	"github.com/novel/internal/jobqueue"
 	"github.com/novel/internal/middleware"
+	"github.com/novel/internal/model"
 	"github.com/novel/internal/pkg/config"
+	"github.com/novel/internal/pkg/cursor"
+	"github.com/novel/internal/pkg/jwtkeys"
+	"github.com/novel/internal/pkg/ratelimit"
 	"github.com/novel/internal/repository"
+	"github.com/novel/internal/scheduler"
 	"github.com/novel/internal/service"
 	"gorm.io/gorm"
 )
 
 // SetupRouter 设置并返回一个配置好的 Gin 引擎 (最终版)
-func SetupRouter(db *gorm.DB, cfg *config.Config) *gin.Engine {
+// 评分相关的后台计算任务和定时任务也在这里分别注册到 queue 与 sched
+func SetupRouter(db *gorm.DB, cfg *config.Config, queue *jobqueue.Queue, sched *scheduler.Scheduler) (*gin.Engine, error) {
 	router := gin.New()
 	router.Use(middleware.RequestIDMiddleware())
 	router.Use(gin.Recovery())
@@ -21,43 +30,182 @@ func SetupRouter(db *gorm.DB, cfg *config.Config) *gin.Engine {
 	novelRepo := repository.NewNovelRepository(db)
 	categoryRepo := repository.NewCategoryRepository(db) // <-- 确保已创建
 	tagRepo := repository.NewTagRepository(db)           // <-- 确保已创建
+	jobRepo := repository.NewJobRepository(db)
+	baselineRepo := repository.NewBaselineRepository(db)
+	snapshotRepo := repository.NewSnapshotRepository(db)
+	incidentRepo := repository.NewIncidentRepository(db)
+	voteRingRepo := repository.NewVoteRingRepository(db)
+	tokenRepo := repository.NewTokenRepository(db)
+	txm := repository.NewTxManager(db)
 
-	trustSvc := service.NewTrustService(userRepo, novelRepo)
+	cursorSecret := cfg.Pagination.CursorSecret
+	if cursorSecret == "" {
+		cursorSecret = cfg.JWT.SecretKey
+	}
+	if cursorSecret == "" {
+		return nil, errors.New("pagination.cursor_secret or jwt.secret_key must be configured")
+	}
+	cursors := cursor.NewCodec(cursorSecret)
+
+	weights, err := service.NewWeightPipeline(cfg.Algorithm.WeightFactors)
+	if err != nil {
+		return nil, err
+	}
+
+	trustSvc := service.NewTrustService(userRepo, novelRepo, &cfg.Algorithm.TrustDecay)
+	baselineSvc := service.NewBaselineService(baselineRepo, &cfg.Algorithm)
+	trendingTracker := service.NewTrendingTracker(&cfg.Algorithm.Trending)
+	detector := service.NewReviewBombDetector(&cfg.Algorithm.ReviewBombing)
+	probationSvc := service.NewProbationService(userRepo, jobRepo, txm, &cfg.Algorithm.Probation)
 	// 确保 NewNovelService 的签名和调用是最新版本，能接收所有需要的 repo
-	novelSvc := service.NewNovelService(novelRepo, trustSvc, categoryRepo, tagRepo, &cfg.Algorithm)
-	userSvc := service.NewUserService(userRepo, &cfg.JWT)
+	novelSvc := service.NewNovelService(novelRepo, jobRepo, txm, trustSvc, categoryRepo, tagRepo, cursors, weights, baselineSvc, trendingTracker, incidentRepo, detector, probationSvc)
+	novelSvc.RegisterJobHandlers(queue)
+	chartSvc := service.NewChartService(snapshotRepo, novelRepo)
+	voteRingSvc := service.NewVoteRingService(voteRingRepo, trustSvc, &cfg.Algorithm.VoteRings)
+
+	// --- 定时任务 ---
+	if task := cfg.Scheduler.TrustRecalculation; cfg.Scheduler.Enabled && task.Enabled {
+		err := sched.Register("trust_recalculation", task.Cron, func(ctx context.Context) error {
+			return trustSvc.RecalculateAllUserTrustScores(ctx, task.BatchSize)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if task := cfg.Scheduler.TrustDecay; cfg.Scheduler.Enabled && task.Enabled && cfg.Algorithm.TrustDecay.Enabled {
+		err := sched.Register("trust_decay", task.Cron, func(ctx context.Context) error {
+			return trustSvc.DecayInactiveTrustScores(ctx, task.BatchSize)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if task := cfg.Scheduler.BaselineRefresh; cfg.Scheduler.Enabled && task.Enabled && cfg.Algorithm.Baselines.Enabled {
+		err := sched.Register("baseline_refresh", task.Cron, func(ctx context.Context) error {
+			if err := baselineSvc.RefreshBaselines(ctx); err != nil {
+				return err
+			}
+			return novelSvc.RefreshAllNovelScores(ctx, task.BatchSize)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if task := cfg.Scheduler.IncidentExpiry; cfg.Scheduler.Enabled && task.Enabled && cfg.Algorithm.ReviewBombing.Enabled {
+		if err := sched.Register("incident_expiry", task.Cron, novelSvc.ExpireIncidents); err != nil {
+			return nil, err
+		}
+	}
+	if task := cfg.Scheduler.NovelSnapshot; cfg.Scheduler.Enabled && task.Enabled {
+		if err := sched.Register("novel_snapshot", task.Cron, chartSvc.CaptureDailySnapshot); err != nil {
+			return nil, err
+		}
+	}
+	if task := cfg.Scheduler.VoteRingAnalysis; cfg.Scheduler.Enabled && task.Enabled {
+		err := sched.Register("vote_ring_analysis", task.Cron, func(ctx context.Context) error {
+			_, err := voteRingSvc.Analyze(ctx, task.BatchSize)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	jwtKeys, err := jwtkeys.Load(&cfg.JWT)
+	if err != nil {
+		return nil, err
+	}
+	userSvc := service.NewUserService(userRepo, tokenRepo, txm, &cfg.JWT, jwtKeys)
+	if task := cfg.Scheduler.TokenCleanup; cfg.Scheduler.Enabled && task.Enabled {
+		if err := sched.Register("token_cleanup", task.Cron, userSvc.CleanupExpiredTokens); err != nil {
+			return nil, err
+		}
+	}
 
 	novelHandler := handler.NewNovelHandler(novelSvc)
-	userHandler := handler.NewUserHandler(userSvc)
+	userHandler := handler.NewUserHandler(userSvc, trustSvc)
+	chartHandler := handler.NewChartHandler(chartSvc)
+	jwksHandler := handler.NewJWKSHandler(jwtKeys)
+
+	// 试用期账号的操作频率限制，创建小说只对仍在试用期内的编辑生效
+	limits := cfg.Algorithm.Probation.RateLimits
+	voteLimit := middleware.ProbationRateLimit(probationSvc, ratelimit.New(limits.Vote.Limit, limits.Vote.Window), "vote")
+	novelCreateLimit := middleware.ProbationRateLimit(probationSvc, ratelimit.New(limits.NovelCreate.Limit, limits.NovelCreate.Window), "novel_create")
+	voteRingHandler := handler.NewVoteRingHandler(voteRingSvc)
 
 	// --- 路由设置 ---
+	// 其他服务从这里获取公钥来校验访问令牌
+	router.GET("/.well-known/jwks.json", jwksHandler.GetJWKS)
+
 	apiV1 := router.Group("/api/v1")
 	{
 		// 开放路由
 		apiV1.POST("/register", userHandler.Register)
 		apiV1.POST("/login", userHandler.Login)
+		apiV1.POST("/refresh", userHandler.Refresh)
 
 		novelsPublic := apiV1.Group("/novels")
 		{
 			novelsPublic.GET("", novelHandler.GetNovels)
+			novelsPublic.GET("/trending", novelHandler.GetTrendingNovels)
 			novelsPublic.GET("/:id", novelHandler.GetNovelByID)
+			novelsPublic.GET("/:id/ratings", novelHandler.GetNovelRatings)
+			novelsPublic.GET("/:id/history", chartHandler.GetNovelHistory)
 		}
+		apiV1.GET("/charts/weekly", chartHandler.GetWeeklyChart)
 
 		authRequired := apiV1.Group("")                      // 首先，创建路由组，authRequired 的类型是 *gin.RouterGroup
 		authRequired.Use(middleware.AuthMiddleware(userSvc)) // 然后，对这个路由组应用中间件
 		{
-			// 小说相关的写操作，通常需要认证，甚至需要管理员权限
+			// 登录用户即可进行的操作
+			authRequired.POST("/logout", userHandler.Logout)
+			authRequired.POST("/logout-all", userHandler.LogoutAll)
+			authRequired.GET("/me/trust", userHandler.GetMyTrust)
+
 			novelsProtected := authRequired.Group("/novels")
 			{
-				novelsProtected.POST("", novelHandler.CreateNovel) // <-- 注册新路由
 				novelsProtected.POST("/:id/rate", novelHandler.CreateRating)
 			}
 
 			ratingsProtected := authRequired.Group("/ratings")
 			{
-				ratingsProtected.POST("/:id/vote", novelHandler.VoteForRating)
+				ratingsProtected.PUT("/:id", novelHandler.UpdateRating)
+				ratingsProtected.DELETE("/:id", novelHandler.DeleteRating)
+				ratingsProtected.POST("/:id/vote", voteLimit, novelHandler.VoteForRating)
+			}
+
+			// 小说信息的维护需要编辑及以上角色
+			novelsEditor := authRequired.Group("/novels", middleware.RequireRole(model.RoleEditor))
+			{
+				novelsEditor.POST("", novelCreateLimit, novelHandler.CreateNovel)
+				novelsEditor.PUT("/:id", novelHandler.ReplaceNovel)
+				novelsEditor.PATCH("/:id", novelHandler.UpdateNovel)
+				novelsEditor.GET("/:id/score/explain", novelHandler.ExplainNovelScore)
+			}
+
+			// 删除与恢复需要版主及以上角色
+			novelsModerator := authRequired.Group("/novels", middleware.RequireRole(model.RoleModerator))
+			{
+				novelsModerator.DELETE("/:id", novelHandler.DeleteNovel)
+				novelsModerator.POST("/:id/restore", novelHandler.RestoreNovel)
+			}
+
+			// 评分异常事件 (疑似集中刷分) 由版主处理
+			moderation := authRequired.Group("/moderation", middleware.RequireRole(model.RoleModerator))
+			{
+				moderation.GET("/incidents", novelHandler.ListIncidents)
+				moderation.GET("/incidents/:id", novelHandler.GetIncident)
+				moderation.POST("/incidents/:id/resolve", novelHandler.ResolveIncident)
+			}
+
+			admin := authRequired.Group("/admin", middleware.RequireRole(model.RoleAdmin))
+			{
+				admin.PUT("/users/:id/role", userHandler.UpdateUserRole)
+				admin.POST("/novels/rebuild-scores", novelHandler.RebuildAllNovelScores)
+				admin.POST("/novels/:id/rebuild-scores", novelHandler.RebuildNovelScores)
+				admin.GET("/vote-rings/reports", voteRingHandler.ListReports)
+				admin.GET("/vote-rings/reports/:id", voteRingHandler.GetReport)
 			}
 		}
 	}
-	return router
+	return router, nil
 }
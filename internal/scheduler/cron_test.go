@@ -0,0 +1,48 @@
+package scheduler
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestParseCronNext(t *testing.T) {
+	base := time.Date(2024, time.January, 31, 10, 17, 30, 0, time.UTC) // 周三
+
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"0 4 * * *", time.Date(2024, time.February, 1, 4, 0, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, time.January, 31, 10, 30, 0, 0, time.UTC)},
+		{"5/20 10 * * *", time.Date(2024, time.January, 31, 10, 25, 0, 0, time.UTC)},
+		{"0 9-17 * * 1-5", time.Date(2024, time.January, 31, 11, 0, 0, 0, time.UTC)},
+		{"30 2 * * 7", time.Date(2024, time.February, 4, 2, 30, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1,15 * 5", time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2024, time.January, 31, 11, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{"@every 90m", base.Add(90 * time.Minute)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			s, err := ParseCron(tt.spec)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, s.Next(base))
+		})
+	}
+}
+
+func TestParseCronNeverMatches(t *testing.T) {
+	s, err := ParseCron("0 0 30 2 *")
+	require.NoError(t, err)
+	assert.True(t, s.Next(time.Now()).IsZero())
+}
+
+func TestParseCronInvalid(t *testing.T) {
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "0 0 0 * *", "*/0 * * * *", "5-1 * * * *", "a * * * *", "@every 10ms", "@every soon"} {
+		_, err := ParseCron(spec)
+		assert.Error(t, err, spec)
+	}
+}
@@ -0,0 +1,138 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"github.com/novel/internal/pkg/logger"
+	"go.uber.org/zap"
+	"sync"
+	"time"
+)
+
+// TaskFunc 是定时任务的执行函数，ctx 会在调度器关闭时被取消
+type TaskFunc func(ctx context.Context) error
+
+// Locker 尝试获取一个跨进程的互斥锁，获取成功时返回释放锁的函数
+// 用于保证多个实例同时部署时，同一个定时任务在同一时刻只有一个实例在执行
+type Locker func(ctx context.Context, name string) (unlock func(), acquired bool, err error)
+
+type task struct {
+	name     string
+	schedule Schedule
+	fn       TaskFunc
+}
+
+// Scheduler 是一个进程内的 cron 风格定时任务调度器
+type Scheduler struct {
+	locker Locker
+	tasks  []*task
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	started bool
+}
+
+// New 创建调度器，locker 为 nil 时不做跨实例互斥
+func New(locker Locker) *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Scheduler{locker: locker, ctx: ctx, cancel: cancel}
+}
+
+// Register 注册一个定时任务，spec 为 cron 表达式，必须在 Start 之前调用
+func (s *Scheduler) Register(name, spec string, fn TaskFunc) error {
+	schedule, err := ParseCron(spec)
+	if err != nil {
+		return fmt.Errorf("failed to register scheduled task %s: %w", name, err)
+	}
+	s.tasks = append(s.tasks, &task{name: name, schedule: schedule, fn: fn})
+	return nil
+}
+
+// Start 为每个任务启动一个调度协程
+func (s *Scheduler) Start() {
+	if s.started {
+		return
+	}
+	s.started = true
+	for _, t := range s.tasks {
+		s.wg.Add(1)
+		go s.loop(t)
+	}
+	logger.Infof("Scheduler started with %d tasks", len(s.tasks))
+}
+
+// Shutdown 取消正在执行的任务并等待所有调度协程退出
+func (s *Scheduler) Shutdown(ctx context.Context) error {
+	s.cancel()
+	if !s.started {
+		return nil
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		logger.InfoRaw("Scheduler stopped")
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("scheduler shutdown: %w", ctx.Err())
+	}
+}
+
+// loop 等待任务的下一次触发时间并执行，同一任务在本进程内不会重叠执行
+func (s *Scheduler) loop(t *task) {
+	defer s.wg.Done()
+	for {
+		next := t.schedule.Next(time.Now())
+		if next.IsZero() {
+			logger.WarnRaw("Scheduled task will never run again", zap.String("task", t.name))
+			return
+		}
+		logger.DebugRaw("Scheduled task waiting", zap.String("task", t.name), zap.Time("next_run", next))
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-s.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		s.run(t)
+	}
+}
+
+// run 在持有跨实例锁的情况下执行一次任务
+func (s *Scheduler) run(t *task) {
+	fields := []zap.Field{zap.String("task", t.name)}
+	defer func() {
+		if r := recover(); r != nil {
+			logger.ErrorRaw("Scheduled task panicked", append(fields, zap.Any("panic", r))...)
+		}
+	}()
+
+	if s.locker != nil {
+		unlock, acquired, err := s.locker(s.ctx, t.name)
+		if err != nil {
+			logger.ErrorRaw("Failed to acquire scheduler lock", append(fields, zap.Error(err))...)
+			return
+		}
+		if !acquired {
+			logger.InfoRaw("Scheduled task is running on another instance, skipping", fields...)
+			return
+		}
+		defer unlock()
+	}
+
+	start := time.Now()
+	logger.InfoRaw("Scheduled task started", fields...)
+	if err := t.fn(s.ctx); err != nil {
+		logger.ErrorRaw("Scheduled task failed", append(fields, zap.Error(err), zap.Duration("elapsed", time.Since(start)))...)
+		return
+	}
+	logger.InfoRaw("Scheduled task finished", append(fields, zap.Duration("elapsed", time.Since(start)))...)
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/novel/internal/model"
+	"github.com/novel/internal/pkg/config"
+	"github.com/novel/internal/pkg/logger"
+	"github.com/novel/internal/repository"
+	"go.uber.org/zap"
+	"sync"
+	"time"
+)
+
+// Baseline 是计算某本小说得分时使用的 IMDb 公式参数
+type Baseline struct {
+	Scope   model.BaselineScope `json:"scope"`
+	ScopeID uint                `json:"scope_id,omitempty"`
+	M       float64             `json:"m"`
+	C       float64             `json:"c"`
+}
+
+// BaselineService 负责按分类、出版类型统计评分基准，并为每本小说选择适用的基准
+type BaselineService interface {
+	BaselineFor(novel *model.Novel) Baseline
+	RefreshBaselines(ctx context.Context) error
+}
+
+type baselineKey struct {
+	scope model.BaselineScope
+	id    uint
+}
+
+type baselineService struct {
+	repo   repository.BaselineRepository
+	cfg    config.BaselineConfig
+	global Baseline
+
+	mu       sync.RWMutex
+	cache    map[baselineKey]model.ScoreBaseline
+	loadedAt time.Time
+}
+
+// NewBaselineService 是 baselineService 的构造函数
+// 未启用分组基准时，所有小说都使用配置文件中的全站参数 imdb_m / imdb_c
+func NewBaselineService(repo repository.BaselineRepository, cfg *config.AlgorithmConfig) BaselineService {
+	c := cfg.Baselines
+	if c.CacheTTL <= 0 {
+		c.CacheTTL = 5 * time.Minute
+	}
+	return &baselineService{
+		repo:   repo,
+		cfg:    c,
+		global: Baseline{Scope: model.BaselineScopeGlobal, M: cfg.ImdbM, C: cfg.ImdbC},
+	}
+}
+
+// BaselineFor 按 分类 → 出版类型 → 全站 的顺序选择第一个可用的基准
+// 分组基准没有统计 m 时沿用全站的 m
+func (s *baselineService) BaselineFor(novel *model.Novel) Baseline {
+	if !s.cfg.Enabled {
+		return s.global
+	}
+	cache := s.load()
+	for _, key := range []baselineKey{
+		{model.BaselineScopeCategory, novel.CategoryID},
+		{model.BaselineScopePublicationType, uint(novel.PublicationType)},
+	} {
+		if b, ok := cache[key]; ok {
+			baseline := Baseline{Scope: b.Scope, ScopeID: b.ScopeID, M: b.M, C: b.C}
+			if baseline.M <= 0 {
+				baseline.M = s.global.M
+			}
+			return baseline
+		}
+	}
+	return s.global
+}
+
+// load 返回缓存的分组基准，超过缓存时长后从数据库重新加载
+// 多实例部署时只有一个实例执行统计任务，其他实例依靠定期重新加载获得最新结果
+func (s *baselineService) load() map[baselineKey]model.ScoreBaseline {
+	s.mu.RLock()
+	cache, fresh := s.cache, time.Since(s.loadedAt) < s.cfg.CacheTTL
+	s.mu.RUnlock()
+	if fresh {
+		return cache
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if time.Since(s.loadedAt) < s.cfg.CacheTTL {
+		return s.cache
+	}
+	baselines, err := s.repo.FindAll()
+	if err != nil {
+		// 加载失败时继续使用旧的缓存，稍后再试
+		logger.ErrorRaw("Failed to load score baselines", zap.Error(err))
+		s.loadedAt = time.Now()
+		return s.cache
+	}
+	s.cache = make(map[baselineKey]model.ScoreBaseline, len(baselines))
+	for _, b := range baselines {
+		s.cache[baselineKey{b.Scope, b.ScopeID}] = b
+	}
+	s.loadedAt = time.Now()
+	return s.cache
+}
+
+// RefreshBaselines 根据当前的评分数据重新统计各分类、各出版类型的基准并保存
+// 样本不足 (小说数或权重之和低于配置的阈值) 的分组不生成基准，回退到上一级
+func (s *baselineService) RefreshBaselines(ctx context.Context) error {
+	byCategory, err := s.repo.StatsByCategory(s.cfg.MPercentile)
+	if err != nil {
+		return fmt.Errorf("failed to compute category baselines: %w", err)
+	}
+	byType, err := s.repo.StatsByPublicationType(s.cfg.MPercentile)
+	if err != nil {
+		return fmt.Errorf("failed to compute publication type baselines: %w", err)
+	}
+
+	var baselines []model.ScoreBaseline
+	baselines = s.appendBaselines(baselines, model.BaselineScopeCategory, byCategory)
+	baselines = s.appendBaselines(baselines, model.BaselineScopePublicationType, byType)
+	if err := s.repo.ReplaceAll(baselines); err != nil {
+		return fmt.Errorf("failed to save score baselines: %w", err)
+	}
+
+	// 使本实例立即使用新的基准
+	s.mu.Lock()
+	s.loadedAt = time.Time{}
+	s.mu.Unlock()
+	logger.Info(ctx, "Score baselines refreshed", zap.Int("baselines", len(baselines)))
+	return nil
+}
+
+func (s *baselineService) appendBaselines(dst []model.ScoreBaseline, scope model.BaselineScope, stats []repository.BaselineStats) []model.ScoreBaseline {
+	for _, st := range stats {
+		if st.NovelsCount < s.cfg.MinNovels || st.WeightSum < s.cfg.MinWeightSum || st.WeightSum <= 0 {
+			continue
+		}
+		b := model.ScoreBaseline{
+			Scope:       scope,
+			ScopeID:     st.ScopeID,
+			C:           st.WeightedSum / st.WeightSum,
+			NovelsCount: st.NovelsCount,
+			WeightSum:   st.WeightSum,
+		}
+		if s.cfg.MPercentile > 0 {
+			b.M = st.M
+		}
+		dst = append(dst, b)
+	}
+	return dst
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"github.com/novel/internal/model"
+	"github.com/novel/internal/pkg/config"
+	"github.com/novel/internal/pkg/logger"
+	"github.com/novel/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+// fakeBaselineRepo 在内存中保存评分基准
+type fakeBaselineRepo struct {
+	byCategory []repository.BaselineStats
+	byType     []repository.BaselineStats
+	saved      []model.ScoreBaseline
+}
+
+func (r *fakeBaselineRepo) StatsByCategory(float64) ([]repository.BaselineStats, error) {
+	return r.byCategory, nil
+}
+
+func (r *fakeBaselineRepo) StatsByPublicationType(float64) ([]repository.BaselineStats, error) {
+	return r.byType, nil
+}
+
+func (r *fakeBaselineRepo) ReplaceAll(baselines []model.ScoreBaseline) error {
+	r.saved = baselines
+	return nil
+}
+
+func (r *fakeBaselineRepo) FindAll() ([]model.ScoreBaseline, error) {
+	return r.saved, nil
+}
+
+func TestBaselineFallback(t *testing.T) {
+	require.NoError(t, logger.InitLogger(&config.LogConfig{Level: "error"}))
+	repo := &fakeBaselineRepo{
+		byCategory: []repository.BaselineStats{
+			{ScopeID: 1, NovelsCount: 10, WeightSum: 100, WeightedSum: 850, M: 40},
+			{ScopeID: 2, NovelsCount: 2, WeightSum: 100, WeightedSum: 900}, // 小说数不足
+		},
+		byType: []repository.BaselineStats{
+			{ScopeID: uint(model.TypeWebNovel), NovelsCount: 20, WeightSum: 200, WeightedSum: 1500, M: 30},
+		},
+	}
+	svc := NewBaselineService(repo, &config.AlgorithmConfig{
+		ImdbM: 25, ImdbC: 7,
+		Baselines: config.BaselineConfig{Enabled: true, MinNovels: 5, MinWeightSum: 50},
+	})
+	require.NoError(t, svc.RefreshBaselines(context.Background()))
+	require.Len(t, repo.saved, 2)
+
+	// 分类有足够的样本：使用分类的 C，未配置分位数时沿用全站 m
+	b := svc.BaselineFor(&model.Novel{CategoryID: 1, PublicationType: model.TypeWebNovel})
+	assert.Equal(t, model.BaselineScopeCategory, b.Scope)
+	assert.InDelta(t, 8.5, b.C, 1e-9)
+	assert.InDelta(t, 25, b.M, 1e-9)
+
+	// 分类样本不足时回退到出版类型
+	b = svc.BaselineFor(&model.Novel{CategoryID: 2, PublicationType: model.TypeWebNovel})
+	assert.Equal(t, model.BaselineScopePublicationType, b.Scope)
+	assert.InDelta(t, 7.5, b.C, 1e-9)
+
+	// 都没有时使用全站参数
+	b = svc.BaselineFor(&model.Novel{CategoryID: 3, PublicationType: model.TypePublished})
+	assert.Equal(t, model.BaselineScopeGlobal, b.Scope)
+	assert.InDelta(t, 7.0, b.C, 1e-9)
+}
@@ -0,0 +1,225 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/novel/internal/dto"
+	"github.com/novel/internal/pkg/logger"
+	"github.com/novel/internal/repository"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"sort"
+	"time"
+)
+
+// ErrInvalidDate 表示日期参数不是 YYYY-MM-DD 格式
+var ErrInvalidDate = errors.New("invalid date")
+
+const (
+	defaultHistoryDays = 90
+	maxHistoryDays     = 365
+	// weeklyChartSize 是周榜的上榜名次，只有进入前 weeklyChartSize 名才算上榜
+	weeklyChartSize  = 100
+	defaultChartList = 20
+	maxChartList     = 100
+)
+
+// SnapshotPoint 是小说得分历史中的一个点
+type SnapshotPoint struct {
+	Date          string  `json:"date"`
+	WeightedScore float64 `json:"weighted_score"`
+	RatingsCount  int     `json:"ratings_count"`
+	Rank          int     `json:"rank"`
+	CategoryRank  int     `json:"category_rank"`
+	RankChange    int     `json:"rank_change"` // 与前一个快照相比的名次变化，正数表示上升
+}
+
+// NovelHistory 是小说的得分与排名历史
+type NovelHistory struct {
+	NovelID uint            `json:"novel_id"`
+	Points  []SnapshotPoint `json:"points"`
+}
+
+// ChartEntry 是周榜中的一本小说
+type ChartEntry struct {
+	NovelID       uint    `json:"novel_id"`
+	Title         string  `json:"title"`
+	Rank          *int    `json:"rank"`          // 本期不在快照中 (评分被全部撤回或移出了该分类) 时为 null
+	PreviousRank  *int    `json:"previous_rank"` // 上周不在快照中时为 null
+	RankChange    int     `json:"rank_change"`   // 名次变化，正数表示上升；新上榜或本期不在快照中时为 0
+	WeightedScore float64 `json:"weighted_score"`
+	ScoreChange   float64 `json:"score_change"`
+	NewRatings    int     `json:"new_ratings"` // 本周新增的评分数
+}
+
+// WeeklyChart 对比本期与一周前的快照，列出上升、下降与新上榜的小说
+type WeeklyChart struct {
+	Date         string       `json:"date"`
+	PreviousDate string       `json:"previous_date,omitempty"` // 一周前没有快照时为空，此时榜上的小说都算新上榜
+	CategoryID   *uint        `json:"category_id,omitempty"`
+	ChartSize    int          `json:"chart_size"`
+	Risers       []ChartEntry `json:"risers"`
+	Fallers      []ChartEntry `json:"fallers"` // 包括跌出榜单的小说
+	NewEntries   []ChartEntry `json:"new_entries"`
+}
+
+// ChartService 定义了得分快照与榜单相关的业务逻辑
+type ChartService interface {
+	CaptureDailySnapshot(ctx context.Context) error
+	GetNovelHistory(novelID uint, days int) (*NovelHistory, error)
+	GetWeeklyChart(query *dto.WeeklyChartQuery) (*WeeklyChart, error)
+}
+
+type chartService struct {
+	repo      repository.SnapshotRepository
+	novelRepo repository.NovelRepository
+}
+
+// NewChartService 是 chartService 的构造函数
+func NewChartService(repo repository.SnapshotRepository, novelRepo repository.NovelRepository) ChartService {
+	return &chartService{repo: repo, novelRepo: novelRepo}
+}
+
+// CaptureDailySnapshot 记录当天所有小说的得分与排名，由每日定时任务调用
+func (s *chartService) CaptureDailySnapshot(ctx context.Context) error {
+	count, err := s.repo.CaptureDaily(time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to capture novel snapshots: %w", err)
+	}
+	logger.Info(ctx, "Captured daily novel snapshots", zap.Int64("novels", count))
+	return nil
+}
+
+// GetNovelHistory 返回小说最近 days 天的得分与排名历史
+func (s *chartService) GetNovelHistory(novelID uint, days int) (*NovelHistory, error) {
+	if _, err := s.novelRepo.FindByID(novelID); err != nil {
+		return nil, err
+	}
+	if days <= 0 {
+		days = defaultHistoryDays
+	}
+	if days > maxHistoryDays {
+		days = maxHistoryDays
+	}
+	snapshots, err := s.repo.FindByNovel(novelID, time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		return nil, err
+	}
+
+	history := &NovelHistory{NovelID: novelID, Points: make([]SnapshotPoint, len(snapshots))}
+	for i, snap := range snapshots {
+		p := SnapshotPoint{
+			Date:          snap.Date.Format(time.DateOnly),
+			WeightedScore: snap.WeightedScore,
+			RatingsCount:  snap.RatingsCount,
+			Rank:          snap.Rank,
+			CategoryRank:  snap.CategoryRank,
+		}
+		if i > 0 {
+			p.RankChange = snapshots[i-1].Rank - snap.Rank
+		}
+		history.Points[i] = p
+	}
+	return history, nil
+}
+
+// GetWeeklyChart 生成截至指定日期 (默认最近一次快照) 的周榜
+func (s *chartService) GetWeeklyChart(query *dto.WeeklyChartQuery) (*WeeklyChart, error) {
+	asOf := time.Now()
+	if query.Date != "" {
+		d, err := time.ParseInLocation(time.DateOnly, query.Date, time.Local)
+		if err != nil {
+			return nil, ErrInvalidDate
+		}
+		asOf = d
+	}
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultChartList
+	}
+	if limit > maxChartList {
+		limit = maxChartList
+	}
+
+	date, err := s.repo.LatestDate(asOf)
+	if err != nil {
+		return nil, err
+	}
+	if date == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	chart := &WeeklyChart{Date: date.Format(time.DateOnly), CategoryID: query.CategoryID, ChartSize: weeklyChartSize}
+
+	// 上一期取一周前当天或更早的最近一次快照
+	previous, err := s.repo.LatestDate(date.AddDate(0, 0, -7))
+	if err != nil {
+		return nil, err
+	}
+	// 没有上一期时与一周前 (没有快照的日期) 对比，所有小说都没有上期排名
+	previousDate := date.AddDate(0, 0, -7)
+	if previous != nil {
+		previousDate = *previous
+		chart.PreviousDate = previous.Format(time.DateOnly)
+	}
+	rows, err := s.repo.CompareRanks(*date, previousDate, query.CategoryID, weeklyChartSize)
+	if err != nil {
+		return nil, err
+	}
+	chart.Risers, chart.Fallers, chart.NewEntries = buildWeeklyChart(rows, weeklyChartSize, limit)
+	return chart, nil
+}
+
+// buildWeeklyChart 把两期排名的对比结果分为上升、下降与新上榜三组，每组最多 limit 条
+//   - 新上榜：本期在榜，上期不在榜
+//   - 上升：两期都在榜且名次上升，按上升幅度排序
+//   - 下降：上期在榜且名次下降 (包括跌出榜单)，按下降幅度排序，本期已不在快照中的排在最后
+func buildWeeklyChart(rows []repository.ChartRow, chartSize, limit int) (risers, fallers, newEntries []ChartEntry) {
+	risers, fallers, newEntries = []ChartEntry{}, []ChartEntry{}, []ChartEntry{}
+	for _, row := range rows {
+		e := ChartEntry{
+			NovelID:       row.NovelID,
+			Title:         row.Title,
+			Rank:          row.Rank,
+			PreviousRank:  row.PreviousRank,
+			WeightedScore: row.WeightedScore,
+			NewRatings:    row.RatingsCount,
+		}
+		if row.PreviousScore != nil {
+			e.ScoreChange = row.WeightedScore - *row.PreviousScore
+		}
+		if row.PreviousCount != nil {
+			e.NewRatings = row.RatingsCount - *row.PreviousCount
+		}
+		inChart := row.Rank != nil && *row.Rank <= chartSize
+		wasInChart := row.PreviousRank != nil && *row.PreviousRank <= chartSize
+		if row.Rank != nil && row.PreviousRank != nil {
+			e.RankChange = *row.PreviousRank - *row.Rank
+		}
+		switch {
+		case inChart && !wasInChart:
+			e.RankChange = 0
+			newEntries = append(newEntries, e)
+		case inChart && e.RankChange > 0:
+			risers = append(risers, e)
+		case wasInChart && (row.Rank == nil || e.RankChange < 0):
+			fallers = append(fallers, e)
+		}
+	}
+	sort.SliceStable(risers, func(i, j int) bool { return risers[i].RankChange > risers[j].RankChange })
+	sort.SliceStable(fallers, func(i, j int) bool {
+		if (fallers[i].Rank == nil) != (fallers[j].Rank == nil) {
+			return fallers[j].Rank == nil
+		}
+		return fallers[i].RankChange < fallers[j].RankChange
+	})
+	sort.SliceStable(newEntries, func(i, j int) bool { return *newEntries[i].Rank < *newEntries[j].Rank })
+	return truncateEntries(risers, limit), truncateEntries(fallers, limit), truncateEntries(newEntries, limit)
+}
+
+func truncateEntries(entries []ChartEntry, limit int) []ChartEntry {
+	if len(entries) > limit {
+		return entries[:limit]
+	}
+	return entries
+}
@@ -0,0 +1,40 @@
+package service
+
+import (
+	"github.com/novel/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestBuildWeeklyChart(t *testing.T) {
+	rank := func(r int) *int { return &r }
+	rows := []repository.ChartRow{
+		{NovelID: 1, Rank: rank(1), PreviousRank: rank(1)},  // 名次不变
+		{NovelID: 2, Rank: rank(2), PreviousRank: rank(8)},  // 上升 6 名
+		{NovelID: 3, Rank: rank(3)},                         // 新上榜
+		{NovelID: 4, Rank: rank(4), PreviousRank: rank(5)},  // 上升 1 名
+		{NovelID: 5, Rank: rank(5), PreviousRank: rank(12)}, // 上期不在榜：新上榜
+		{NovelID: 6, Rank: rank(7), PreviousRank: rank(2)},  // 下降 5 名
+		{NovelID: 7, Rank: rank(15), PreviousRank: rank(3)}, // 跌出榜单
+		{NovelID: 8, PreviousRank: rank(4)},                 // 本期已不在快照中
+	}
+	risers, fallers, newEntries := buildWeeklyChart(rows, 10, 10)
+
+	assert.Equal(t, []uint{2, 4}, chartIDs(risers))
+	assert.Equal(t, 6, risers[0].RankChange)
+	assert.Equal(t, []uint{7, 6, 8}, chartIDs(fallers))
+	assert.Equal(t, -12, fallers[0].RankChange)
+	assert.Equal(t, []uint{3, 5}, chartIDs(newEntries))
+	assert.Equal(t, 0, newEntries[1].RankChange)
+
+	risers, _, _ = buildWeeklyChart(rows, 10, 1)
+	assert.Equal(t, []uint{2}, chartIDs(risers))
+}
+
+func chartIDs(entries []ChartEntry) []uint {
+	ids := []uint{}
+	for _, e := range entries {
+		ids = append(ids, e.NovelID)
+	}
+	return ids
+}
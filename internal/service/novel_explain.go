@@ -0,0 +1,103 @@
+package service
+
+import "time"
+
+// ScoreExplanation 解释一本小说的最终得分是如何由 IMDb 加权公式得出的
+// score = v_w/(v_w+m) × R_w + m/(v_w+m) × C
+type ScoreExplanation struct {
+	NovelID       uint    `json:"novel_id"`
+	WeightedScore float64 `json:"weighted_score"` // 最终得分
+	RatingsCount  int     `json:"ratings_count"`
+
+	WeightSum    float64  `json:"v_w"`      // 所有有效评分的权重之和
+	WeightedMean float64  `json:"r_w"`      // 按权重加权的平均分
+	M            float64  `json:"m"`        // 入榜最低影响力阈值
+	C            float64  `json:"c"`        // 基准分
+	Baseline     Baseline `json:"baseline"` // m、c 的来源：分类、出版类型或全站
+
+	RatingsShare float64 `json:"ratings_share"` // 得分中来自评分的比例 v_w/(v_w+m)
+	PriorShare   float64 `json:"prior_share"`   // 得分中来自基准分的比例 m/(v_w+m)
+	Shrinkage    float64 `json:"shrinkage"`     // 收缩造成的偏移 score - R_w，为负表示被拉低
+
+	FrozenUntil *time.Time `json:"frozen_until,omitempty"` // 疑似集中刷分时分数被冻结，weighted_score 为冻结前的分数
+
+	TopRatings []RatingContribution `json:"top_ratings"` // 权重最高的评分
+}
+
+// RatingContribution 是单条评分对加权平均分 R_w 的贡献
+type RatingContribution struct {
+	RatingID     uint          `json:"rating_id"`
+	Username     string        `json:"username"`
+	Score        int           `json:"score"`
+	Weight       float64       `json:"weight"`        // 当前生效的权重
+	WeightShare  float64       `json:"weight_share"`  // 权重占 v_w 的比例
+	Contribution float64       `json:"contribution"`  // 对 R_w 的贡献 score × weight / v_w
+	Factors      []FactorValue `json:"factors"`       // 按当前数据和配置重新计算的各项权重因子
+	CurrentValue float64       `json:"current_value"` // 各因子之积，与 weight 不一致说明权重尚待后台任务更新
+}
+
+const (
+	defaultExplainTop = 10
+	maxExplainTop     = 50
+)
+
+// ExplainNovelScore 给出小说最终得分的计算明细，top 为展示的高权重评分条数
+func (s *novelService) ExplainNovelScore(id uint, top int) (*ScoreExplanation, error) {
+	novel, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if top <= 0 {
+		top = defaultExplainTop
+	}
+	if top > maxExplainTop {
+		top = maxExplainTop
+	}
+
+	baseline := s.baselines.BaselineFor(novel)
+	vw, m := novel.ScoreWeightSum, baseline.M
+	exp := &ScoreExplanation{
+		NovelID:       novel.ID,
+		WeightedScore: novel.WeightedScore,
+		RatingsCount:  novel.RatingsCount,
+		WeightSum:     vw,
+		M:             m,
+		C:             baseline.C,
+		Baseline:      baseline,
+		TopRatings:    []RatingContribution{},
+	}
+	if vw > 0 {
+		exp.WeightedMean = novel.ScoreWeightedSum / vw
+	}
+	if vw+m > 0 {
+		exp.RatingsShare = vw / (vw + m)
+		exp.PriorShare = m / (vw + m)
+	}
+	exp.Shrinkage = novel.WeightedScore - exp.WeightedMean
+	if novel.ScoreFrozen(time.Now()) {
+		exp.FrozenUntil = novel.ScoreFrozenUntil
+	}
+
+	ratings, err := s.repo.FindTopWeightedRatings(id, top)
+	if err != nil {
+		return nil, err
+	}
+	for i := range ratings {
+		r := &ratings[i]
+		current, factors := s.weights.Compute(s.weightInput(r, s.probation.CheckProbation))
+		c := RatingContribution{
+			RatingID:     r.ID,
+			Username:     r.User.Username,
+			Score:        r.Score,
+			Weight:       r.Weight,
+			Factors:      factors,
+			CurrentValue: current,
+		}
+		if vw > 0 {
+			c.WeightShare = r.Weight / vw
+			c.Contribution = float64(r.Score) * r.Weight / vw
+		}
+		exp.TopRatings = append(exp.TopRatings, c)
+	}
+	return exp, nil
+}
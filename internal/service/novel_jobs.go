@@ -0,0 +1,300 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/novel/internal/jobqueue"
+	"github.com/novel/internal/model"
+	"github.com/novel/internal/pkg/logger"
+	"github.com/novel/internal/repository"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"time"
+)
+
+// --- 后台计算任务的载荷 ---
+
+type ratingJobPayload struct {
+	RatingID uint `json:"rating_id"`
+}
+
+type ratingEditedPayload struct {
+	RatingID  uint    `json:"rating_id"`
+	OldWeight float64 `json:"old_weight"`
+}
+
+// ratingWithdrawnPayload 记录撤回时评分的快照，因为评分本身已被删除
+type ratingWithdrawnPayload struct {
+	RatingID uint    `json:"rating_id"`
+	UserID   uint    `json:"user_id"`
+	NovelID  uint    `json:"novel_id"`
+	Weight   float64 `json:"weight"`
+}
+
+type ratingVotedPayload struct {
+	VoterID    uint `json:"voter_id"`
+	RatingID   uint `json:"rating_id"`
+	VoteChange int  `json:"vote_change"`
+}
+
+type probationEndedPayload struct {
+	UserID uint `json:"user_id"`
+}
+
+// enqueueJob 在给定事务中写入一个后台任务
+func (s *novelService) enqueueJob(tx *gorm.DB, jobType string, payload interface{}) error {
+	job, err := jobqueue.NewJob(jobType, payload)
+	if err != nil {
+		return err
+	}
+	return s.jobRepo.WithTx(tx).Enqueue(job)
+}
+
+// RegisterJobHandlers 将评分相关的后台计算任务注册到任务队列
+func (s *novelService) RegisterJobHandlers(q *jobqueue.Queue) {
+	q.Register(model.JobTypeRatingCreated, s.handleRatingCreated)
+	q.Register(model.JobTypeRatingEdited, s.handleRatingEdited)
+	q.Register(model.JobTypeRatingWithdrawn, s.handleRatingWithdrawn)
+	q.Register(model.JobTypeRatingVoted, s.handleRatingVoted)
+	q.Register(model.JobTypeProbationEnded, s.handleProbationEnded)
+	q.Register(model.JobTypeNovelsRebuildScores, s.handleRebuildAllNovelScores)
+}
+
+// 任务失败时会被整体重试，因此每个处理函数都先执行可重复执行的步骤 (权重与小说分数的增量更新在同一事务中完成)，
+// 最后才执行增量修改信誉分这类不可重复的步骤
+
+func (s *novelService) handleRatingCreated(ctx context.Context, payload []byte) error {
+	var p ratingJobPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return fmt.Errorf("%w: invalid payload: %v", jobqueue.ErrDiscard, err)
+	}
+	rating, err := s.findRatingForJob(p.RatingID)
+	if err != nil {
+		return err
+	}
+	// 先检查集中刷分，被标记为可疑的评分在计算权重时即被降权
+	if err := s.screenRating(ctx, rating); err != nil {
+		return err
+	}
+	initialWeight, err := s.updateRatingWeight(ctx, rating)
+	if err != nil {
+		return err
+	}
+	return s.trustSvc.UpdateTrustScoreOnNewRating(rating.UserID, rating.ID, initialWeight)
+}
+
+func (s *novelService) handleRatingEdited(ctx context.Context, payload []byte) error {
+	var p ratingEditedPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return fmt.Errorf("%w: invalid payload: %v", jobqueue.ErrDiscard, err)
+	}
+	rating, err := s.findRatingForJob(p.RatingID)
+	if err != nil {
+		return err
+	}
+	newWeight, err := s.updateRatingWeight(ctx, rating)
+	if err != nil {
+		return err
+	}
+	return s.trustSvc.UpdateTrustScoreOnRatingEdit(rating.UserID, rating.ID, p.OldWeight, newWeight)
+}
+
+func (s *novelService) handleRatingWithdrawn(ctx context.Context, payload []byte) error {
+	var p ratingWithdrawnPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return fmt.Errorf("%w: invalid payload: %v", jobqueue.ErrDiscard, err)
+	}
+	// 小说分数已在撤回的事务中扣除，这里只需撤销评分带来的信誉分
+	return s.trustSvc.UpdateTrustScoreOnRatingWithdrawn(p.UserID, p.RatingID, p.Weight)
+}
+
+func (s *novelService) handleRatingVoted(ctx context.Context, payload []byte) error {
+	var p ratingVotedPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return fmt.Errorf("%w: invalid payload: %v", jobqueue.ErrDiscard, err)
+	}
+	rating, err := s.findRatingForJob(p.RatingID)
+	if err != nil {
+		return err
+	}
+	if _, err := s.updateRatingWeight(ctx, rating); err != nil {
+		return err
+	}
+	return s.trustSvc.UpdateTrustScoreOnVote(p.VoterID, rating.UserID, rating.ID, p.VoteChange)
+}
+
+// handleProbationEnded 为结束试用期的用户的每条评分写入一个编辑任务，
+// 由它重新计算权重 (不再有 probation 因子)，并按新旧权重修正小说分数与信誉奖励
+func (s *novelService) handleProbationEnded(ctx context.Context, payload []byte) error {
+	var p probationEndedPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return fmt.Errorf("%w: invalid payload: %v", jobqueue.ErrDiscard, err)
+	}
+	ratings, err := s.repo.FindRatingWeightsByUser(p.UserID)
+	if err != nil {
+		return err
+	}
+	if len(ratings) == 0 {
+		return nil
+	}
+	err = s.txm.Transaction(func(tx *gorm.DB) error {
+		for _, rating := range ratings {
+			err := s.enqueueJob(tx, model.JobTypeRatingEdited, ratingEditedPayload{RatingID: rating.ID, OldWeight: rating.Weight})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	logger.Info(ctx, "Queued reweighing of ratings after probation ended",
+		zap.Uint("user_id", p.UserID), zap.Int("ratings", len(ratings)))
+	return nil
+}
+
+// handleRebuildAllNovelScores 分批对全部小说执行全量重算，用于修复历史数据
+func (s *novelService) handleRebuildAllNovelScores(ctx context.Context, _ []byte) error {
+	return s.forEachNovel(ctx, "Rebuilding novel scores", 200, func(id uint) error {
+		_, err := s.RebuildNovelScores(id)
+		return err
+	})
+}
+
+// RefreshAllNovelScores 用当前的评分基准重新计算全部小说的最终得分，评分聚合值保持不变
+// 在评分基准重新统计之后由定时任务调用
+func (s *novelService) RefreshAllNovelScores(ctx context.Context, batchSize int) error {
+	if batchSize <= 0 {
+		batchSize = 200
+	}
+	return s.forEachNovel(ctx, "Refreshing novel scores", batchSize, func(id uint) error {
+		_, err := s.repo.ApplyScoreDelta(id, repository.ScoreDelta{}, s.novelScore)
+		return err
+	})
+}
+
+// forEachNovel 按 ID 顺序分批遍历全部小说，处理期间被删除的小说会被跳过
+func (s *novelService) forEachNovel(ctx context.Context, msg string, batchSize int, fn func(id uint) error) error {
+	var lastID uint
+	var processed int
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		ids, err := s.repo.FindIDsAfter(lastID, batchSize)
+		if err != nil {
+			return fmt.Errorf("failed to list novels after id %d: %w", lastID, err)
+		}
+		if len(ids) == 0 {
+			break
+		}
+		for _, id := range ids {
+			if err := fn(id); err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+		}
+		processed += len(ids)
+		lastID = ids[len(ids)-1]
+		logger.Info(ctx, msg, zap.Int("processed", processed), zap.Uint("last_id", lastID))
+	}
+	return nil
+}
+
+// findRatingForJob 加载任务涉及的评分，评分已被删除时放弃该任务
+func (s *novelService) findRatingForJob(ratingID uint) (*model.Rating, error) {
+	rating, err := s.repo.FindRatingByID(ratingID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: rating %d no longer exists", jobqueue.ErrDiscard, ratingID)
+	}
+	return rating, err
+}
+
+// --- 权重与小说分数的计算 ---
+
+// updateRatingWeight 重新计算评分的权重，并在同一事务中把权重的变化增量地计入小说分数
+func (s *novelService) updateRatingWeight(ctx context.Context, rating *model.Rating) (float64, error) {
+	newWeight, _ := s.weights.Compute(s.weightInput(rating, s.probation.OnProbation))
+
+	var novel *model.Novel
+	err := s.txm.Transaction(func(tx *gorm.DB) error {
+		repo := s.repo.WithTx(tx)
+		previous, err := repo.SetRatingWeight(rating.ID, newWeight)
+		if err != nil {
+			return err
+		}
+		// 增量以加锁读到的旧权重和分值为准，而不是任务开始时加载的快照
+		weightChange := newWeight - previous.Weight
+		delta := repository.ScoreDelta{
+			WeightSum:   weightChange,
+			WeightedSum: float64(previous.Score) * weightChange,
+		}
+		novel, err = repo.ApplyScoreDelta(previous.NovelID, delta, s.novelScore)
+		return err
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, fmt.Errorf("%w: rating %d or its novel no longer exists", jobqueue.ErrDiscard, rating.ID)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to update weight of rating %d: %w", rating.ID, err)
+	}
+
+	rating.Weight = newWeight
+	logger.Debug(ctx, "Updated rating weight and novel score",
+		zap.Uint("rating_id", rating.ID),
+		zap.Float64("weight", newWeight),
+		zap.Uint("novel_id", novel.ID),
+		zap.Float64("weighted_score", novel.WeightedScore))
+	return newWeight, nil
+}
+
+// weightInput 组装计算权重所需的数据，rating 需预加载作者
+// onProbation 判断作者是否在试用期：写入权重时用 OnProbation，只读的解释接口用不记录状态的 CheckProbation
+func (s *novelService) weightInput(rating *model.Rating, onProbation func(user *model.User) (bool, error)) *WeightInput {
+	in := &WeightInput{Rating: rating, Now: time.Now()}
+	if rating.User.ID != 0 {
+		in.Author = &rating.User
+		probation, err := onProbation(in.Author)
+		if err != nil {
+			// 无法判断时按已结束试用期处理，不因统计失败而降低正常用户的权重
+			logger.WarnRaw("Failed to check author probation", zap.Uint("user_id", in.Author.ID), zap.Error(err))
+		}
+		in.AuthorOnProbation = probation
+	}
+	return in
+}
+
+// novelScore 根据小说的评分聚合值，用 IMDb 加权公式计算最终分数
+// m、c 取小说所属分类或出版类型的基准，样本不足时使用全站参数；分数冻结期间保持原值
+func (s *novelService) novelScore(novel *model.Novel) float64 {
+	if novel.ScoreFrozen(time.Now()) {
+		return novel.WeightedScore
+	}
+	var weightedAvgScore float64
+	if novel.ScoreWeightSum > 0 {
+		weightedAvgScore = novel.ScoreWeightedSum / novel.ScoreWeightSum
+	}
+	b := s.baselines.BaselineFor(novel)
+	v_w, R_w, m, c := novel.ScoreWeightSum, weightedAvgScore, b.M, b.C
+	if v_w+m <= 0 {
+		return c
+	}
+	return (v_w/(v_w+m))*R_w + (m/(v_w+m))*c
+}
+
+// RebuildNovelScores 全量重新统计一本小说的评分聚合值与热度，作为增量更新出现偏差时的修复工具
+func (s *novelService) RebuildNovelScores(id uint) (*model.Novel, error) {
+	if err := s.rebuildTrending(id); err != nil {
+		return nil, err
+	}
+	return s.repo.RebuildScoreAggregates(id, s.novelScore)
+}
+
+// ScheduleRebuildAllNovelScores 提交一个对全部小说执行全量重算的后台任务
+func (s *novelService) ScheduleRebuildAllNovelScores() error {
+	return s.txm.Transaction(func(tx *gorm.DB) error {
+		return s.enqueueJob(tx, model.JobTypeNovelsRebuildScores, struct{}{})
+	})
+}
@@ -1,83 +1,167 @@
 package service
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"github.com/novel/internal/dto"
+	"github.com/novel/internal/jobqueue"
 	"github.com/novel/internal/model"
-	"github.com/novel/internal/pkg/config"
+	"github.com/novel/internal/pkg/cursor"
+	"github.com/novel/internal/pkg/quality"
 	"github.com/novel/internal/repository"
 	"gorm.io/gorm"
-	"log"
-	"math"
+	"time"
 )
 
+// 定义评分相关的业务错误，方便上层进行判断
+var (
+	ErrRatingAlreadyExists = errors.New("user has already rated this novel")
+	ErrNotRatingOwner      = errors.New("rating does not belong to the user")
+)
+
+// ErrUnknownSortKey 表示请求的排序方式不在支持的列表中
+var ErrUnknownSortKey = repository.ErrUnknownSortKey
+
 // NovelService 定义了与小说相关的业务逻辑接口
 type NovelService interface {
 	GetRankedNovels(query *dto.ListQuery) (*dto.PaginatedResponse, error)
+	GetTrendingNovels(query *dto.TrendingQuery) (*dto.PaginatedResponse, error)
 	GetNovelWithCalculatedScores(id uint) (*NovelScoreDetails, error)
+	GetNovelRatings(novelID uint, query *dto.RatingListQuery) (*dto.PaginatedResponse, error)
+	ExplainNovelScore(id uint, top int) (*ScoreExplanation, error)
 	CreateRatingForNovel(userID, novelID uint, score int, comment string) (*model.Rating, error)
+	UpdateRatingForUser(userID, ratingID uint, score int, comment string) (*model.Rating, error)
+	WithdrawRating(userID, ratingID uint) error
 	VoteForRating(userID, ratingID uint, voteType model.VoteType) error
 	CreateNovel(req *dto.CreateNovelRequest) (*model.Novel, error)
+	RegisterJobHandlers(q *jobqueue.Queue)
+	UpdateNovel(id uint, req *dto.UpdateNovelRequest) (*model.Novel, error)
+	RebuildNovelScores(id uint) (*model.Novel, error)
+	ScheduleRebuildAllNovelScores() error
+	RefreshAllNovelScores(ctx context.Context, batchSize int) error
+	DeleteNovel(id uint) error
+	RestoreNovel(id uint) (*model.Novel, error)
+	ListIncidents(query *dto.IncidentListQuery) (*dto.PaginatedResponse, error)
+	GetIncident(id uint) (*IncidentDetails, error)
+	ResolveIncident(moderatorID, id uint, req *dto.ResolveIncidentRequest) (*model.ScoreIncident, error)
+	ExpireIncidents(ctx context.Context) error
 }
 
 // NovelScoreDetails 是一个新的 DTO，用于封装小说及其各种计算分数
 type NovelScoreDetails struct {
-	Novel         *model.Novel `json:"novel"`
-	RatingsCount  int          `json:"ratings_count"`
-	WeightedScore float64      `json:"weighted_score"`
+	Novel         *model.Novel     `json:"novel"`
+	RatingsCount  int              `json:"ratings_count"`
+	WeightedScore float64          `json:"weighted_score"`
+	Stats         *dto.RatingStats `json:"stats"` // 评分分布与统计值
 }
 
 // novelService 结构体实现了 NovelService 接口
 type novelService struct {
 	repo         repository.NovelRepository
+	jobRepo      repository.JobRepository
+	txm          repository.TxManager
 	trustSvc     TrustService
-	m            float64 // IMDb 公式参数 m
-	c            float64 // IMDb 公式参数 c
+	baselines    BaselineService // 提供每本小说适用的 IMDb 公式参数 m、c
+	trending     *TrendingTracker
+	incidentRepo repository.IncidentRepository
+	detector     *ReviewBombDetector
+	probation    ProbationService // 新账号试用期：评分降权、投票折减
 	categoryRepo repository.CategoryRepository
 	tagRepo      repository.TagRepository
+	cursors      *cursor.Codec
+	weights      *WeightPipeline
 }
 
 // NewNovelService 是 novelService 的构造函数，负责所有依赖的注入
-func NewNovelService(repo repository.NovelRepository, trustSvc TrustService, categoryRepo repository.CategoryRepository, tagRepo repository.TagRepository, cfg *config.AlgorithmConfig) NovelService {
+func NewNovelService(repo repository.NovelRepository, jobRepo repository.JobRepository, txm repository.TxManager, trustSvc TrustService, categoryRepo repository.CategoryRepository, tagRepo repository.TagRepository, cursors *cursor.Codec, weights *WeightPipeline, baselines BaselineService, trending *TrendingTracker, incidentRepo repository.IncidentRepository, detector *ReviewBombDetector, probation ProbationService) NovelService {
 	return &novelService{
 		repo:         repo,
+		jobRepo:      jobRepo,
+		txm:          txm,
 		trustSvc:     trustSvc,
-		m:            cfg.ImdbM,
-		c:            cfg.ImdbC,
+		baselines:    baselines,
+		trending:     trending,
+		incidentRepo: incidentRepo,
+		detector:     detector,
+		probation:    probation,
 		categoryRepo: categoryRepo,
 		tagRepo:      tagRepo,
+		cursors:      cursors,
+		weights:      weights,
 	}
 
 }
 
 // GetRankedNovels 实现了获取排序和分页后的小说列表的业务逻辑
 func (s *novelService) GetRankedNovels(query *dto.ListQuery) (*dto.PaginatedResponse, error) {
-	if query.PageSize > 100 {
-		query.PageSize = 100
+	normalizePaging(&query.Page, &query.PageSize)
+
+	filter := novelListFilter(query)
+	after, err := s.decodeListCursor(query.Cursor, filter)
+	if err != nil {
+		return nil, err
+	}
+	page, err := s.repo.FindAll(query, after)
+	if errors.Is(err, repository.ErrKeysetMismatch) {
+		return nil, ErrInvalidCursor
 	}
-	// 直接将 query 传递给 Repository
-	novels, total, err := s.repo.FindAll(query)
 	if err != nil {
 		return nil, err
 	}
-	return &dto.PaginatedResponse{
-		Total:    total,
-		Page:     query.Page,
-		PageSize: query.PageSize,
-		Data:     novels,
-	}, nil
+	nextCursor, err := s.encodeListCursor(page.Next, filter)
+	if err != nil {
+		return nil, err
+	}
+	return paginatedResponse(page.Total, query.Page, query.PageSize, after != nil, nextCursor, page.Novels), nil
 }
 
-// GetNovelWithCalculatedScores 直接从数据库读取预计算的分数，实现高性能读取
+// GetNovelRatings 分页获取小说的评分列表，只返回对外公开的字段
+func (s *novelService) GetNovelRatings(novelID uint, query *dto.RatingListQuery) (*dto.PaginatedResponse, error) {
+	if _, err := s.repo.FindByID(novelID); err != nil {
+		return nil, err
+	}
+	normalizePaging(&query.Page, &query.PageSize)
+
+	filter := novelRatingsFilter(novelID)
+	after, err := s.decodeListCursor(query.Cursor, filter)
+	if err != nil {
+		return nil, err
+	}
+	page, err := s.repo.FindRatingsByNovel(novelID, query, after)
+	if errors.Is(err, repository.ErrKeysetMismatch) {
+		return nil, ErrInvalidCursor
+	}
+	if err != nil {
+		return nil, err
+	}
+	nextCursor, err := s.encodeListCursor(page.Next, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	ratings := make([]dto.RatingResponse, len(page.Ratings))
+	for i := range page.Ratings {
+		ratings[i] = dto.NewRatingResponse(&page.Ratings[i])
+	}
+	return paginatedResponse(page.Total, query.Page, query.PageSize, after != nil, nextCursor, ratings), nil
+}
+
+// GetNovelWithCalculatedScores 直接从数据库读取预计算的分数，评分分布与统计值通过 SQL 聚合实时计算
 func (s *novelService) GetNovelWithCalculatedScores(id uint) (*NovelScoreDetails, error) {
 	novel, err := s.repo.FindByID(id)
 	if err != nil {
 		return nil, err
 	}
+	stats, err := s.repo.RatingStats(id)
+	if err != nil {
+		return nil, err
+	}
 	return &NovelScoreDetails{
 		Novel:         novel,
 		RatingsCount:  novel.RatingsCount,
 		WeightedScore: novel.WeightedScore,
+		Stats:         stats,
 	}, nil
 }
 
@@ -87,155 +171,184 @@ func (s *novelService) CreateRatingForNovel(userID, novelID uint, score int, com
 	if err != nil {
 		return nil, err
 	}
-	// [TODO] 在这里可以增加业务规则，例如：一个用户对一本书只能评分一次
+	// 一个用户对一本书只能评分一次，修改请使用编辑接口
+	_, err = s.repo.FindUserRatingForNovel(userID, novelID)
+	if err == nil {
+		return nil, ErrRatingAlreadyExists
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("failed to check existing rating")
+	}
 	rating := &model.Rating{
 		UserID:  userID,
 		NovelID: novelID,
 		Score:   score,
 		Comment: comment,
 	}
-	if err := s.repo.CreateRating(rating); err != nil {
+	if err := s.assessCommentQuality(s.repo, rating); err != nil {
+		return nil, err
+	}
+	// 评分与后续计算任务在同一事务中写入，保证计算任务不会丢失
+	// 新评分的权重在计算任务中才确定，此时只计入评分数
+	err = s.txm.Transaction(func(tx *gorm.DB) error {
+		repo := s.repo.WithTx(tx)
+		if err := repo.CreateRating(rating); err != nil {
+			return err
+		}
+		if _, err := repo.ApplyScoreDelta(novelID, repository.ScoreDelta{Count: 1}, s.novelScore); err != nil {
+			return err
+		}
+		if err := s.recordTrending(repo, novelID, s.trending.eventWeight(0), time.Now()); err != nil {
+			return err
+		}
+		return s.enqueueJob(tx, model.JobTypeRatingCreated, ratingJobPayload{RatingID: rating.ID})
+	})
+	if err != nil {
+		// 并发请求可能同时通过上面的检查，由数据库唯一索引兜底
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, ErrRatingAlreadyExists
+		}
 		return nil, errors.New("failed to create rating in repository")
 	}
-	go s.triggerCalculationsOnNewRating(rating)
 	return rating, nil
 }
 
-// VoteForRating 实现了完整的投票业务逻辑
-func (s *novelService) VoteForRating(userID, ratingID uint, voteType model.VoteType) error {
-	rating, err := s.repo.FindRatingByID(ratingID)
+// UpdateRatingForUser 修改用户自己的评分与评论，并重新计算相关分数
+func (s *novelService) UpdateRatingForUser(userID, ratingID uint, score int, comment string) (*model.Rating, error) {
+	var rating *model.Rating
+	err := s.txm.Transaction(func(tx *gorm.DB) error {
+		repo := s.repo.WithTx(tx)
+		var err error
+		if rating, err = repo.FindRatingByIDForUpdate(ratingID); err != nil {
+			return err
+		}
+		if rating.UserID != userID {
+			return ErrNotRatingOwner
+		}
+		oldScore, oldWeight := rating.Score, rating.Weight
+		rating.Score = score
+		rating.Comment = comment
+		if err := s.assessCommentQuality(repo, rating); err != nil {
+			return err
+		}
+		if err := repo.UpdateRatingContent(rating); err != nil {
+			return err
+		}
+		// 权重暂时不变，先按新旧分值的差修正小说分数，权重的变化由计算任务随后补上
+		delta := repository.ScoreDelta{WeightedSum: float64(score-oldScore) * oldWeight}
+		if _, err := repo.ApplyScoreDelta(rating.NovelID, delta, s.novelScore); err != nil {
+			return err
+		}
+		return s.enqueueJob(tx, model.JobTypeRatingEdited, ratingEditedPayload{RatingID: rating.ID, OldWeight: oldWeight})
+	})
 	if err != nil {
-		return err
-	}
-	oldVote, err := s.repo.FindUserVote(userID, ratingID)
-	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
-		return errors.New("failed to check existing vote")
+		if errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, ErrNotRatingOwner) {
+			return nil, err
+		}
+		return nil, errors.New("failed to update rating in repository")
 	}
+	return rating, nil
+}
 
-	var newVote *model.RatingVote
-	isCancelVote := false
-	var voteChange int
+// assessCommentQuality 评估评论质量并计算内容指纹，与更早的评分内容相同的评论视为复制粘贴
+func (s *novelService) assessCommentQuality(repo repository.NovelRepository, rating *model.Rating) error {
+	rating.CommentHash = quality.Hash(rating.Comment)
+	duplicate := false
+	if rating.CommentHash != "" {
+		count, err := repo.CountEarlierRatingsWithCommentHash(rating.CommentHash, rating.ID)
+		if err != nil {
+			return fmt.Errorf("failed to check duplicate comments: %w", err)
+		}
+		duplicate = count > 0
+	}
+	result := quality.Score(rating.Comment, duplicate)
+	rating.QualityScore = &result.Score
+	return nil
+}
 
-	if errors.Is(err, gorm.ErrRecordNotFound) { // 首次投票
-		newVote = &model.RatingVote{UserID: userID, RatingID: ratingID, Vote: voteType}
-		voteChange = int(voteType) // 赞同为+1，反对为-1
-		if voteType == model.VoteTypeUp {
-			rating.UpvotesCount++
-		} else {
-			rating.DownvotesCount++
+// WithdrawRating 撤回用户自己的评分，评分收到的投票一并删除
+func (s *novelService) WithdrawRating(userID, ratingID uint) error {
+	err := s.txm.Transaction(func(tx *gorm.DB) error {
+		repo := s.repo.WithTx(tx)
+		rating, err := repo.FindRatingByIDForUpdate(ratingID)
+		if err != nil {
+			return err
 		}
-	} else { // 已投过票
-		if oldVote.Vote == voteType { // 取消投票
-			isCancelVote = true
-			voteChange = -int(voteType) // 取消赞同为-1，取消反对为+1
-			if oldVote.Vote == model.VoteTypeUp {
-				rating.UpvotesCount--
-			} else {
-				rating.DownvotesCount--
-			}
-		} else { // 改票
-			newVote = &model.RatingVote{UserID: userID, RatingID: ratingID, Vote: voteType}
-			voteChange = 2 * int(voteType) // 赞->踩为-2, 踩->赞为+2
-			if voteType == model.VoteTypeUp {
-				rating.UpvotesCount++
-				rating.DownvotesCount--
-			} else {
-				rating.DownvotesCount++
-				rating.UpvotesCount--
-			}
+		if rating.UserID != userID {
+			return ErrNotRatingOwner
 		}
-	}
-	if isCancelVote {
-		err = s.repo.UpdateRatingVote(rating, oldVote, nil)
-	} else {
-		err = s.repo.UpdateRatingVote(rating, oldVote, newVote)
-	}
+		if err := repo.DeleteRatingWithVotes(rating); err != nil {
+			return err
+		}
+		delta := repository.ScoreDelta{
+			Count:       -1,
+			WeightSum:   -rating.Weight,
+			WeightedSum: -float64(rating.Score) * rating.Weight,
+		}
+		if _, err := repo.ApplyScoreDelta(rating.NovelID, delta, s.novelScore); err != nil {
+			return err
+		}
+		// 撤销评分本身的热度，评分收到的投票的热度随时间衰减，全量重算时才会扣除
+		if err := s.recordTrending(repo, rating.NovelID, -s.trending.eventWeight(0), rating.CreatedAt); err != nil {
+			return err
+		}
+		return s.enqueueJob(tx, model.JobTypeRatingWithdrawn, ratingWithdrawnPayload{
+			RatingID: rating.ID,
+			UserID:   rating.UserID,
+			NovelID:  rating.NovelID,
+			Weight:   rating.Weight,
+		})
+	})
 	if err != nil {
-		return errors.New("failed to update vote")
+		if errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, ErrNotRatingOwner) {
+			return err
+		}
+		return errors.New("failed to withdraw rating")
 	}
-	go s.triggerCalculationsOnVote(userID, rating, voteChange)
 	return nil
 }
 
-// --- 后台异步计算任务 ---
-
-func (s *novelService) triggerCalculationsOnNewRating(rating *model.Rating) {
-	initialWeight := s.calculateAndSaveSingleRatingWeight(rating)
-	s.trustSvc.UpdateTrustScoreOnNewRating(rating.UserID, initialWeight)
-	s.recalculateAndUpdateNovelScores(rating.NovelID)
-}
-
-func (s *novelService) triggerCalculationsOnVote(voterID uint, rating *model.Rating, voteChange int) {
-	s.calculateAndSaveSingleRatingWeight(rating)
-	s.trustSvc.UpdateTrustScoreOnVote(voterID, rating.UserID, voteChange)
-	s.recalculateAndUpdateNovelScores(rating.NovelID)
-}
-
-// --- 核心算法与辅助函数 ---
-
-func (s *novelService) calculateAndSaveSingleRatingWeight(rating *model.Rating) float64 {
-	wAction := s.calculateActionWeight(rating)
-	wQuality := s.calculateQualityWeight(rating)
-	wUser, _ := s.trustSvc.GetUserTrustScore(rating.UserID) // 在后台任务中，我们可以忽略错误，使用默认值
-	wCommunity := s.calculateCommunityWeight(rating)
-
-	finalWeight := wAction * wQuality * wUser * wCommunity
-	rating.Weight = finalWeight
-	if err := s.repo.UpdateRating(rating); err != nil {
-		log.Printf("Failed to update rating weight for rating %d: %v", rating.ID, err)
+// VoteForRating 实现了完整的投票业务逻辑
+// 投票的切换与计数更新由 Repository 在加锁的事务中原子完成，后续计算任务在同一事务中入队
+func (s *novelService) VoteForRating(userID, ratingID uint, voteType model.VoteType) error {
+	voterWeight, err := s.probation.VoterWeight(userID)
+	if err != nil {
+		return err
 	}
-	return finalWeight
-}
-
-func (s *novelService) recalculateAndUpdateNovelScores(novelID uint) {
-	defer func() {
-		if r := recover(); r != nil {
-			log.Printf("FATAL: Recovered in recalculateAndUpdateNovelScores for novelID %d. Panic: %v", novelID, r)
+	err = s.txm.Transaction(func(tx *gorm.DB) error {
+		repo := s.repo.WithTx(tx)
+		outcome, err := repo.ApplyRatingVote(userID, ratingID, voteType, voterWeight)
+		if err != nil {
+			return err
+		}
+		// 取消或改票时撤销原投票的热度，再计入新的投票
+		novelID := outcome.Rating.NovelID
+		if prev := outcome.Previous; prev != nil {
+			if err := s.recordTrending(repo, novelID, -s.trending.eventWeight(prev.Vote), prev.UpdatedAt); err != nil {
+				return err
+			}
 		}
-	}()
-	novel, err := s.repo.FindByIDWithRatings(novelID)
+		if !outcome.Cancelled {
+			if err := s.recordTrending(repo, novelID, s.trending.eventWeight(voteType), time.Now()); err != nil {
+				return err
+			}
+		}
+		return s.enqueueJob(tx, model.JobTypeRatingVoted, ratingVotedPayload{
+			VoterID:    userID,
+			RatingID:   ratingID,
+			VoteChange: outcome.VoteChange,
+		})
+	})
 	if err != nil {
-		log.Printf("ERROR: Failed to find novel %d for score recalculation: %v", novelID, err)
-		return
-	}
-	var totalWeightedScore, totalWeight float64
-	for _, rating := range novel.Ratings {
-		totalWeightedScore += float64(rating.Score) * rating.Weight
-		totalWeight += rating.Weight
-	}
-	var weightedAvgScore float64
-	if totalWeight > 0 {
-		weightedAvgScore = totalWeightedScore / totalWeight
-	}
-	v_w, R_w, m, c := totalWeight, weightedAvgScore, s.m, s.c
-	finalWeightedScore := (v_w/(v_w+m))*R_w + (m/(v_w+m))*c
-	novel.WeightedScore = finalWeightedScore
-	novel.RatingsCount = len(novel.Ratings)
-	if err := s.repo.Update(novel); err != nil {
-		log.Printf("ERROR: Failed to update scores for novel %d: %v", novelID, err)
-	}
-	log.Printf("INFO: Successfully recalculated scores for novel %d. New WeightedScore: %.2f, RatingsCount: %d", novelID, finalWeightedScore, novel.RatingsCount)
-}
-
-func (s *novelService) calculateActionWeight(rating *model.Rating) float64 {
-	if rating.Comment != "" {
-		return 1.0
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		return errors.New("failed to update vote")
 	}
-	return 0.5
-}
-
-func (s *novelService) calculateQualityWeight(rating *model.Rating) float64 {
-	// TODO 未来考虑集成AI进行评论质量的权重计算
-	return 1.0
+	return nil
 }
 
-func (s *novelService) calculateCommunityWeight(rating *model.Rating) float64 {
-	netUpvotes := rating.UpvotesCount - rating.DownvotesCount
-	if netUpvotes < 0 {
-		netUpvotes = 0
-	}
-	return 1 + 0.5*math.Log10(float64(netUpvotes)+1)
-}
+// --- 核心算法与辅助函数 ---
 
 func (s *novelService) CreateNovel(req *dto.CreateNovelRequest) (*model.Novel, error) {
 	category, err := s.categoryRepo.FindOrCreate(req.CategoryName)
@@ -281,3 +394,99 @@ func (s *novelService) CreateNovel(req *dto.CreateNovelRequest) (*model.Novel, e
 
 	return novel, nil
 }
+
+// UpdateNovel 按请求中提供的字段更新小说，分类与标签名称会被重新解析为实体
+func (s *novelService) UpdateNovel(id uint, req *dto.UpdateNovelRequest) (*model.Novel, error) {
+	novel, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	categoryID, publicationType := novel.CategoryID, novel.PublicationType
+
+	if req.CategoryName != nil {
+		category, err := s.categoryRepo.FindOrCreate(*req.CategoryName)
+		if err != nil {
+			return nil, errors.New("failed to process category")
+		}
+		novel.CategoryID = category.ID
+	}
+
+	// tags 为 nil 表示不修改标签关联
+	var tags []*model.Tag
+	if req.TagNames != nil {
+		tags, err = s.tagRepo.FindOrCreateByNames(*req.TagNames)
+		if err != nil {
+			return nil, errors.New("failed to process tags")
+		}
+		if tags == nil {
+			tags = []*model.Tag{}
+		}
+	}
+
+	if req.Title != nil {
+		novel.Title = *req.Title
+	}
+	if req.Author != nil {
+		novel.Author = *req.Author
+	}
+	if req.Description != nil {
+		novel.Description = *req.Description
+	}
+	if req.CoverImageURL != nil {
+		novel.CoverImageURL = *req.CoverImageURL
+	}
+	if req.PublicationType != nil {
+		novel.PublicationType = model.PublicationType(*req.PublicationType)
+	}
+	if req.WordCount != nil {
+		novel.WordCount = *req.WordCount
+	}
+	if req.Publisher != nil {
+		novel.Publisher = nilIfEmpty(*req.Publisher)
+	}
+	if req.Isbn != nil {
+		novel.Isbn = nilIfEmpty(*req.Isbn)
+	}
+	if req.PublicationSite != nil {
+		novel.PublicationSite = *req.PublicationSite
+	}
+	if req.SerializationStatus != nil {
+		novel.SerializationStatus = model.SerializationStatus(*req.SerializationStatus)
+	}
+
+	if err := s.repo.UpdateInTx(novel, tags); err != nil {
+		return nil, errors.New("failed to update novel in transaction")
+	}
+	// 分类或出版类型变化后适用的评分基准可能不同，需要重新计算得分
+	if novel.CategoryID != categoryID || novel.PublicationType != publicationType {
+		rescored, err := s.repo.ApplyScoreDelta(novel.ID, repository.ScoreDelta{}, s.novelScore)
+		if err != nil {
+			return nil, fmt.Errorf("failed to recompute score of novel %d: %w", novel.ID, err)
+		}
+		novel.WeightedScore = rescored.WeightedScore
+	}
+	if tags != nil {
+		novel.Tags = tags
+	}
+	return novel, nil
+}
+
+// DeleteNovel 软删除小说，其评分与投票随之软删除，不再参与任何计算
+// 评分作者已获得的信誉分保持不变
+func (s *novelService) DeleteNovel(id uint) error {
+	return s.repo.DeleteWithRatings(id)
+}
+
+// RestoreNovel 恢复被软删除的小说及随其一起删除的评分与投票
+// 小说上预计算的分数在删除期间保持原样，因此恢复后无需重新计算
+func (s *novelService) RestoreNovel(id uint) (*model.Novel, error) {
+	return s.repo.RestoreWithRatings(id)
+}
+
+// nilIfEmpty 将空字符串转换为 nil，用于可空的字符串字段
+func nilIfEmpty(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
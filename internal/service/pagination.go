@@ -0,0 +1,104 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/novel/internal/dto"
+	"github.com/novel/internal/pkg/cursor"
+	"github.com/novel/internal/repository"
+	"sort"
+	"strings"
+)
+
+// ErrInvalidCursor 表示分页游标无效，或与本次查询的筛选、排序条件不一致
+var ErrInvalidCursor = cursor.ErrInvalidCursor
+
+const (
+	defaultPageSize = 10
+	maxPageSize     = 100
+)
+
+// listCursor 是列表游标中保存的内容
+// Filter 是筛选条件的摘要，防止把一个查询的游标用在另一个查询上
+type listCursor struct {
+	repository.Keyset
+	Filter string `json:"f"`
+}
+
+// normalizePaging 修正不合法的页码和每页数量
+func normalizePaging(page, pageSize *int) {
+	if *pageSize <= 0 {
+		*pageSize = defaultPageSize
+	}
+	if *pageSize > maxPageSize {
+		*pageSize = maxPageSize
+	}
+	if *page < 1 {
+		*page = 1
+	}
+}
+
+// filterDigest 计算筛选条件的摘要
+func filterDigest(filter string) string {
+	sum := sha256.Sum256([]byte(filter))
+	return hex.EncodeToString(sum[:8])
+}
+
+// novelListFilter 计算小说列表筛选条件的摘要
+func novelListFilter(query *dto.ListQuery) string {
+	tagIDs := append([]uint(nil), query.TagIDs...)
+	sort.Slice(tagIDs, func(i, j int) bool { return tagIDs[i] < tagIDs[j] })
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "novels;q=%s;tags=%v", strings.TrimSpace(query.Q), tagIDs)
+	if query.PublicationType != nil {
+		fmt.Fprintf(&b, ";type=%d", *query.PublicationType)
+	}
+	if query.CategoryID != nil {
+		fmt.Fprintf(&b, ";category=%d", *query.CategoryID)
+	}
+	return filterDigest(b.String())
+}
+
+// novelRatingsFilter 计算某本小说评分列表的筛选条件摘要
+func novelRatingsFilter(novelID uint) string {
+	return filterDigest(fmt.Sprintf("ratings;novel=%d", novelID))
+}
+
+// decodeListCursor 解析请求中的游标，未传游标时返回 nil
+func (s *novelService) decodeListCursor(token, filter string) (*repository.Keyset, error) {
+	if token == "" {
+		return nil, nil
+	}
+	var c listCursor
+	if err := s.cursors.Decode(token, &c); err != nil {
+		return nil, err
+	}
+	if c.Filter != filter {
+		return nil, ErrInvalidCursor
+	}
+	return &c.Keyset, nil
+}
+
+// encodeListCursor 生成指向 next 的游标，next 为 nil 时返回空字符串
+func (s *novelService) encodeListCursor(next *repository.Keyset, filter string) (string, error) {
+	if next == nil {
+		return "", nil
+	}
+	return s.cursors.Encode(listCursor{Keyset: *next, Filter: filter})
+}
+
+// paginatedResponse 组装分页响应，按游标翻页时页码没有意义，不返回
+func paginatedResponse(total *int64, page, pageSize int, byCursor bool, nextCursor string, data interface{}) *dto.PaginatedResponse {
+	resp := &dto.PaginatedResponse{
+		Total:      total,
+		PageSize:   pageSize,
+		NextCursor: nextCursor,
+		Data:       data,
+	}
+	if !byCursor {
+		resp.Page = page
+	}
+	return resp
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"github.com/novel/internal/jobqueue"
+	"github.com/novel/internal/model"
+	"github.com/novel/internal/pkg/config"
+	"github.com/novel/internal/repository"
+	"gorm.io/gorm"
+	"time"
+)
+
+// ProbationService 判断账号是否处于新账号试用期
+// 试用期内评分由 probation 权重因子降权，投票折减计入社区认可度，投票与创建小说受频率限制
+type ProbationService interface {
+	OnProbation(user *model.User) (bool, error)
+	CheckProbation(user *model.User) (bool, error)
+	IsOnProbation(userID uint) (bool, error)
+	VoterWeight(userID uint) (float64, error)
+}
+
+type probationService struct {
+	userRepo repository.UserRepository
+	jobRepo  repository.JobRepository
+	txm      repository.TxManager
+	cfg      *config.ProbationConfig
+	now      func() time.Time
+}
+
+// NewProbationService 是 probationService 的构造函数
+func NewProbationService(userRepo repository.UserRepository, jobRepo repository.JobRepository, txm repository.TxManager, cfg *config.ProbationConfig) ProbationService {
+	return &probationService{userRepo: userRepo, jobRepo: jobRepo, txm: txm, cfg: cfg, now: time.Now}
+}
+
+// OnProbation 判断用户是否仍在试用期
+// 已结束试用期的用户直接返回；注册时长达标后才统计评分数与收到的赞同票，全部达标时记录试用期结束，
+// 并在同一事务中写入重新计算其评分权重的任务，试用期内被降权的评分随之恢复
+func (s *probationService) OnProbation(user *model.User) (bool, error) {
+	onProbation, eligible, err := s.evaluate(user)
+	if err != nil || !eligible {
+		return onProbation, err
+	}
+	now := s.now()
+	if err := s.endProbation(user.ID, now); err != nil {
+		return false, err
+	}
+	user.ProbationEndedAt = &now
+	return false, nil
+}
+
+// CheckProbation 与 OnProbation 的判断相同，但全部达标时不记录试用期结束，供只读的查询使用
+func (s *probationService) CheckProbation(user *model.User) (bool, error) {
+	onProbation, _, err := s.evaluate(user)
+	return onProbation, err
+}
+
+// evaluate 判断用户是否仍在试用期，eligible 表示尚未记录结束但已满足全部结束条件
+func (s *probationService) evaluate(user *model.User) (onProbation, eligible bool, err error) {
+	if !s.cfg.Enabled || user == nil || user.ID == 0 || user.ProbationEndedAt != nil {
+		return false, false, nil
+	}
+	if s.now().Sub(user.CreatedAt) < s.cfg.MinAccountAge {
+		return true, false, nil
+	}
+	stats, err := s.userRepo.FindActivityStats(user.ID)
+	if err != nil {
+		return false, false, err
+	}
+	if stats.RatingsCount < int64(s.cfg.MinRatings) || stats.UpvotesReceived < int64(s.cfg.MinUpvotesReceived) {
+		return true, false, nil
+	}
+	return false, true, nil
+}
+
+// endProbation 记录试用期结束，只有真正结束试用期的那次调用写入重新计算权重的任务
+func (s *probationService) endProbation(userID uint, at time.Time) error {
+	return s.txm.Transaction(func(tx *gorm.DB) error {
+		ended, err := s.userRepo.WithTx(tx).EndProbation(userID, at)
+		if err != nil || !ended {
+			return err
+		}
+		job, err := jobqueue.NewJob(model.JobTypeProbationEnded, probationEndedPayload{UserID: userID})
+		if err != nil {
+			return err
+		}
+		return s.jobRepo.WithTx(tx).Enqueue(job)
+	})
+}
+
+// IsOnProbation 按用户ID判断是否仍在试用期
+func (s *probationService) IsOnProbation(userID uint) (bool, error) {
+	if !s.cfg.Enabled {
+		return false, nil
+	}
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return false, err
+	}
+	return s.OnProbation(user)
+}
+
+// VoterWeight 返回用户的投票计入评分赞同/反对信誉分之和的值：投票者的信誉分，试用期内再乘以 vote_weight
+func (s *probationService) VoterWeight(userID uint) (float64, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return 0, err
+	}
+	onProbation, err := s.OnProbation(user)
+	if err != nil {
+		return 0, err
+	}
+	if onProbation {
+		return user.TrustScore * s.cfg.VoteWeight, nil
+	}
+	return user.TrustScore, nil
+}
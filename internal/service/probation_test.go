@@ -0,0 +1,116 @@
+package service
+
+import (
+	"github.com/novel/internal/model"
+	"github.com/novel/internal/pkg/config"
+	"github.com/novel/internal/repository"
+	"github.com/novel/internal/repository/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+// recordingJobRepo 记录写入的任务
+type recordingJobRepo struct {
+	repository.JobRepository
+	jobs []*model.Job
+}
+
+func (r *recordingJobRepo) WithTx(tx *gorm.DB) repository.JobRepository { return r }
+
+func (r *recordingJobRepo) Enqueue(jobs ...*model.Job) error {
+	r.jobs = append(r.jobs, jobs...)
+	return nil
+}
+
+func newTestProbationService(repo *mocks.UserRepositoryMock, now time.Time) *probationService {
+	cfg := &config.ProbationConfig{
+		Enabled:            true,
+		MinAccountAge:      7 * 24 * time.Hour,
+		MinRatings:         5,
+		MinUpvotesReceived: 3,
+		VoteWeight:         0.5,
+	}
+	svc := NewProbationService(repo, &recordingJobRepo{}, immediateTxManager{}, cfg).(*probationService)
+	svc.now = func() time.Time { return now }
+	return svc
+}
+
+func TestProbationByAccountAge(t *testing.T) {
+	now := time.Now()
+	repo := new(mocks.UserRepositoryMock)
+	svc := newTestProbationService(repo, now)
+
+	// 注册时长不足时无需查询活跃度
+	user := &model.User{Model: gorm.Model{ID: 1, CreatedAt: now.Add(-24 * time.Hour)}}
+	onProbation, err := svc.OnProbation(user)
+	require.NoError(t, err)
+	assert.True(t, onProbation)
+	repo.AssertNotCalled(t, "FindActivityStats", uint(1))
+}
+
+func TestProbationEndsWhenAllThresholdsMet(t *testing.T) {
+	now := time.Now()
+	repo := new(mocks.UserRepositoryMock)
+	svc := newTestProbationService(repo, now)
+
+	// 评分数达标但收到的赞同票不足，仍在试用期
+	inactive := &model.User{Model: gorm.Model{ID: 1, CreatedAt: now.AddDate(0, -1, 0)}}
+	repo.On("FindActivityStats", uint(1)).Return(&repository.UserActivityStats{RatingsCount: 8, UpvotesReceived: 2}, nil)
+	onProbation, err := svc.OnProbation(inactive)
+	require.NoError(t, err)
+	assert.True(t, onProbation)
+
+	// 全部达标后记录试用期结束并写入重新计算权重的任务，之后不再查询
+	active := &model.User{Model: gorm.Model{ID: 2, CreatedAt: now.AddDate(0, -1, 0)}}
+	repo.On("FindActivityStats", uint(2)).Return(&repository.UserActivityStats{RatingsCount: 5, UpvotesReceived: 3}, nil).Once()
+	repo.On("EndProbation", uint(2), now).Return(true, nil).Once()
+	onProbation, err = svc.OnProbation(active)
+	require.NoError(t, err)
+	assert.False(t, onProbation)
+	require.NotNil(t, active.ProbationEndedAt)
+	jobs := svc.jobRepo.(*recordingJobRepo).jobs
+	require.Len(t, jobs, 1)
+	assert.Equal(t, model.JobTypeProbationEnded, jobs[0].Type)
+	assert.JSONEq(t, `{"user_id":2}`, jobs[0].Payload)
+
+	onProbation, err = svc.OnProbation(active)
+	require.NoError(t, err)
+	assert.False(t, onProbation)
+	repo.AssertExpectations(t)
+}
+
+func TestCheckProbationDoesNotEndProbation(t *testing.T) {
+	now := time.Now()
+	repo := new(mocks.UserRepositoryMock)
+	svc := newTestProbationService(repo, now)
+
+	// 全部达标时只返回判断结果，不记录试用期结束
+	user := &model.User{Model: gorm.Model{ID: 1, CreatedAt: now.AddDate(0, -1, 0)}}
+	repo.On("FindActivityStats", uint(1)).Return(&repository.UserActivityStats{RatingsCount: 5, UpvotesReceived: 3}, nil)
+	onProbation, err := svc.CheckProbation(user)
+	require.NoError(t, err)
+	assert.False(t, onProbation)
+	assert.Nil(t, user.ProbationEndedAt)
+	repo.AssertNotCalled(t, "EndProbation", uint(1), now)
+}
+
+func TestProbationVoterWeight(t *testing.T) {
+	now := time.Now()
+	repo := new(mocks.UserRepositoryMock)
+	svc := newTestProbationService(repo, now)
+
+	ended := now.AddDate(0, 0, -1)
+	repo.On("FindByID", uint(1)).Return(&model.User{Model: gorm.Model{ID: 1, CreatedAt: now}, TrustScore: 1.0}, nil)
+	repo.On("FindByID", uint(2)).Return(&model.User{Model: gorm.Model{ID: 2}, TrustScore: 1.4, ProbationEndedAt: &ended}, nil)
+
+	weight, err := svc.VoterWeight(1)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.5, weight, 1e-9)
+
+	weight, err = svc.VoterWeight(2)
+	require.NoError(t, err)
+	assert.InDelta(t, 1.4, weight, 1e-9)
+}
@@ -0,0 +1,374 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/novel/internal/dto"
+	"github.com/novel/internal/model"
+	"github.com/novel/internal/pkg/config"
+	"github.com/novel/internal/pkg/logger"
+	"github.com/novel/internal/repository"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"math"
+	"time"
+)
+
+// ErrIncidentResolved 表示异常事件已经处理过
+var ErrIncidentResolved = errors.New("incident has already been resolved")
+
+// ReviewBombDetector 根据配置判断一本小说最近的评分是否为集中刷分
+type ReviewBombDetector struct {
+	cfg config.ReviewBombingConfig
+}
+
+// NewReviewBombDetector 是 ReviewBombDetector 的构造函数，未配置的参数使用默认值
+func NewReviewBombDetector(cfg *config.ReviewBombingConfig) *ReviewBombDetector {
+	c := *cfg
+	if c.Window <= 0 {
+		c.Window = time.Hour
+	}
+	if c.MinRatings <= 0 {
+		c.MinRatings = 10
+	}
+	if c.HistoryPeriod <= c.Window {
+		c.HistoryPeriod = 720 * time.Hour
+	}
+	if c.VelocityMultiplier <= 0 {
+		c.VelocityMultiplier = 5
+	}
+	if c.SkewThreshold <= 0 {
+		c.SkewThreshold = 3
+	}
+	if c.LowTrustScore <= 0 {
+		c.LowTrustScore = 0.95
+	}
+	if c.FreezeFor <= 0 {
+		c.FreezeFor = 72 * time.Hour
+	}
+	return &ReviewBombDetector{cfg: c}
+}
+
+// evaluate 判断窗口内的评分是否构成集中刷分，构成时返回待创建的事件 (只填写检测数据)
+// historyCount 是窗口之前 history_period 内的评分数，reference 是用于比较的参考分
+func (d *ReviewBombDetector) evaluate(stats *repository.RatingWindowStats, historyCount int64, reference float64) (*model.ScoreIncident, bool) {
+	if stats.Count < d.cfg.MinRatings {
+		return nil, false
+	}
+	// 按历史速度折算到一个窗口内的评分数，评分很少的小说至少按 1 条计算
+	expected := float64(historyCount) * d.cfg.Window.Seconds() / (d.cfg.HistoryPeriod - d.cfg.Window).Seconds()
+	if float64(stats.Count) < d.cfg.VelocityMultiplier*math.Max(expected, 1) {
+		return nil, false
+	}
+	skew := stats.MeanScore - reference
+	if math.Abs(skew) < d.cfg.SkewThreshold {
+		return nil, false
+	}
+	lowTrustShare := float64(stats.LowTrustCount) / float64(stats.Count)
+	if lowTrustShare < d.cfg.LowTrustShare {
+		return nil, false
+	}
+
+	direction := 1
+	if skew < 0 {
+		direction = -1
+	}
+	return &model.ScoreIncident{
+		Status:        model.IncidentStatusOpen,
+		RatingsCount:  stats.Count,
+		ExpectedCount: expected,
+		WindowMean:    stats.MeanScore,
+		ReferenceMean: reference,
+		Direction:     direction,
+		LowTrustShare: lowTrustShare,
+	}, true
+}
+
+// suspicious 判断一条评分是否符合事件的可疑条件：由低信誉用户给出，且向事件方向偏离参考分
+// rating 需预加载作者
+func (d *ReviewBombDetector) suspicious(incident *model.ScoreIncident, rating *model.Rating) bool {
+	if rating.User.ID == 0 || rating.User.TrustScore >= d.cfg.LowTrustScore {
+		return false
+	}
+	return (float64(rating.Score)-incident.ReferenceMean)*float64(incident.Direction) >= d.cfg.SkewThreshold
+}
+
+// screenRating 在新评分的计算任务中检查小说是否正在遭遇集中刷分
+// 小说已有待处理的事件时，只判断这条评分是否可疑；否则检查最近的评分是否构成新的事件
+// 冻结已到期的事件先按过期处理，不再标记新的评分
+func (s *novelService) screenRating(ctx context.Context, rating *model.Rating) error {
+	if !s.detector.cfg.Enabled {
+		return nil
+	}
+	incident, err := s.incidentRepo.FindOpenByNovel(rating.NovelID)
+	if err == nil && !incident.FrozenUntil.After(time.Now()) {
+		if err := s.expireIncident(ctx, incident.ID); err != nil {
+			return err
+		}
+		err = gorm.ErrRecordNotFound
+	}
+	if err == nil {
+		if rating.SuspectedIncidentID == nil && s.detector.suspicious(incident, rating) {
+			if err := s.incidentRepo.MarkRatingSuspicious(incident.ID, rating.ID); err != nil {
+				return fmt.Errorf("failed to mark rating %d as suspicious: %w", rating.ID, err)
+			}
+			rating.SuspectedIncidentID = &incident.ID
+		}
+		return nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	novel, err := s.repo.FindByID(rating.NovelID)
+	if err != nil {
+		return err
+	}
+	cfg := s.detector.cfg
+	now := time.Now()
+	since := now.Add(-cfg.Window)
+	stats, err := s.incidentRepo.RatingWindowStats(novel.ID, since, cfg.LowTrustScore)
+	if err != nil {
+		return err
+	}
+	if stats.Count < cfg.MinRatings {
+		return nil
+	}
+	history, err := s.incidentRepo.CountRatingsBetween(novel.ID, now.Add(-cfg.HistoryPeriod), since)
+	if err != nil {
+		return err
+	}
+	// 小说自身的评分足够多时与自身的加权平均分比较，否则与所属分类的基准分比较
+	reference := s.baselines.BaselineFor(novel).C
+	if novel.ScoreWeightSum >= cfg.MinReferenceWeight && novel.ScoreWeightSum > 0 {
+		reference = novel.ScoreWeightedSum / novel.ScoreWeightSum
+	}
+	incident, ok := s.detector.evaluate(stats, history, reference)
+	if !ok {
+		return nil
+	}
+	incident.NovelID = novel.ID
+	incident.WindowStart = since
+
+	err = s.openIncident(ctx, novel, incident, rating)
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		// 另一个任务同时开启了事件，按已有事件处理这条评分
+		return s.screenRating(ctx, rating)
+	}
+	return err
+}
+
+// openIncident 创建异常事件、标记可疑评分并冻结小说分数
+// 冻结的分数取窗口开始之前最近一次快照的分数，没有快照时取当前分数
+func (s *novelService) openIncident(ctx context.Context, novel *model.Novel, incident *model.ScoreIncident, current *model.Rating) error {
+	cfg := s.detector.cfg
+	frozenScore := novel.WeightedScore
+	if before, err := s.incidentRepo.ScoreBefore(novel.ID, incident.WindowStart); err != nil {
+		return err
+	} else if before != nil {
+		frozenScore = *before
+	}
+	incident.FrozenScore = frozenScore
+	incident.FrozenUntil = time.Now().Add(cfg.FreezeFor)
+
+	err := s.txm.Transaction(func(tx *gorm.DB) error {
+		incidents := s.incidentRepo.WithTx(tx)
+		if err := incidents.Create(incident); err != nil {
+			return err
+		}
+		marked, err := incidents.MarkSuspicious(incident.ID, &repository.SuspicionCriteria{
+			NovelID:       novel.ID,
+			Since:         incident.WindowStart,
+			LowTrustScore: cfg.LowTrustScore,
+			Reference:     incident.ReferenceMean,
+			Direction:     incident.Direction,
+			MinDeviation:  cfg.SkewThreshold,
+		})
+		if err != nil {
+			return err
+		}
+		incident.SuspiciousCount = len(marked)
+		if err := incidents.Save(incident); err != nil {
+			return err
+		}
+		for _, r := range marked {
+			if r.ID == current.ID {
+				current.SuspectedIncidentID = &incident.ID
+				continue
+			}
+			if err := s.enqueueReweigh(tx, &r); err != nil {
+				return err
+			}
+		}
+		return s.repo.WithTx(tx).FreezeScore(novel.ID, frozenScore, incident.FrozenUntil)
+	})
+	if err != nil {
+		return err
+	}
+	logger.Warn(ctx, "Suspected review bombing, novel score frozen",
+		zap.Uint("novel_id", novel.ID),
+		zap.Uint("incident_id", incident.ID),
+		zap.Int("ratings", incident.RatingsCount),
+		zap.Float64("window_mean", incident.WindowMean),
+		zap.Float64("reference_mean", incident.ReferenceMean),
+		zap.Int("suspicious", incident.SuspiciousCount))
+	return nil
+}
+
+// expiredIncidentBatch 是每次处理的到期事件数
+const expiredIncidentBatch = 100
+
+// ExpireIncidents 把冻结已到期、仍无人处理的事件标记为过期并解除小说分数的冻结，由定时任务调用
+func (s *novelService) ExpireIncidents(ctx context.Context) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		incidents, err := s.incidentRepo.FindExpired(time.Now(), expiredIncidentBatch)
+		if err != nil {
+			return fmt.Errorf("failed to list expired incidents: %w", err)
+		}
+		for i := range incidents {
+			if err := s.expireIncident(ctx, incidents[i].ID); err != nil {
+				return err
+			}
+		}
+		if len(incidents) < expiredIncidentBatch {
+			return nil
+		}
+	}
+}
+
+// expireIncident 锁定事件，仍待处理且冻结已到期时标记为过期并解除分数冻结
+// 已标记的评分保持降权，等待版主事后处理
+func (s *novelService) expireIncident(ctx context.Context, id uint) error {
+	expired := false
+	err := s.txm.Transaction(func(tx *gorm.DB) error {
+		incidents := s.incidentRepo.WithTx(tx)
+		incident, err := incidents.FindByIDForUpdate(id)
+		if err != nil {
+			return err
+		}
+		if incident.Status != model.IncidentStatusOpen || incident.FrozenUntil.After(time.Now()) {
+			return nil
+		}
+		incident.Status = model.IncidentStatusExpired
+		if err := incidents.Save(incident); err != nil {
+			return err
+		}
+		expired = true
+		_, err = s.repo.WithTx(tx).UnfreezeScore(incident.NovelID, s.novelScore)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to expire incident %d: %w", id, err)
+	}
+	if expired {
+		logger.Info(ctx, "Score incident expired without moderation, novel score unfrozen", zap.Uint("incident_id", id))
+	}
+	return nil
+}
+
+// enqueueReweigh 提交重新计算评分权重的任务，rating 为权重变化之前的评分
+// 权重为 0 的评分尚未执行过创建任务，创建任务会按最新的标记计算权重，无需重复提交
+func (s *novelService) enqueueReweigh(tx *gorm.DB, rating *model.Rating) error {
+	if rating.Weight == 0 {
+		return nil
+	}
+	return s.enqueueJob(tx, model.JobTypeRatingEdited, ratingEditedPayload{RatingID: rating.ID, OldWeight: rating.Weight})
+}
+
+// IncidentDetails 是异常事件及其可疑评分
+type IncidentDetails struct {
+	*model.ScoreIncident
+	NovelTitle        string               `json:"novel_title"`
+	SuspiciousRatings []dto.RatingResponse `json:"suspicious_ratings"`
+}
+
+// ListIncidents 分页列出评分异常事件，最新的在前
+func (s *novelService) ListIncidents(query *dto.IncidentListQuery) (*dto.PaginatedResponse, error) {
+	normalizePaging(&query.Page, &query.PageSize)
+	incidents, total, err := s.incidentRepo.FindAll(model.IncidentStatus(query.Status), query.Page, query.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	return paginatedResponse(&total, query.Page, query.PageSize, false, "", incidents), nil
+}
+
+// GetIncident 返回异常事件的详情，包括被标记为可疑的评分
+func (s *novelService) GetIncident(id uint) (*IncidentDetails, error) {
+	incident, err := s.incidentRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	details := &IncidentDetails{ScoreIncident: incident, SuspiciousRatings: []dto.RatingResponse{}}
+	if novel, err := s.repo.FindByID(incident.NovelID); err == nil {
+		details.NovelTitle = novel.Title
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	ratings, err := s.incidentRepo.FindSuspiciousRatings(id)
+	if err != nil {
+		return nil, err
+	}
+	for i := range ratings {
+		details.SuspiciousRatings = append(details.SuspiciousRatings, dto.NewRatingResponse(&ratings[i]))
+	}
+	return details, nil
+}
+
+// ResolveIncident 由版主处理异常事件并解除分数冻结，已过期的事件同样可以处理
+// 确认刷分时可疑评分保持降权；判定为误报时清除标记，可疑评分由后台任务恢复正常权重
+func (s *novelService) ResolveIncident(moderatorID, id uint, req *dto.ResolveIncidentRequest) (*model.ScoreIncident, error) {
+	var incident *model.ScoreIncident
+	err := s.txm.Transaction(func(tx *gorm.DB) error {
+		incidents := s.incidentRepo.WithTx(tx)
+		var err error
+		if incident, err = incidents.FindByIDForUpdate(id); err != nil {
+			return err
+		}
+		wasOpen := incident.Status == model.IncidentStatusOpen
+		if !wasOpen && incident.Status != model.IncidentStatusExpired {
+			return ErrIncidentResolved
+		}
+
+		now := time.Now()
+		incident.ResolvedAt = &now
+		incident.ResolvedBy = &moderatorID
+		incident.Note = req.Note
+		incident.Status = model.IncidentStatusConfirmed
+		if req.Action == "dismiss" {
+			incident.Status = model.IncidentStatusDismissed
+			cleared, err := incidents.ClearSuspicious(incident.ID)
+			if err != nil {
+				return err
+			}
+			for i := range cleared {
+				if err := s.enqueueReweigh(tx, &cleared[i]); err != nil {
+					return err
+				}
+			}
+		}
+		if err := incidents.Save(incident); err != nil {
+			return err
+		}
+		if !wasOpen {
+			// 过期时分数已经解冻，小说此后可能又进入了新的事件，不能解除新事件的冻结
+			return nil
+		}
+		_, err = s.repo.WithTx(tx).UnfreezeScore(incident.NovelID, s.novelScore)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// 小说已被删除，只记录处理结果
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return incident, nil
+}
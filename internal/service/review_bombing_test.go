@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"github.com/novel/internal/dto"
+	"github.com/novel/internal/model"
+	"github.com/novel/internal/pkg/config"
+	"github.com/novel/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+func TestReviewBombDetectorEvaluate(t *testing.T) {
+	d := NewReviewBombDetector(&config.ReviewBombingConfig{
+		Enabled:            true,
+		Window:             time.Hour,
+		MinRatings:         10,
+		HistoryPeriod:      721 * time.Hour, // 窗口之前正好 720 小时
+		VelocityMultiplier: 5,
+		SkewThreshold:      3,
+		LowTrustScore:      0.95,
+		LowTrustShare:      0.5,
+	})
+	bomb := &repository.RatingWindowStats{Count: 20, MeanScore: 1.5, LowTrustCount: 16}
+
+	incident, ok := d.evaluate(bomb, 720, 8.0) // 平时每小时 1 条
+	require.True(t, ok)
+	assert.Equal(t, -1, incident.Direction)
+	assert.InDelta(t, 1.0, incident.ExpectedCount, 1e-9)
+	assert.InDelta(t, 0.8, incident.LowTrustShare, 1e-9)
+
+	// 热门小说平时每小时就有 10 条评分，20 条不算异常
+	_, ok = d.evaluate(bomb, 7200, 8.0)
+	assert.False(t, ok)
+
+	// 平均分与参考分接近
+	_, ok = d.evaluate(bomb, 720, 3.0)
+	assert.False(t, ok)
+
+	// 多数评分来自正常用户
+	_, ok = d.evaluate(&repository.RatingWindowStats{Count: 20, MeanScore: 1.5, LowTrustCount: 5}, 720, 8.0)
+	assert.False(t, ok)
+
+	// 评分数太少
+	_, ok = d.evaluate(&repository.RatingWindowStats{Count: 8, MeanScore: 1.5, LowTrustCount: 8}, 0, 8.0)
+	assert.False(t, ok)
+
+	// 集中打高分同样会被识别
+	incident, ok = d.evaluate(&repository.RatingWindowStats{Count: 12, MeanScore: 9.8, LowTrustCount: 12}, 0, 5.5)
+	require.True(t, ok)
+	assert.Equal(t, 1, incident.Direction)
+
+	// 可疑评分：低信誉用户向事件方向偏离参考分
+	down := &model.ScoreIncident{ReferenceMean: 8, Direction: -1}
+	assert.True(t, d.suspicious(down, &model.Rating{Score: 1, User: model.User{Model: gorm.Model{ID: 1}, TrustScore: 0.9}}))
+	assert.False(t, d.suspicious(down, &model.Rating{Score: 1, User: model.User{Model: gorm.Model{ID: 2}, TrustScore: 1.2}}))
+	assert.False(t, d.suspicious(down, &model.Rating{Score: 7, User: model.User{Model: gorm.Model{ID: 3}, TrustScore: 0.9}}))
+}
+
+// fakeIncidentRepo 在内存中保存异常事件，只实现过期处理用到的方法
+type fakeIncidentRepo struct {
+	repository.IncidentRepository
+	incidents map[uint]*model.ScoreIncident
+}
+
+func (r *fakeIncidentRepo) WithTx(*gorm.DB) repository.IncidentRepository { return r }
+
+func (r *fakeIncidentRepo) FindByIDForUpdate(id uint) (*model.ScoreIncident, error) {
+	incident, ok := r.incidents[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *incident
+	return &copied, nil
+}
+
+func (r *fakeIncidentRepo) Save(incident *model.ScoreIncident) error {
+	copied := *incident
+	r.incidents[incident.ID] = &copied
+	return nil
+}
+
+func (r *fakeIncidentRepo) FindOpenByNovel(novelID uint) (*model.ScoreIncident, error) {
+	for _, incident := range r.incidents {
+		if incident.NovelID == novelID && incident.Status == model.IncidentStatusOpen {
+			copied := *incident
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeIncidentRepo) FindExpired(now time.Time, limit int) ([]model.ScoreIncident, error) {
+	var expired []model.ScoreIncident
+	for _, incident := range r.incidents {
+		if incident.Status == model.IncidentStatusOpen && !incident.FrozenUntil.After(now) && len(expired) < limit {
+			expired = append(expired, *incident)
+		}
+	}
+	return expired, nil
+}
+
+func (r *fakeIncidentRepo) RatingWindowStats(uint, time.Time, float64) (*repository.RatingWindowStats, error) {
+	return &repository.RatingWindowStats{}, nil
+}
+
+// fakeFrozenNovelRepo 保存一本分数被冻结的小说
+type fakeFrozenNovelRepo struct {
+	repository.NovelRepository
+	novel *model.Novel
+}
+
+func (r *fakeFrozenNovelRepo) WithTx(*gorm.DB) repository.NovelRepository { return r }
+
+func (r *fakeFrozenNovelRepo) FindByID(uint) (*model.Novel, error) { return r.novel, nil }
+
+func (r *fakeFrozenNovelRepo) UnfreezeScore(_ uint, score repository.ScoreFunc) (*model.Novel, error) {
+	r.novel.ScoreFrozenUntil = nil
+	r.novel.WeightedScore = score(r.novel)
+	return r.novel, nil
+}
+
+func newExpiryTestService(frozenUntil time.Time) (*novelService, *fakeIncidentRepo, *fakeFrozenNovelRepo) {
+	novel := &model.Novel{Model: gorm.Model{ID: 1}, WeightedScore: 8, ScoreWeightSum: 10, ScoreWeightedSum: 30, ScoreFrozenUntil: &frozenUntil}
+	incidents := &fakeIncidentRepo{incidents: map[uint]*model.ScoreIncident{
+		5: {ID: 5, NovelID: 1, Status: model.IncidentStatusOpen, FrozenScore: 8, FrozenUntil: frozenUntil},
+	}}
+	novels := &fakeFrozenNovelRepo{novel: novel}
+	svc := &novelService{
+		repo:         novels,
+		txm:          immediateTxManager{},
+		incidentRepo: incidents,
+		detector:     NewReviewBombDetector(&config.ReviewBombingConfig{Enabled: true}),
+		baselines:    &fakeGlobalBaseline{baseline: Baseline{M: 0, C: 6}},
+	}
+	return svc, incidents, novels
+}
+
+// fakeGlobalBaseline 对所有小说返回同一个基准
+type fakeGlobalBaseline struct {
+	BaselineService
+	baseline Baseline
+}
+
+func (b *fakeGlobalBaseline) BaselineFor(*model.Novel) Baseline { return b.baseline }
+
+func TestExpireIncidents(t *testing.T) {
+	svc, incidents, novels := newExpiryTestService(time.Now().Add(-time.Minute))
+
+	require.NoError(t, svc.ExpireIncidents(context.Background()))
+	assert.Equal(t, model.IncidentStatusExpired, incidents.incidents[5].Status)
+	assert.Nil(t, novels.novel.ScoreFrozenUntil)
+	assert.InDelta(t, 3.0, novels.novel.WeightedScore, 1e-9, "解冻后按评分聚合值重新计算")
+
+	// 过期的事件仍可由版主处理，但不会再次解冻
+	novels.novel.ScoreFrozenUntil = &time.Time{}
+	incident, err := svc.ResolveIncident(9, 5, &dto.ResolveIncidentRequest{Action: "confirm"})
+	require.NoError(t, err)
+	assert.Equal(t, model.IncidentStatusConfirmed, incident.Status)
+	assert.NotNil(t, novels.novel.ScoreFrozenUntil)
+}
+
+func TestScreenRatingExpiresStaleIncident(t *testing.T) {
+	svc, incidents, novels := newExpiryTestService(time.Now().Add(-time.Minute))
+
+	// 冻结到期后的新评分不再被标记为可疑
+	rating := &model.Rating{Model: gorm.Model{ID: 11}, NovelID: 1, Score: 1, User: model.User{Model: gorm.Model{ID: 3}, TrustScore: 0.5}}
+	require.NoError(t, svc.screenRating(context.Background(), rating))
+	assert.Nil(t, rating.SuspectedIncidentID)
+	assert.Equal(t, model.IncidentStatusExpired, incidents.incidents[5].Status)
+	assert.Nil(t, novels.novel.ScoreFrozenUntil)
+
+	// 冻结期内的事件保持不变
+	svc, incidents, _ = newExpiryTestService(time.Now().Add(time.Hour))
+	require.NoError(t, svc.ExpireIncidents(context.Background()))
+	assert.Equal(t, model.IncidentStatusOpen, incidents.incidents[5].Status)
+}
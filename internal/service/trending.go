@@ -0,0 +1,151 @@
+package service
+
+import (
+	"github.com/novel/internal/dto"
+	"github.com/novel/internal/model"
+	"github.com/novel/internal/pkg/config"
+	"github.com/novel/internal/pkg/trending"
+	"github.com/novel/internal/repository"
+	"time"
+)
+
+// TrendingTracker 按配置的半衰期维护小说在各时间窗口的热度排序键
+type TrendingTracker struct {
+	halfLives map[trending.Window]time.Duration
+	cfg       config.TrendingConfig
+}
+
+// NewTrendingTracker 是 TrendingTracker 的构造函数，未配置的参数使用默认值
+func NewTrendingTracker(cfg *config.TrendingConfig) *TrendingTracker {
+	c := *cfg
+	if c.DayHalfLife <= 0 {
+		c.DayHalfLife = 6 * time.Hour
+	}
+	if c.WeekHalfLife <= 0 {
+		c.WeekHalfLife = 48 * time.Hour
+	}
+	if c.MonthHalfLife <= 0 {
+		c.MonthHalfLife = 240 * time.Hour
+	}
+	if c.RatingWeight <= 0 {
+		c.RatingWeight = 1
+	}
+	return &TrendingTracker{
+		halfLives: map[trending.Window]time.Duration{
+			trending.Day:   c.DayHalfLife,
+			trending.Week:  c.WeekHalfLife,
+			trending.Month: c.MonthHalfLife,
+		},
+		cfg: c,
+	}
+}
+
+// trendingKey 返回小说在某个时间窗口的热度排序键字段
+func trendingKey(novel *model.Novel, w trending.Window) *float64 {
+	switch w {
+	case trending.Day:
+		return &novel.TrendingDay
+	case trending.Month:
+		return &novel.TrendingMonth
+	default:
+		return &novel.TrendingWeek
+	}
+}
+
+// record 把一次发生在 at 时刻的事件计入小说的全部时间窗口，weight 为负表示撤销
+func (t *TrendingTracker) record(novel *model.Novel, weight float64, at, now time.Time) {
+	for w, halfLife := range t.halfLives {
+		key := trendingKey(novel, w)
+		*key = trending.Add(*key, weight, at, now, halfLife)
+	}
+}
+
+// Heat 返回小说在某个时间窗口 now 时刻的热度
+func (t *TrendingTracker) Heat(novel *model.Novel, w trending.Window, now time.Time) float64 {
+	return trending.Heat(*trendingKey(novel, w), now, t.halfLives[w])
+}
+
+// eventWeight 返回一次热度事件计入的热度
+func (t *TrendingTracker) eventWeight(vote model.VoteType) float64 {
+	switch vote {
+	case model.VoteTypeUp:
+		return t.cfg.UpvoteWeight
+	case model.VoteTypeDown:
+		return t.cfg.DownvoteWeight
+	default:
+		return t.cfg.RatingWeight
+	}
+}
+
+// horizon 是全量重算时回溯的时长，更早的事件贡献已不足千分之一
+func (t *TrendingTracker) horizon() time.Duration {
+	var longest time.Duration
+	for _, halfLife := range t.halfLives {
+		if halfLife > longest {
+			longest = halfLife
+		}
+	}
+	return 10 * longest
+}
+
+// recordTrending 在小说的热度中计入 (或以负的 weight 撤销) 一次发生在 at 时刻的事件
+func (s *novelService) recordTrending(repo repository.NovelRepository, novelID uint, weight float64, at time.Time) error {
+	if weight == 0 {
+		return nil
+	}
+	now := time.Now()
+	return repo.ApplyTrending(novelID, func(novel *model.Novel) {
+		s.trending.record(novel, weight, at, now)
+	})
+}
+
+// rebuildTrending 根据评分和投票记录全量重算小说的热度，用于修复增量更新的偏差或半衰期调整之后
+func (s *novelService) rebuildTrending(novelID uint) error {
+	now := time.Now()
+	events, err := s.repo.FindTrendingEvents(novelID, now.Add(-s.trending.horizon()))
+	if err != nil {
+		return err
+	}
+	return s.repo.ApplyTrending(novelID, func(novel *model.Novel) {
+		for _, w := range trending.Windows {
+			*trendingKey(novel, w) = 0
+		}
+		for _, e := range events {
+			s.trending.record(novel, s.trending.eventWeight(e.Vote), e.At, now)
+		}
+	})
+}
+
+// TrendingNovel 是热度榜中的一本小说及其当前热度
+type TrendingNovel struct {
+	*model.Novel
+	Heat float64 `json:"heat"`
+}
+
+// GetTrendingNovels 按时间窗口获取热度榜，分页方式与小说列表相同
+func (s *novelService) GetTrendingNovels(query *dto.TrendingQuery) (*dto.PaginatedResponse, error) {
+	window, ok := trending.ParseWindow(query.Window)
+	if !ok {
+		return nil, ErrUnknownSortKey
+	}
+	resp, err := s.GetRankedNovels(&dto.ListQuery{
+		Page:            query.Page,
+		PageSize:        query.PageSize,
+		SortBy:          "trending_" + string(window),
+		Cursor:          query.Cursor,
+		PublicationType: query.PublicationType,
+		CategoryID:      query.CategoryID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	novels := resp.Data.([]model.Novel)
+	now := time.Now()
+	items := make([]TrendingNovel, len(novels))
+	for i := range novels {
+		items[i] = TrendingNovel{Novel: &novels[i], Heat: s.trending.Heat(&novels[i], window, now)}
+	}
+	resp.Data = items
+	return resp, nil
+}
@@ -1,29 +1,62 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"github.com/novel/internal/model"
+	"github.com/novel/internal/pkg/config"
+	"github.com/novel/internal/pkg/logger"
 	"github.com/novel/internal/repository"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
 	"log"
+	"math"
 	"time"
 )
 
+const (
+	defaultTrustChangesLimit = 20
+	maxTrustChangesLimit     = 100
+)
+
+// TrustSummary 向用户解释其当前的信誉分
+type TrustSummary struct {
+	TrustScore    float64             `json:"trust_score"`
+	Tier          model.TrustTier     `json:"tier"`
+	Penalty       float64             `json:"penalty,omitempty"`         // 投票分析给出的惩罚
+	LastActiveAt  time.Time           `json:"last_active_at"`            // 最近一次评分或投票的时间
+	DecayStartsAt *time.Time          `json:"decay_starts_at,omitempty"` // 继续不活跃时开始衰减的时间，未开启衰减时为空
+	RecentChanges []model.TrustChange `json:"recent_changes"`
+}
+
 // TrustService 定义了计算用户信誉的接口 (最终版)
 type TrustService interface {
 	GetUserTrustScore(userID uint) (float64, error)
-	UpdateTrustScoreOnNewRating(userID uint, ratingWeight float64) error
-	UpdateTrustScoreOnVote(voterID, authorID uint, voteChange int) error
+	GetTrustSummary(userID uint, limit int) (*TrustSummary, error)
+	UpdateTrustScoreOnNewRating(userID, ratingID uint, ratingWeight float64) error
+	UpdateTrustScoreOnRatingEdit(userID, ratingID uint, oldWeight, newWeight float64) error
+	UpdateTrustScoreOnRatingWithdrawn(userID, ratingID uint, ratingWeight float64) error
+	UpdateTrustScoreOnVote(voterID, authorID, ratingID uint, voteChange int) error
+	RecalculateAllUserTrustScores(ctx context.Context, batchSize int) error
+	DecayInactiveTrustScores(ctx context.Context, batchSize int) error
+	ApplyTrustPenalties(penalties map[uint]float64) (int, error)
 }
 
 // trustService 结构体实现了 TrustService 接口 (最终版)
 type trustService struct {
 	userRepo  repository.UserRepository
 	novelRepo repository.NovelRepository // 注入 novelRepo 以备全量计算使用
+	decay     *config.TrustDecayConfig
+	now       func() time.Time
 }
 
 // NewTrustService 构造函数 (最终版)
-func NewTrustService(userRepo repository.UserRepository, novelRepo repository.NovelRepository) TrustService {
+func NewTrustService(userRepo repository.UserRepository, novelRepo repository.NovelRepository, decay *config.TrustDecayConfig) TrustService {
 	return &trustService{
 		userRepo:  userRepo,
 		novelRepo: novelRepo,
+		decay:     decay,
+		now:       time.Now,
 	}
 }
 
@@ -36,47 +69,164 @@ func (s *trustService) GetUserTrustScore(userID uint) (float64, error) {
 	return user.TrustScore, nil
 }
 
-// --- 增量计算方法 ---
-
-func (s *trustService) UpdateTrustScoreOnNewRating(userID uint, ratingWeight float64) error {
+// GetTrustSummary 返回用户当前的信誉分、衰减状态与最近 limit 条变动记录
+func (s *trustService) GetTrustSummary(userID uint, limit int) (*TrustSummary, error) {
+	if limit <= 0 {
+		limit = defaultTrustChangesLimit
+	}
+	if limit > maxTrustChangesLimit {
+		limit = maxTrustChangesLimit
+	}
 	user, err := s.userRepo.FindByID(userID)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	lastActive, err := s.userRepo.FindLastActivityAt(userID)
+	if err != nil {
+		return nil, err
 	}
-	var scoreChange float64
+	changes, err := s.userRepo.FindTrustChanges(userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	summary := &TrustSummary{
+		TrustScore:    user.TrustScore,
+		Tier:          model.TrustTierOf(user.TrustScore),
+		Penalty:       user.TrustPenalty,
+		LastActiveAt:  lastActive,
+		RecentChanges: changes,
+	}
+	if s.decay.Enabled {
+		startsAt := lastActive.Add(s.decay.InactiveAfter)
+		summary.DecayStartsAt = &startsAt
+	}
+	return summary, nil
+}
+
+// minRecordedPeriodicDelta 是全量重算与衰减写入变动记录的最小变化量
+const minRecordedPeriodicDelta = 0.01
+
+// trustCompute 基于用户最新的状态返回新的信誉分 (未截断) 与变动原因，可以同时修改用户的其他信誉字段
+type trustCompute func(user *model.User) (float64, model.TrustChange)
+
+// updateTrustScore 加锁读取用户，用 compute 基于最新的信誉分计算新分数后保存并记录变动
+func (s *trustService) updateTrustScore(userID uint, compute trustCompute) error {
+	return s.userRepo.UpdateTrustScores(map[uint]repository.TrustUpdate{userID: s.trustUpdate(compute)})
+}
+
+// trustUpdate 把 compute 包装为 repository.TrustUpdate：把新分数截断到上下限，并返回实际的变动
+func (s *trustService) trustUpdate(compute trustCompute) repository.TrustUpdate {
+	return func(user *model.User) *model.TrustChange {
+		score, change := compute(user)
+		score = s.applyLimits(score)
+		change.Delta = score - user.TrustScore
+		change.ScoreAfter = score
+		user.TrustScore = score
+		if math.Abs(change.Delta) < 1e-9 {
+			return nil
+		}
+		// 每天执行的全量重算与衰减几乎对每个用户都会产生微小的变化 (例如注册天数带来的加分)，
+		// 这些变化只更新信誉分、不写入变动记录，避免淹没真正的事件
+		periodic := change.Reason == model.TrustReasonRecalculation || change.Reason == model.TrustReasonDecay
+		if periodic && math.Abs(change.Delta) < minRecordedPeriodicDelta {
+			return nil
+		}
+		return &change
+	}
+}
+
+// --- 增量计算方法 ---
+
+func (s *trustService) UpdateTrustScoreOnNewRating(userID, ratingID uint, ratingWeight float64) error {
+	return s.updateTrustScore(userID, func(user *model.User) (float64, model.TrustChange) {
+		return user.TrustScore + s.ratingReward(ratingWeight),
+			model.TrustChange{Reason: model.TrustReasonRatingCreated, RatingID: &ratingID}
+	})
+}
+
+// UpdateTrustScoreOnRatingEdit 评分被编辑后，用新旧权重对应奖励的差值修正信誉分
+func (s *trustService) UpdateTrustScoreOnRatingEdit(userID, ratingID uint, oldWeight, newWeight float64) error {
+	scoreChange := s.ratingReward(newWeight) - s.ratingReward(oldWeight)
+	if scoreChange == 0 {
+		return nil
+	}
+	return s.updateTrustScore(userID, func(user *model.User) (float64, model.TrustChange) {
+		return user.TrustScore + scoreChange,
+			model.TrustChange{Reason: model.TrustReasonRatingEdited, RatingID: &ratingID}
+	})
+}
+
+// UpdateTrustScoreOnRatingWithdrawn 评分被撤回后，收回当初因这条评分获得的信誉分
+func (s *trustService) UpdateTrustScoreOnRatingWithdrawn(userID, ratingID uint, ratingWeight float64) error {
+	return s.updateTrustScore(userID, func(user *model.User) (float64, model.TrustChange) {
+		return user.TrustScore - s.ratingReward(ratingWeight),
+			model.TrustChange{Reason: model.TrustReasonRatingWithdrawn, RatingID: &ratingID}
+	})
+}
+
+// ratingReward 返回一条评分为作者带来的信誉分奖励，高权重评分奖励更多
+func (s *trustService) ratingReward(ratingWeight float64) float64 {
 	if ratingWeight > 0.8 {
-		scoreChange = 0.1
-	} else {
-		scoreChange = 0.02
+		return 0.1
 	}
-	user.TrustScore = s.applyLimits(user.TrustScore + scoreChange)
-	return s.userRepo.Update(user)
+	return 0.02
 }
 
-func (s *trustService) UpdateTrustScoreOnVote(voterID, authorID uint, voteChange int) error {
-	author, err := s.userRepo.FindByID(authorID)
-	if err != nil {
-		return err
+// UpdateTrustScoreOnVote 在同一事务中更新评分作者与投票者的信誉分，任务重试时不会只完成其中一半
+func (s *trustService) UpdateTrustScoreOnVote(voterID, authorID, ratingID uint, voteChange int) error {
+	updates := map[uint]repository.TrustUpdate{
+		authorID: s.trustUpdate(func(author *model.User) (float64, model.TrustChange) {
+			return author.TrustScore + float64(voteChange)*0.01,
+				model.TrustChange{Reason: model.TrustReasonVoteReceived, RatingID: &ratingID, VoterID: &voterID}
+		}),
 	}
-	authorScoreChange := float64(voteChange) * 0.01
-	author.TrustScore = s.applyLimits(author.TrustScore + authorScoreChange)
-	if err := s.userRepo.Update(author); err != nil {
-		return err
+	if voterID != authorID {
+		updates[voterID] = s.trustUpdate(func(voter *model.User) (float64, model.TrustChange) {
+			return voter.TrustScore + 0.001, model.TrustChange{Reason: model.TrustReasonVoteCast, RatingID: &ratingID}
+		})
 	}
-	if voterID == authorID {
-		return nil
+	return s.userRepo.UpdateTrustScores(updates)
+}
+
+// RecalculateAllUserTrustScores 分批全量重算所有用户的信誉分，由定时任务调用
+// 单个用户失败只记录日志并继续，ctx 被取消时在当前批次结束后停止
+func (s *trustService) RecalculateAllUserTrustScores(ctx context.Context, batchSize int) error {
+	if batchSize <= 0 {
+		batchSize = 200
 	}
-	voter, err := s.userRepo.FindByID(voterID)
-	if err != nil {
-		return err
+	var lastID uint
+	var processed, failed int
+	for {
+		if err := ctx.Err(); err != nil {
+			logger.Warn(ctx, "Trust score recalculation interrupted",
+				zap.Int("processed", processed), zap.Int("failed", failed), zap.Uint("last_id", lastID))
+			return err
+		}
+		ids, err := s.userRepo.FindIDsAfter(lastID, batchSize)
+		if err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			break
+		}
+		for _, id := range ids {
+			if err := s.RecalculateAndSaveUserTrustScore(id); err != nil {
+				failed++
+				logger.Warn(ctx, "Failed to recalculate trust score", zap.Uint("user_id", id), zap.Error(err))
+				continue
+			}
+			processed++
+		}
+		lastID = ids[len(ids)-1]
+		logger.Info(ctx, "Trust score recalculation progress",
+			zap.Int("processed", processed), zap.Int("failed", failed), zap.Uint("last_id", lastID))
 	}
-	voterScoreChange := 0.001
-	voter.TrustScore = s.applyLimits(voter.TrustScore + voterScoreChange)
-	return s.userRepo.Update(voter)
+	logger.Info(ctx, "Trust score recalculation completed", zap.Int("processed", processed), zap.Int("failed", failed))
+	return nil
 }
 
 // RecalculateAndSaveUserTrustScore 全量重新计算一个用户的信誉分 (作为内部工具)
-// 注意：这个方法不再是接口的一部分，但实现被保留了下来，供内部定时任务调用
+// 注意：这个方法不是接口的一部分，由 RecalculateAllUserTrustScores 在定时任务中逐个调用
 func (s *trustService) RecalculateAndSaveUserTrustScore(userID uint) error {
 	defer func() {
 		if r := recover(); r != nil {
@@ -102,8 +252,141 @@ func (s *trustService) RecalculateAndSaveUserTrustScore(userID uint) error {
 	score += float64(highQualityComments) * 0.1
 	score += float64(totalUpvotes) * 0.01
 
-	user.TrustScore = s.applyLimits(score)
-	return s.userRepo.Update(user)
+	// 计入不活跃造成的衰减，使全量重算与定时衰减的结果一致
+	var lastActive time.Time
+	if s.decay.Enabled {
+		if lastActive, err = s.userRepo.FindLastActivityAt(userID); err != nil {
+			return err
+		}
+	}
+	return s.updateTrustScore(userID, func(user *model.User) (float64, model.TrustChange) {
+		total := score - user.TrustPenalty
+		if s.decay.Enabled {
+			now := s.now()
+			if decayFrom := lastActive.Add(s.decay.InactiveAfter); now.After(decayFrom) {
+				total = decayTrust(s.applyLimits(total), s.decayTarget(user), now.Sub(decayFrom), s.decay.HalfLife)
+				user.TrustDecayedAt = &now
+			}
+		}
+		return total, model.TrustChange{Reason: model.TrustReasonRecalculation}
+	})
+}
+
+// DecayInactiveTrustScores 分批让长期不活跃用户的信誉分向中性值回落，由定时任务调用
+// 衰减从最近一次活跃加上 inactive_after 与上一次衰减中较晚的时间开始计算，因此重复执行不会重复衰减
+func (s *trustService) DecayInactiveTrustScores(ctx context.Context, batchSize int) error {
+	if !s.decay.Enabled {
+		return nil
+	}
+	if batchSize <= 0 {
+		batchSize = 200
+	}
+	now := s.now()
+	activeSince := now.Add(-s.decay.InactiveAfter)
+	var lastID uint
+	var decayed, failed int
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		ids, err := s.userRepo.FindInactiveIDsAfter(lastID, activeSince, batchSize)
+		if err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			break
+		}
+		for _, id := range ids {
+			changed, err := s.decayUserTrustScore(id, now)
+			if err != nil {
+				failed++
+				logger.Warn(ctx, "Failed to decay trust score", zap.Uint("user_id", id), zap.Error(err))
+				continue
+			}
+			if changed {
+				decayed++
+			}
+		}
+		lastID = ids[len(ids)-1]
+	}
+	logger.Info(ctx, "Trust score decay completed", zap.Int("decayed", decayed), zap.Int("failed", failed))
+	return nil
+}
+
+// decayUserTrustScore 衰减单个不活跃用户的信誉分，返回信誉分是否发生变化
+func (s *trustService) decayUserTrustScore(userID uint, now time.Time) (bool, error) {
+	lastActive, err := s.userRepo.FindLastActivityAt(userID)
+	if err != nil {
+		return false, err
+	}
+	changed := false
+	err = s.updateTrustScore(userID, func(user *model.User) (float64, model.TrustChange) {
+		change := model.TrustChange{Reason: model.TrustReasonDecay}
+		from := lastActive.Add(s.decay.InactiveAfter)
+		if user.TrustDecayedAt != nil && user.TrustDecayedAt.After(from) {
+			from = *user.TrustDecayedAt
+		}
+		if !now.After(from) {
+			return user.TrustScore, change
+		}
+		score := decayTrust(user.TrustScore, s.decayTarget(user), now.Sub(from), s.decay.HalfLife)
+		user.TrustDecayedAt = &now
+		changed = s.applyLimits(score) != user.TrustScore
+		return score, change
+	})
+	return changed, err
+}
+
+// decayTarget 返回衰减的目标值：中性值减去用户当前的惩罚
+func (s *trustService) decayTarget(user *model.User) float64 {
+	return s.applyLimits(s.decay.Neutral - user.TrustPenalty)
+}
+
+// decayTrust 让 score 与 target 的差距按半衰期 halfLife 指数缩小 elapsed 时长
+func decayTrust(score, target float64, elapsed, halfLife time.Duration) float64 {
+	if elapsed <= 0 || halfLife <= 0 {
+		return score
+	}
+	return target + (score-target)*math.Pow(0.5, elapsed.Hours()/halfLife.Hours())
+}
+
+// ApplyTrustPenalties 把用户的信誉分惩罚更新为 penalties 中给出的值，不在其中的用户解除已有的惩罚
+// 信誉分按新旧惩罚的差值调整，返回惩罚发生变化的用户数
+func (s *trustService) ApplyTrustPenalties(penalties map[uint]float64) (int, error) {
+	ids, err := s.userRepo.FindPenalizedIDs()
+	if err != nil {
+		return 0, err
+	}
+	for id := range penalties {
+		ids = append(ids, id)
+	}
+
+	changed := 0
+	seen := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		penalty := penalties[id]
+		err := s.updateTrustScore(id, func(user *model.User) (float64, model.TrustChange) {
+			change := model.TrustChange{Reason: model.TrustReasonPenalty}
+			if penalty == user.TrustPenalty {
+				return user.TrustScore, change
+			}
+			score := user.TrustScore - (penalty - user.TrustPenalty)
+			user.TrustPenalty = penalty
+			changed++
+			return score, change
+		})
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return changed, err
+		}
+	}
+	return changed, nil
 }
 
 // applyLimits 辅助函数
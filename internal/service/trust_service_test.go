@@ -1,12 +1,15 @@
 package service
 
 import (
+	"context"
 	"github.com/novel/internal/model"
+	"github.com/novel/internal/pkg/config"
 	"github.com/novel/internal/repository/mocks"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"gorm.io/gorm"
 	"testing"
+	"time"
 )
 
 // TestUpdateTrustScoreOnNewRating 是我们的第一个测试用例
@@ -18,7 +21,7 @@ func TestUpdateTrustScoreOnNewRating(t *testing.T) {
 
 	// 创建我们要测试的 trustService 实例，并注入 mock repository
 	// 注意：因为我们只测试这个方法，所以 novelRepo 可以暂时传 nil
-	trustSvc := NewTrustService(mockUserRepo, nil)
+	trustSvc := NewTrustService(mockUserRepo, nil, &config.TrustDecayConfig{})
 
 	// 定义测试用的数据
 	testUserID := uint(1)
@@ -37,14 +40,15 @@ func TestUpdateTrustScoreOnNewRating(t *testing.T) {
 	// 我们期望它返回我们定义的 expectedUser 和 nil 错误。
 	mockUserRepo.On("FindByID", testUserID).Return(expectedUser, nil)
 
-	// 当 Update 方法被调用时，我们期望传入的对象是 *model.User 类型，
+	// 当 UpdateTrustScore 方法被调用时，我们期望传入的对象是 *model.User 类型和一条变动记录，
 	// 并且我们让这次调用返回 nil 错误，表示更新成功。
-	mockUserRepo.On("Update", mock.AnythingOfType("*model.User")).Return(nil)
+	mockUserRepo.On("UpdateTrustScore", mock.AnythingOfType("*model.User"), mock.AnythingOfType("*model.TrustChange")).Return(nil)
 
 	// --- 2. Act (执行阶段) ---
 
 	// 调用我们要测试的方法
-	err := trustSvc.UpdateTrustScoreOnNewRating(testUserID, highQualityRatingWeight)
+	testRatingID := uint(7)
+	err := trustSvc.UpdateTrustScoreOnNewRating(testUserID, testRatingID, highQualityRatingWeight)
 
 	// --- 3. Assert (断言阶段) ---
 
@@ -55,7 +59,7 @@ func TestUpdateTrustScoreOnNewRating(t *testing.T) {
 	// 断言：我们期望 mockUserRepo 的所有预设期望都已经被满足了
 	mockUserRepo.AssertExpectations(t)
 
-	// 进阶断言：我们可以捕获 Update 方法被调用时传入的参数，并检查它
+	// 进阶断言：我们可以捕获 UpdateTrustScore 方法被调用时传入的参数，并检查它
 	// 获取被捕获的调用参数
 	capturedUser := mockUserRepo.Calls[1].Arguments.Get(0).(*model.User)
 
@@ -63,6 +67,106 @@ func TestUpdateTrustScoreOnNewRating(t *testing.T) {
 	expectedScoreChange := 0.1
 	expectedFinalScore := initialTrustScore + expectedScoreChange
 
-	// 断言：传入 Update 方法的 user 对象的 TrustScore 是否是我们期望的值
+	// 断言：传入 UpdateTrustScore 方法的 user 对象的 TrustScore 是否是我们期望的值
 	assert.Equal(t, expectedFinalScore, capturedUser.TrustScore)
+
+	// 断言：变动记录写明了原因、变动值和来源评分
+	capturedChange := mockUserRepo.Calls[1].Arguments.Get(1).(*model.TrustChange)
+	assert.Equal(t, model.TrustReasonRatingCreated, capturedChange.Reason)
+	assert.InDelta(t, expectedScoreChange, capturedChange.Delta, 1e-9)
+	assert.Equal(t, expectedFinalScore, capturedChange.ScoreAfter)
+	assert.Equal(t, testRatingID, *capturedChange.RatingID)
+}
+
+// TestApplyTrustPenalties 检查惩罚按新旧差值调整信誉分，并解除不再可疑的用户的惩罚
+func TestApplyTrustPenalties(t *testing.T) {
+	mockUserRepo := new(mocks.UserRepositoryMock)
+	trustSvc := NewTrustService(mockUserRepo, nil, &config.TrustDecayConfig{})
+
+	// 用户 1 已有 0.1 的惩罚，这次不再可疑；用户 2 新增 0.2 的惩罚；用户 3 的惩罚不变
+	cleared := &model.User{Model: gorm.Model{ID: 1}, TrustScore: 1.0, TrustPenalty: 0.1}
+	penalized := &model.User{Model: gorm.Model{ID: 2}, TrustScore: 1.2}
+	unchanged := &model.User{Model: gorm.Model{ID: 3}, TrustScore: 0.9, TrustPenalty: 0.15}
+	mockUserRepo.On("FindPenalizedIDs").Return([]uint{1, 3}, nil)
+	mockUserRepo.On("FindByID", uint(1)).Return(cleared, nil)
+	mockUserRepo.On("FindByID", uint(2)).Return(penalized, nil)
+	mockUserRepo.On("FindByID", uint(3)).Return(unchanged, nil)
+	mockUserRepo.On("UpdateTrustScore", mock.AnythingOfType("*model.User"), mock.AnythingOfType("*model.TrustChange")).Return(nil)
+
+	changed, err := trustSvc.ApplyTrustPenalties(map[uint]float64{2: 0.2, 3: 0.15})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, changed)
+
+	assert.InDelta(t, 1.1, cleared.TrustScore, 1e-9)
+	assert.Equal(t, 0.0, cleared.TrustPenalty)
+	assert.InDelta(t, 1.0, penalized.TrustScore, 1e-9)
+	assert.Equal(t, 0.2, penalized.TrustPenalty)
+	assert.InDelta(t, 0.9, unchanged.TrustScore, 1e-9)
+	// 惩罚不变的用户加锁读取后原样保存，不写入变动记录
+	mockUserRepo.AssertNumberOfCalls(t, "UpdateTrustScore", 3)
+	mockUserRepo.AssertCalled(t, "UpdateTrustScore", unchanged, (*model.TrustChange)(nil))
+}
+
+func TestDecayTrust(t *testing.T) {
+	halfLife := 180 * 24 * time.Hour
+	assert.InDelta(t, 1.2, decayTrust(1.4, 1.0, halfLife, halfLife), 1e-9)
+	assert.InDelta(t, 0.95, decayTrust(0.9, 1.0, halfLife, halfLife), 1e-9)
+	// 分两次衰减与一次衰减相同
+	half := decayTrust(1.4, 1.0, halfLife/2, halfLife)
+	assert.InDelta(t, 1.2, decayTrust(half, 1.0, halfLife/2, halfLife), 1e-9)
+	assert.Equal(t, 1.4, decayTrust(1.4, 1.0, 0, halfLife))
+}
+
+// TestDecayInactiveTrustScores 检查衰减从上一次衰减的时间开始计算，并记录变动
+func TestDecayInactiveTrustScores(t *testing.T) {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	mockUserRepo := new(mocks.UserRepositoryMock)
+	svc := NewTrustService(mockUserRepo, nil, &config.TrustDecayConfig{
+		Enabled:       true,
+		InactiveAfter: 90 * day,
+		HalfLife:      180 * day,
+		Neutral:       1.0,
+	}).(*trustService)
+	svc.now = func() time.Time { return now }
+
+	// 用户 1 自 270 天前不活跃，但上一次衰减在 180 天前，本次只衰减 180 天
+	decayedAt := now.Add(-180 * day)
+	user := &model.User{Model: gorm.Model{ID: 1}, TrustScore: 1.4, TrustDecayedAt: &decayedAt}
+	mockUserRepo.On("FindInactiveIDsAfter", uint(0), now.Add(-90*day), 200).Return([]uint{1}, nil)
+	mockUserRepo.On("FindInactiveIDsAfter", uint(1), now.Add(-90*day), 200).Return([]uint{}, nil)
+	mockUserRepo.On("FindByID", uint(1)).Return(user, nil)
+	mockUserRepo.On("FindLastActivityAt", uint(1)).Return(now.Add(-270*day), nil)
+	mockUserRepo.On("UpdateTrustScore", user, mock.AnythingOfType("*model.TrustChange")).Return(nil)
+
+	assert.NoError(t, svc.DecayInactiveTrustScores(context.Background(), 0))
+	assert.InDelta(t, 1.2, user.TrustScore, 1e-9)
+	assert.Equal(t, now, *user.TrustDecayedAt)
+	change := mockUserRepo.Calls[3].Arguments.Get(1).(*model.TrustChange)
+	assert.Equal(t, model.TrustReasonDecay, change.Reason)
+	assert.InDelta(t, -0.2, change.Delta, 1e-9)
+}
+
+func TestRecalculationSkipsTinyLedgerEntries(t *testing.T) {
+	mockUserRepo := new(mocks.UserRepositoryMock)
+	svc := NewTrustService(mockUserRepo, nil, &config.TrustDecayConfig{}).(*trustService)
+
+	// 注册天数每天只带来约 0.0017 的加分，只更新信誉分，不写入变动记录
+	user := &model.User{Model: gorm.Model{ID: 1, CreatedAt: time.Now().Add(-24 * time.Hour)}, TrustScore: 1.0}
+	mockUserRepo.On("FindByIDWithRatings", uint(1)).Return(user, nil)
+	mockUserRepo.On("FindByID", uint(1)).Return(user, nil)
+	mockUserRepo.On("UpdateTrustScore", user, (*model.TrustChange)(nil)).Return(nil)
+	assert.NoError(t, svc.RecalculateAndSaveUserTrustScore(1))
+	assert.InDelta(t, 1.0017, user.TrustScore, 1e-4)
+
+	// 有意义的修正仍然记录
+	user2 := &model.User{Model: gorm.Model{ID: 2, CreatedAt: time.Now()}, TrustScore: 1.0,
+		Ratings: []model.Rating{{Weight: 0.9, UpvotesCount: 5}}}
+	mockUserRepo.On("FindByIDWithRatings", uint(2)).Return(user2, nil)
+	mockUserRepo.On("FindByID", uint(2)).Return(user2, nil)
+	mockUserRepo.On("UpdateTrustScore", user2, mock.AnythingOfType("*model.TrustChange")).Return(nil)
+	assert.NoError(t, svc.RecalculateAndSaveUserTrustScore(2))
+	change := mockUserRepo.Calls[5].Arguments.Get(1).(*model.TrustChange)
+	assert.Equal(t, model.TrustReasonRecalculation, change.Reason)
+	assert.InDelta(t, 0.15, change.Delta, 1e-4)
 }
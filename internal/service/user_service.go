@@ -1,12 +1,21 @@
 package service
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"github.com/novel/internal/model"
 	"github.com/novel/internal/pkg/config"
+	"github.com/novel/internal/pkg/jwtkeys"
+	"github.com/novel/internal/pkg/logger"
 	"github.com/novel/internal/repository"
+	"go.uber.org/zap"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 	"time"
@@ -17,26 +26,57 @@ var (
 	ErrUserAlreadyExists  = errors.New("username is already registered")
 	ErrInvalidCredentials = errors.New("invalid username or password")
 	ErrInvalidToken       = errors.New("invalid or expired token")
+	ErrInvalidRole        = errors.New("invalid user role")
+	// ErrRefreshTokenReused 表示一个已经轮换过的刷新令牌被再次使用，令牌可能已泄露，所在会话已被整体作废
+	ErrRefreshTokenReused = errors.New("refresh token reused")
 )
 
+// TokenClaims 是从 JWT 中解析出的身份信息
+type TokenClaims struct {
+	UserID    uint
+	Role      model.Role
+	JTI       string // 访问令牌的唯一ID，用于吊销
+	SessionID string // 签发该令牌的登录会话
+}
+
+// TokenPair 是登录或刷新后签发的一组令牌
+type TokenPair struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"` // 访问令牌的有效期 (秒)
+}
+
 // UserService 定义了用户认证相关的核心业务逻辑接口
 type UserService interface {
 	Register(username, password string) (*model.User, error)
-	Login(username, password string) (string, error)
-	ParseToken(tokenString string) (uint, error)
+	Login(username, password string) (*TokenPair, error)
+	Refresh(refreshToken string) (*TokenPair, error)
+	Logout(sessionID string) error
+	LogoutAll(userID uint) error
+	ParseToken(tokenString string) (*TokenClaims, error)
+	UpdateRole(userID uint, role model.Role) (*model.User, error)
+	CleanupExpiredTokens(ctx context.Context) error
 }
 
 // userService 结构体实现了 UserService 接口
 type userService struct {
-	repo   repository.UserRepository
-	jwtCfg *config.JWTConfig
+	repo      repository.UserRepository
+	tokenRepo repository.TokenRepository
+	txm       repository.TxManager
+	jwtCfg    *config.JWTConfig
+	keys      *jwtkeys.KeySet
+	now       func() time.Time
 }
 
 // NewUserService 是 userService 的构造函数，负责依赖注入
-func NewUserService(repo repository.UserRepository, jwtCfg *config.JWTConfig) UserService {
+func NewUserService(repo repository.UserRepository, tokenRepo repository.TokenRepository, txm repository.TxManager, jwtCfg *config.JWTConfig, keys *jwtkeys.KeySet) UserService {
 	return &userService{
-		repo:   repo,
-		jwtCfg: jwtCfg,
+		repo:      repo,
+		tokenRepo: tokenRepo,
+		txm:       txm,
+		jwtCfg:    jwtCfg,
+		keys:      keys,
+		now:       time.Now,
 	}
 }
 
@@ -62,6 +102,7 @@ func (s *userService) Register(username, password string) (*model.User, error) {
 	user := &model.User{
 		Username:     username,
 		PasswordHash: string(hashedPassword),
+		Role:         model.RoleReader,
 	}
 
 	// 4. 持久化到数据库
@@ -74,69 +115,204 @@ func (s *userService) Register(username, password string) (*model.User, error) {
 	return user, nil
 }
 
-// Login 负责处理用户登录逻辑
-func (s *userService) Login(username, password string) (string, error) {
+// Login 负责处理用户登录逻辑，成功后开启一个新的会话并签发访问令牌与刷新令牌
+func (s *userService) Login(username, password string) (*TokenPair, error) {
 	// 1. 根据用户名查找用户
 	user, err := s.repo.FindByUsername(username)
 	if err != nil {
 		// 统一返回“无效凭证”错误，避免泄露“用户不存在”的信息
-		return "", ErrInvalidCredentials
+		return nil, ErrInvalidCredentials
 	}
 
 	// 2. 验证密码哈希与提供的密码是否匹配
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
 		// 密码不匹配
-		return "", ErrInvalidCredentials
+		return nil, ErrInvalidCredentials
+	}
+
+	// 3. 签发令牌
+	return s.issueTokens(s.tokenRepo, user, uuid.NewString())
+}
+
+// Refresh 用刷新令牌换取一组新的令牌，旧的刷新令牌随即失效 (轮换)
+// 已轮换过的刷新令牌再次出现说明令牌可能被盗用，此时作废整个会话并返回 ErrRefreshTokenReused
+func (s *userService) Refresh(refreshToken string) (*TokenPair, error) {
+	var pair *TokenPair
+	var reused *model.RefreshToken
+	err := s.txm.Transaction(func(tx *gorm.DB) error {
+		tokens := s.tokenRepo.WithTx(tx)
+		stored, err := tokens.FindRefreshTokenByHashForUpdate(hashRefreshToken(refreshToken))
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidToken
+			}
+			return err
+		}
+		now := s.now()
+		if stored.RevokedAt != nil || !now.Before(stored.ExpiresAt) {
+			return ErrInvalidToken
+		}
+		if stored.RotatedAt != nil {
+			// 作废会话需要提交，因此这里不返回错误
+			reused = stored
+			return tokens.RevokeSession(stored.SessionID, now)
+		}
+
+		// 按最新的用户信息签发，角色变更在刷新后即生效
+		user, err := s.repo.FindByID(stored.UserID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidToken
+			}
+			return err
+		}
+		if err := tokens.MarkRefreshTokenRotated(stored.ID, now); err != nil {
+			return err
+		}
+		pair, err = s.issueTokens(tokens, user, stored.SessionID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if reused != nil {
+		logger.WarnRaw("Refresh token reuse detected, session revoked",
+			zap.Uint("user_id", reused.UserID), zap.String("session_id", reused.SessionID))
+		return nil, ErrRefreshTokenReused
 	}
+	return pair, nil
+}
+
+// Logout 退出当前会话：作废会话的刷新令牌并吊销其访问令牌
+func (s *userService) Logout(sessionID string) error {
+	return s.tokenRepo.RevokeSession(sessionID, s.now())
+}
+
+// LogoutAll 退出用户的所有会话
+func (s *userService) LogoutAll(userID uint) error {
+	return s.tokenRepo.RevokeUserSessions(userID, s.now())
+}
+
+// CleanupExpiredTokens 删除已过期的刷新令牌与吊销记录，由定时任务调用
+func (s *userService) CleanupExpiredTokens(ctx context.Context) error {
+	deleted, err := s.tokenRepo.DeleteExpired(s.now())
+	if err != nil {
+		return fmt.Errorf("failed to delete expired tokens: %w", err)
+	}
+	logger.Info(ctx, "Deleted expired tokens", zap.Int64("rows", deleted))
+	return nil
+}
 
-	// 3. 创建 JWT (JSON Web Token)
-	duration, err := time.ParseDuration(s.jwtCfg.ExpiryTime)
+// issueTokens 在 sessionID 会话中为用户签发访问令牌与刷新令牌，刷新令牌只保存哈希
+func (s *userService) issueTokens(tokens repository.TokenRepository, user *model.User, sessionID string) (*TokenPair, error) {
+	accessTTL, err := time.ParseDuration(s.jwtCfg.ExpiryTime)
 	if err != nil {
-		return "", errors.New("系统配置的过期时间无效")
+		return nil, errors.New("系统配置的过期时间无效")
 	}
+	refreshTTL, err := time.ParseDuration(s.jwtCfg.RefreshExpiryTime)
+	if err != nil {
+		return nil, errors.New("系统配置的刷新令牌过期时间无效")
+	}
+
+	now := s.now()
+	jti := uuid.NewString()
 	claims := jwt.MapClaims{
 		"user_id": user.ID,
-		"exp":     time.Now().Add(duration).Unix(),
-		"iat":     time.Now().Unix(),
+		"role":    string(user.Role),
+		"jti":     jti,
+		"sid":     sessionID,
+		"exp":     now.Add(accessTTL).Unix(),
+		"iat":     now.Unix(),
 	}
 
-	// 使用 HS256 签名算法创建一个新的 Token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	// 使用当前的签名密钥签名，非对称密钥会在头部写入 kid
+	accessToken, err := s.keys.Sign(claims)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign token: %w", err)
+	}
 
-	// 使用我们在配置中定义的密钥，对 Token 进行签名，生成最终的 Token 字符串
-	tokenString, err := token.SignedString([]byte(s.jwtCfg.SecretKey))
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+	refreshToken := base64.RawURLEncoding.EncodeToString(raw)
+	err = tokens.CreateRefreshToken(&model.RefreshToken{
+		UserID:          user.ID,
+		SessionID:       sessionID,
+		TokenHash:       hashRefreshToken(refreshToken),
+		ExpiresAt:       now.Add(refreshTTL),
+		AccessJTI:       jti,
+		AccessExpiresAt: now.Add(accessTTL),
+	})
 	if err != nil {
-		return "", fmt.Errorf("failed to sign token: %w", err)
+		return nil, fmt.Errorf("failed to save refresh token: %w", err)
 	}
+	return &TokenPair{AccessToken: accessToken, RefreshToken: refreshToken, ExpiresIn: int64(accessTTL.Seconds())}, nil
+}
 
-	return tokenString, nil
+// hashRefreshToken 返回刷新令牌的 SHA-256 哈希 (十六进制)
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
 
-// ParseToken 负责解析和验证 JWT
-func (s *userService) ParseToken(tokenString string) (uint, error) {
-	// 1. 解析 Token 字符串
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		// 2. 校验签名算法是否是我们预期的 HS256
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		// 3. 返回我们的密钥供库进行签名验证
-		return []byte(s.jwtCfg.SecretKey), nil
-	})
+// ParseToken 负责解析和验证 JWT，并拒绝已被吊销的令牌
+func (s *userService) ParseToken(tokenString string) (*TokenClaims, error) {
+	// 1. 解析 Token 字符串，按 kid 查找校验密钥，签名算法必须与该密钥一致
+	token, err := jwt.Parse(tokenString, s.keys.Keyfunc, jwt.WithValidMethods(s.keys.ValidMethods()))
 
 	if err != nil {
-		return 0, ErrInvalidToken // 解析或签名验证失败
+		return nil, ErrInvalidToken // 解析或签名验证失败
 	}
 
-	// 4. 验证 Token 是否有效，并提取 Claims
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		// 5. 从 Claims 中获取 user_id
-		userIDFloat, ok := claims["user_id"].(float64)
-		if !ok {
-			return 0, ErrInvalidToken // user_id 类型不正确
-		}
-		return uint(userIDFloat), nil
+	// 2. 验证 Token 是否有效，并提取 Claims
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, ErrInvalidToken
+	}
+	// 3. 从 Claims 中获取 user_id
+	userIDFloat, ok := claims["user_id"].(float64)
+	if !ok {
+		return nil, ErrInvalidToken // user_id 类型不正确
+	}
+	// 4. 没有 jti 的旧 Token 无法吊销，一律视为无效，需要重新登录
+	jti, _ := claims["jti"].(string)
+	sessionID, _ := claims["sid"].(string)
+	if jti == "" || sessionID == "" {
+		return nil, ErrInvalidToken
 	}
+	revoked, err := s.tokenRepo.IsAccessTokenRevoked(jti)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, ErrInvalidToken
+	}
+	// 5. 获取角色，未携带角色或角色无效时按普通读者处理
+	role := model.RoleReader
+	if roleStr, ok := claims["role"].(string); ok && model.Role(roleStr).IsValid() {
+		role = model.Role(roleStr)
+	}
+	return &TokenClaims{UserID: uint(userIDFloat), Role: role, JTI: jti, SessionID: sessionID}, nil
+}
 
-	return 0, ErrInvalidToken
+// UpdateRole 修改用户角色
+// 角色随访问令牌下发，因此同时吊销用户现有的访问令牌，客户端刷新后按新角色签发
+func (s *userService) UpdateRole(userID uint, role model.Role) (*model.User, error) {
+	if !role.IsValid() {
+		return nil, ErrInvalidRole
+	}
+	user, err := s.repo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateRole(userID, role); err != nil {
+		return nil, fmt.Errorf("failed to update user role: %w", err)
+	}
+	if err := s.tokenRepo.RevokeUserAccessTokens(userID, s.now()); err != nil {
+		return nil, fmt.Errorf("failed to revoke access tokens: %w", err)
+	}
+	user.Role = role
+	user.PasswordHash = ""
+	return user, nil
 }
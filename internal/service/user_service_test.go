@@ -0,0 +1,200 @@
+package service
+
+import (
+	"github.com/novel/internal/model"
+	"github.com/novel/internal/pkg/config"
+	"github.com/novel/internal/pkg/jwtkeys"
+	"github.com/novel/internal/repository"
+	"github.com/novel/internal/repository/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+// memoryTokenRepository 是 TokenRepository 的内存实现，用于测试令牌的轮换与吊销
+type memoryTokenRepository struct {
+	refresh []*model.RefreshToken
+	revoked map[string]bool
+}
+
+func newMemoryTokenRepository() *memoryTokenRepository {
+	return &memoryTokenRepository{revoked: make(map[string]bool)}
+}
+
+func (r *memoryTokenRepository) WithTx(*gorm.DB) repository.TokenRepository { return r }
+
+func (r *memoryTokenRepository) CreateRefreshToken(token *model.RefreshToken) error {
+	token.ID = uint(len(r.refresh) + 1)
+	r.refresh = append(r.refresh, token)
+	return nil
+}
+
+func (r *memoryTokenRepository) FindRefreshTokenByHashForUpdate(hash string) (*model.RefreshToken, error) {
+	for _, t := range r.refresh {
+		if t.TokenHash == hash {
+			copied := *t
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memoryTokenRepository) MarkRefreshTokenRotated(id uint, at time.Time) error {
+	r.refresh[id-1].RotatedAt = &at
+	return nil
+}
+
+func (r *memoryTokenRepository) RevokeSession(sessionID string, at time.Time) error {
+	return r.revoke(func(t *model.RefreshToken) bool { return t.SessionID == sessionID }, at, true)
+}
+
+func (r *memoryTokenRepository) RevokeUserSessions(userID uint, at time.Time) error {
+	return r.revoke(func(t *model.RefreshToken) bool { return t.UserID == userID }, at, true)
+}
+
+func (r *memoryTokenRepository) RevokeUserAccessTokens(userID uint, now time.Time) error {
+	return r.revoke(func(t *model.RefreshToken) bool { return t.UserID == userID }, now, false)
+}
+
+func (r *memoryTokenRepository) revoke(match func(*model.RefreshToken) bool, at time.Time, refresh bool) error {
+	for _, t := range r.refresh {
+		if !match(t) {
+			continue
+		}
+		if refresh && t.RevokedAt == nil {
+			t.RevokedAt = &at
+		}
+		if t.AccessExpiresAt.After(at) {
+			r.revoked[t.AccessJTI] = true
+		}
+	}
+	return nil
+}
+
+func (r *memoryTokenRepository) IsAccessTokenRevoked(jti string) (bool, error) {
+	return r.revoked[jti], nil
+}
+
+func (r *memoryTokenRepository) DeleteExpired(time.Time) (int64, error) { return 0, nil }
+
+// immediateTxManager 直接执行回调，事务由内存实现模拟
+type immediateTxManager struct{}
+
+func (immediateTxManager) Transaction(fn func(tx *gorm.DB) error) error { return fn(nil) }
+
+func newTestUserService(t *testing.T) (*userService, *mocks.UserRepositoryMock, *memoryTokenRepository) {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret1"), bcrypt.MinCost)
+	require.NoError(t, err)
+	user := &model.User{Model: gorm.Model{ID: 1}, Username: "reader", PasswordHash: string(hash), Role: model.RoleReader}
+
+	userRepo := new(mocks.UserRepositoryMock)
+	userRepo.On("FindByUsername", "reader").Return(user, nil)
+	userRepo.On("FindByID", uint(1)).Return(user, nil)
+	tokens := newMemoryTokenRepository()
+	cfg := &config.JWTConfig{
+		SecretKey:         "test-secret",
+		ExpiryTime:        "15m",
+		RefreshExpiryTime: "720h",
+	}
+	keys, err := jwtkeys.Load(cfg)
+	require.NoError(t, err)
+	svc := NewUserService(userRepo, tokens, immediateTxManager{}, cfg, keys).(*userService)
+	return svc, userRepo, tokens
+}
+
+func TestRefreshRotatesTokens(t *testing.T) {
+	svc, _, _ := newTestUserService(t)
+
+	first, err := svc.Login("reader", "secret1")
+	require.NoError(t, err)
+	claims, err := svc.ParseToken(first.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), claims.UserID)
+	assert.NotEmpty(t, claims.JTI)
+
+	second, err := svc.Refresh(first.RefreshToken)
+	require.NoError(t, err)
+	assert.NotEqual(t, first.RefreshToken, second.RefreshToken)
+	secondClaims, err := svc.ParseToken(second.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, claims.SessionID, secondClaims.SessionID, "刷新后仍属于同一会话")
+	assert.NotEqual(t, claims.JTI, secondClaims.JTI)
+}
+
+func TestRefreshTokenReuseRevokesSession(t *testing.T) {
+	svc, _, _ := newTestUserService(t)
+
+	first, err := svc.Login("reader", "secret1")
+	require.NoError(t, err)
+	second, err := svc.Refresh(first.RefreshToken)
+	require.NoError(t, err)
+
+	// 旧的刷新令牌被再次使用：整个会话作废，包括刚签发的令牌
+	_, err = svc.Refresh(first.RefreshToken)
+	assert.ErrorIs(t, err, ErrRefreshTokenReused)
+	_, err = svc.Refresh(second.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	_, err = svc.ParseToken(second.AccessToken)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	_, err = svc.ParseToken(first.AccessToken)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestLogoutRevokesOnlyCurrentSession(t *testing.T) {
+	svc, _, _ := newTestUserService(t)
+
+	phone, err := svc.Login("reader", "secret1")
+	require.NoError(t, err)
+	laptop, err := svc.Login("reader", "secret1")
+	require.NoError(t, err)
+
+	claims, err := svc.ParseToken(phone.AccessToken)
+	require.NoError(t, err)
+	require.NoError(t, svc.Logout(claims.SessionID))
+
+	_, err = svc.ParseToken(phone.AccessToken)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	_, err = svc.Refresh(phone.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	_, err = svc.ParseToken(laptop.AccessToken)
+	assert.NoError(t, err)
+
+	require.NoError(t, svc.LogoutAll(1))
+	_, err = svc.ParseToken(laptop.AccessToken)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestParseTokenRejectsTokensWithoutJTI(t *testing.T) {
+	svc, _, _ := newTestUserService(t)
+	svc.now = func() time.Time { return time.Now().Add(-time.Hour) }
+
+	// 过期的令牌同样无效
+	expired, err := svc.Login("reader", "secret1")
+	require.NoError(t, err)
+	_, err = svc.ParseToken(expired.AccessToken)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	_, err = svc.ParseToken("not-a-token")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestUpdateRoleRevokesAccessTokens(t *testing.T) {
+	svc, userRepo, _ := newTestUserService(t)
+	userRepo.On("UpdateRole", uint(1), model.RoleEditor).Return(nil)
+
+	pair, err := svc.Login("reader", "secret1")
+	require.NoError(t, err)
+	user, err := svc.UpdateRole(1, model.RoleEditor)
+	require.NoError(t, err)
+	assert.Equal(t, model.RoleEditor, user.Role)
+	userRepo.AssertNotCalled(t, "Update", mock.Anything)
+
+	// 旧的访问令牌携带旧角色，必须重新签发
+	_, err = svc.ParseToken(pair.AccessToken)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/novel/internal/dto"
+	"github.com/novel/internal/model"
+	"github.com/novel/internal/pkg/config"
+	"github.com/novel/internal/pkg/logger"
+	"github.com/novel/internal/pkg/votering"
+	"github.com/novel/internal/repository"
+	"go.uber.org/zap"
+)
+
+// defaultVoteBatchSize 是未指定批大小时每批读取的投票数
+const defaultVoteBatchSize = 5000
+
+// VoteRingReportDetails 是分析报告的详情
+type VoteRingReportDetails struct {
+	*model.VoteRingReport
+	Result *votering.Report `json:"result"`
+}
+
+// VoteRingService 定义了互赞团体与马甲账号分析的业务逻辑
+type VoteRingService interface {
+	Analyze(ctx context.Context, batchSize int) (*model.VoteRingReport, error)
+	ListReports(query *dto.VoteRingReportQuery) (*dto.PaginatedResponse, error)
+	GetReport(id uint) (*VoteRingReportDetails, error)
+}
+
+type voteRingService struct {
+	repo     repository.VoteRingRepository
+	trustSvc TrustService
+	cfg      *config.VoteRingConfig
+}
+
+// NewVoteRingService 是 voteRingService 的构造函数
+func NewVoteRingService(repo repository.VoteRingRepository, trustSvc TrustService, cfg *config.VoteRingConfig) VoteRingService {
+	return &voteRingService{repo: repo, trustSvc: trustSvc, cfg: cfg}
+}
+
+// Analyze 读取全部有效投票进行分析并保存报告
+// 开启 apply_penalties 时按报告更新可疑账号的信誉分惩罚，上次被惩罚而本次不再可疑的账号解除惩罚
+func (s *voteRingService) Analyze(ctx context.Context, batchSize int) (*model.VoteRingReport, error) {
+	if batchSize <= 0 {
+		batchSize = defaultVoteBatchSize
+	}
+	var votes []votering.Vote
+	var lastID uint
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		edges, err := s.repo.FindVotesAfter(lastID, batchSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load rating votes: %w", err)
+		}
+		for _, e := range edges {
+			votes = append(votes, votering.Vote{VoterID: e.VoterID, AuthorID: e.AuthorID, RatingID: e.RatingID, Value: e.Value})
+		}
+		if len(edges) < batchSize {
+			break
+		}
+		lastID = edges[len(edges)-1].ID
+	}
+
+	result := votering.Analyze(votes, voteRingOptions(s.cfg))
+	payload, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	report := &model.VoteRingReport{
+		VotesAnalyzed:    result.VotesAnalyzed,
+		Voters:           result.Voters,
+		SockpuppetGroups: len(result.SockpuppetGroups),
+		Suspects:         len(result.Suspects),
+		Report:           string(payload),
+	}
+	for _, c := range result.Clusters {
+		if c.Flagged {
+			report.FlaggedClusters++
+		}
+	}
+
+	if s.cfg.ApplyPenalties {
+		penalties := make(map[uint]float64, len(result.Suspects))
+		for _, suspect := range result.Suspects {
+			penalties[suspect.UserID] = suspect.Penalty
+		}
+		changed, err := s.trustSvc.ApplyTrustPenalties(penalties)
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply trust penalties: %w", err)
+		}
+		report.PenaltiesApplied = true
+		logger.Info(ctx, "Applied vote ring trust penalties", zap.Int("users_changed", changed))
+	}
+
+	if err := s.repo.CreateReport(report); err != nil {
+		return nil, err
+	}
+	logger.Info(ctx, "Vote ring analysis finished",
+		zap.Uint("report_id", report.ID),
+		zap.Int("votes", report.VotesAnalyzed),
+		zap.Int("flagged_clusters", report.FlaggedClusters),
+		zap.Int("sockpuppet_groups", report.SockpuppetGroups),
+		zap.Int("suspects", report.Suspects))
+	return report, nil
+}
+
+// ListReports 分页查询分析报告，不包含报告详情
+func (s *voteRingService) ListReports(query *dto.VoteRingReportQuery) (*dto.PaginatedResponse, error) {
+	normalizePaging(&query.Page, &query.PageSize)
+	reports, total, err := s.repo.FindReports(query.Page, query.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	return paginatedResponse(&total, query.Page, query.PageSize, false, "", reports), nil
+}
+
+// GetReport 返回分析报告及其中的团体、相似账号与可疑账号明细
+func (s *voteRingService) GetReport(id uint) (*VoteRingReportDetails, error) {
+	report, err := s.repo.FindReportByID(id)
+	if err != nil {
+		return nil, err
+	}
+	var result votering.Report
+	if err := json.Unmarshal([]byte(report.Report), &result); err != nil {
+		return nil, fmt.Errorf("failed to decode vote ring report %d: %w", id, err)
+	}
+	return &VoteRingReportDetails{VoteRingReport: report, Result: &result}, nil
+}
+
+// voteRingOptions 把配置转换为分析参数，未设置的参数使用默认值
+func voteRingOptions(cfg *config.VoteRingConfig) votering.Options {
+	opts := votering.DefaultOptions()
+	if cfg.MinMutualUpvotes > 0 {
+		opts.MinMutualUpvotes = cfg.MinMutualUpvotes
+	}
+	if cfg.MinInternalShare > 0 {
+		opts.MinInternalShare = cfg.MinInternalShare
+	}
+	if cfg.MinVotesSimilarity > 0 {
+		opts.MinVotesSimilarity = cfg.MinVotesSimilarity
+	}
+	if cfg.SimilarityThreshold > 0 {
+		opts.SimilarityThreshold = cfg.SimilarityThreshold
+	}
+	if cfg.MaxCoVoters > 0 {
+		opts.MaxCoVoters = cfg.MaxCoVoters
+	}
+	if cfg.ClusterPenalty > 0 {
+		opts.ClusterPenalty = cfg.ClusterPenalty
+	}
+	if cfg.SimilarityPenalty > 0 {
+		opts.SimilarityPenalty = cfg.SimilarityPenalty
+	}
+	if cfg.MaxPenalty > 0 {
+		opts.MaxPenalty = cfg.MaxPenalty
+	}
+	return opts
+}
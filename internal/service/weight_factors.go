@@ -0,0 +1,209 @@
+package service
+
+import (
+	"errors"
+	"github.com/novel/internal/pkg/quality"
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+// 内置的权重因子
+
+func init() {
+	RegisterWeightFactor("action", WeightFactorParams{"with_comment": 1.0, "without_comment": 0.5}, newActionFactor)
+	RegisterWeightFactor("quality", WeightFactorParams{"min_weight": 0.6, "max_weight": 1.2}, newQualityFactor)
+	RegisterWeightFactor("trust", WeightFactorParams{}, newTrustFactor)
+	RegisterWeightFactor("community", WeightFactorParams{"coefficient": 0.5}, newCommunityFactor)
+	RegisterWeightFactor("suspicion", WeightFactorParams{"weight": 0.1}, newSuspicionFactor)
+	RegisterWeightFactor("probation", WeightFactorParams{"weight": 0.5}, newProbationFactor)
+	RegisterWeightFactor("comment_length", WeightFactorParams{"short_chars": 10, "long_chars": 300, "min_weight": 0.9, "max_weight": 1.1}, newCommentLengthFactor)
+	RegisterWeightFactor("account_age", WeightFactorParams{"full_after_days": 30, "min_weight": 0.7}, newAccountAgeFactor)
+}
+
+// actionFactor 带评论的评分比只打分的评分更有参考价值
+type actionFactor struct {
+	withComment, withoutComment float64
+}
+
+func newActionFactor(p WeightFactorParams) (WeightFactor, error) {
+	return &actionFactor{withComment: p["with_comment"], withoutComment: p["without_comment"]}, nil
+}
+
+func (f *actionFactor) Name() string { return "action" }
+
+func (f *actionFactor) Compute(in *WeightInput) float64 {
+	if in.Rating.Comment != "" {
+		return f.withComment
+	}
+	return f.withoutComment
+}
+
+// qualityFactor 评论内容的质量：质量得分 (0~1) 线性映射到 [min_weight, max_weight]
+// 没有评论时为 1，是否附带评论由 action 因子处理
+type qualityFactor struct {
+	minWeight, maxWeight float64
+}
+
+func newQualityFactor(p WeightFactorParams) (WeightFactor, error) {
+	f := &qualityFactor{minWeight: p["min_weight"], maxWeight: p["max_weight"]}
+	if f.minWeight > f.maxWeight {
+		return nil, errors.New("min_weight must not be greater than max_weight")
+	}
+	return f, nil
+}
+
+func (f *qualityFactor) Name() string { return "quality" }
+
+func (f *qualityFactor) Compute(in *WeightInput) float64 {
+	if strings.TrimSpace(in.Rating.Comment) == "" {
+		return 1.0
+	}
+	// 早于质量评估上线的评分没有保存得分，此时按内容现场评估 (无法识别复制粘贴)
+	score := quality.Score(in.Rating.Comment, false).Score
+	if in.Rating.QualityScore != nil {
+		score = *in.Rating.QualityScore
+	}
+	return f.minWeight + (f.maxWeight-f.minWeight)*score
+}
+
+// trustFactor 评分作者的信誉分
+type trustFactor struct{}
+
+func newTrustFactor(WeightFactorParams) (WeightFactor, error) { return &trustFactor{}, nil }
+
+func (f *trustFactor) Name() string { return "trust" }
+
+func (f *trustFactor) Compute(in *WeightInput) float64 {
+	if in.Author == nil {
+		return 1.0
+	}
+	return in.Author.TrustScore
+}
+
+// communityFactor 社区认可度：1 + coefficient × log10(净赞同信誉分 + 1)
+// 每张票按投票者投票时的信誉分计入，新注册或低信誉账号的投票影响较小
+type communityFactor struct {
+	coefficient float64
+}
+
+func newCommunityFactor(p WeightFactorParams) (WeightFactor, error) {
+	return &communityFactor{coefficient: p["coefficient"]}, nil
+}
+
+func (f *communityFactor) Name() string { return "community" }
+
+func (f *communityFactor) Compute(in *WeightInput) float64 {
+	netTrust := in.Rating.UpvoteTrust - in.Rating.DownvoteTrust
+	if netTrust < 0 {
+		netTrust = 0
+	}
+	return 1 + f.coefficient*math.Log10(netTrust+1)
+}
+
+// suspicionFactor 被标记为疑似刷分的评分乘以 weight，weight 为 0 时相当于不计入小说分数
+type suspicionFactor struct {
+	weight float64
+}
+
+func newSuspicionFactor(p WeightFactorParams) (WeightFactor, error) {
+	f := &suspicionFactor{weight: p["weight"]}
+	if f.weight < 0 || f.weight > 1 {
+		return nil, errors.New("weight must be between 0 and 1")
+	}
+	return f, nil
+}
+
+func (f *suspicionFactor) Name() string { return "suspicion" }
+
+func (f *suspicionFactor) Compute(in *WeightInput) float64 {
+	if in.Rating.SuspectedIncidentID != nil {
+		return f.weight
+	}
+	return 1.0
+}
+
+// probationFactor 试用期账号的评分乘以 weight，试用期结束后由下一次权重重算恢复
+type probationFactor struct {
+	weight float64
+}
+
+func newProbationFactor(p WeightFactorParams) (WeightFactor, error) {
+	f := &probationFactor{weight: p["weight"]}
+	if f.weight < 0 || f.weight > 1 {
+		return nil, errors.New("weight must be between 0 and 1")
+	}
+	return f, nil
+}
+
+func (f *probationFactor) Name() string { return "probation" }
+
+func (f *probationFactor) Compute(in *WeightInput) float64 {
+	if in.AuthorOnProbation {
+		return f.weight
+	}
+	return 1.0
+}
+
+// commentLengthFactor 评论字数在 short_chars 与 long_chars 之间时，权重从 min_weight 线性增长到 max_weight
+type commentLengthFactor struct {
+	shortChars, longChars int
+	minWeight, maxWeight  float64
+}
+
+func newCommentLengthFactor(p WeightFactorParams) (WeightFactor, error) {
+	f := &commentLengthFactor{
+		shortChars: int(p["short_chars"]),
+		longChars:  int(p["long_chars"]),
+		minWeight:  p["min_weight"],
+		maxWeight:  p["max_weight"],
+	}
+	if f.longChars <= f.shortChars {
+		return nil, errors.New("long_chars must be greater than short_chars")
+	}
+	return f, nil
+}
+
+func (f *commentLengthFactor) Name() string { return "comment_length" }
+
+func (f *commentLengthFactor) Compute(in *WeightInput) float64 {
+	n := utf8.RuneCountInString(in.Rating.Comment)
+	switch {
+	case n <= f.shortChars:
+		return f.minWeight
+	case n >= f.longChars:
+		return f.maxWeight
+	}
+	ratio := float64(n-f.shortChars) / float64(f.longChars-f.shortChars)
+	return f.minWeight + (f.maxWeight-f.minWeight)*ratio
+}
+
+// accountAgeFactor 新注册账号的评分权重较低，随注册天数线性增长，满 full_after_days 天后为 1
+type accountAgeFactor struct {
+	fullAfterDays float64
+	minWeight     float64
+}
+
+func newAccountAgeFactor(p WeightFactorParams) (WeightFactor, error) {
+	f := &accountAgeFactor{fullAfterDays: p["full_after_days"], minWeight: p["min_weight"]}
+	if f.fullAfterDays <= 0 {
+		return nil, errors.New("full_after_days must be positive")
+	}
+	return f, nil
+}
+
+func (f *accountAgeFactor) Name() string { return "account_age" }
+
+func (f *accountAgeFactor) Compute(in *WeightInput) float64 {
+	if in.Author == nil {
+		return 1.0
+	}
+	days := in.Now.Sub(in.Author.CreatedAt).Hours() / 24
+	if days >= f.fullAfterDays {
+		return 1.0
+	}
+	if days < 0 {
+		days = 0
+	}
+	return f.minWeight + (1-f.minWeight)*days/f.fullAfterDays
+}
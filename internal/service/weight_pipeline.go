@@ -0,0 +1,124 @@
+package service
+
+import (
+	"fmt"
+	"github.com/novel/internal/model"
+	"github.com/novel/internal/pkg/config"
+	"sort"
+	"strings"
+	"time"
+)
+
+// WeightFactor 是评分权重的一个组成因子，评分的最终权重为所有启用因子之积
+type WeightFactor interface {
+	Name() string
+	Compute(in *WeightInput) float64
+}
+
+// WeightInput 是计算评分权重所需的数据
+type WeightInput struct {
+	Rating *model.Rating
+	Author *model.User // 评分作者，可能为 nil
+	Now    time.Time
+
+	AuthorOnProbation bool // 评分作者是否处于新账号试用期
+}
+
+// FactorValue 记录某个因子对一条评分算出的值，用于解释权重的来源
+type FactorValue struct {
+	Name  string  `json:"name"`
+	Value float64 `json:"value"`
+}
+
+// WeightFactorParams 是因子的参数，未在配置中给出的参数使用默认值
+type WeightFactorParams map[string]float64
+
+// weightFactorSpec 描述一种可配置的因子：参数默认值及构造函数
+type weightFactorSpec struct {
+	defaults WeightFactorParams
+	build    func(p WeightFactorParams) (WeightFactor, error)
+}
+
+// weightFactorSpecs 是所有可用因子的注册表
+var weightFactorSpecs = map[string]weightFactorSpec{}
+
+// RegisterWeightFactor 注册一种新的权重因子，defaults 同时声明了该因子接受的全部参数
+// 应在包初始化时调用
+func RegisterWeightFactor(name string, defaults WeightFactorParams, build func(p WeightFactorParams) (WeightFactor, error)) {
+	if _, ok := weightFactorSpecs[name]; ok {
+		panic(fmt.Sprintf("weight factor %q registered twice", name))
+	}
+	weightFactorSpecs[name] = weightFactorSpec{defaults: defaults, build: build}
+}
+
+// defaultWeightFactors 是未配置 algorithm.weight_factors 时使用的因子
+var defaultWeightFactors = []string{"action", "quality", "trust", "community", "suspicion", "probation"}
+
+// WeightPipeline 按配置顺序依次计算各个因子，并将它们相乘得到评分的最终权重
+type WeightPipeline struct {
+	factors []WeightFactor
+}
+
+// NewWeightPipeline 根据配置构建权重计算流水线，cfgs 为空时使用默认因子
+func NewWeightPipeline(cfgs []config.WeightFactorConfig) (*WeightPipeline, error) {
+	if len(cfgs) == 0 {
+		for _, name := range defaultWeightFactors {
+			cfgs = append(cfgs, config.WeightFactorConfig{Name: name})
+		}
+	}
+
+	p := &WeightPipeline{}
+	seen := make(map[string]bool)
+	for _, c := range cfgs {
+		if c.Enabled != nil && !*c.Enabled {
+			continue
+		}
+		spec, ok := weightFactorSpecs[c.Name]
+		if !ok {
+			return nil, fmt.Errorf("unknown weight factor %q (available: %s)", c.Name, strings.Join(WeightFactorNames(), ", "))
+		}
+		if seen[c.Name] {
+			return nil, fmt.Errorf("weight factor %q configured twice", c.Name)
+		}
+		seen[c.Name] = true
+
+		params := make(WeightFactorParams, len(spec.defaults))
+		for k, v := range spec.defaults {
+			params[k] = v
+		}
+		for k, v := range c.Params {
+			if _, ok := spec.defaults[k]; !ok {
+				return nil, fmt.Errorf("unknown parameter %q for weight factor %q", k, c.Name)
+			}
+			params[k] = v
+		}
+		factor, err := spec.build(params)
+		if err != nil {
+			return nil, fmt.Errorf("invalid parameters for weight factor %q: %w", c.Name, err)
+		}
+		p.factors = append(p.factors, factor)
+	}
+	return p, nil
+}
+
+// WeightFactorNames 返回所有已注册因子的名称
+func WeightFactorNames() []string {
+	names := make([]string, 0, len(weightFactorSpecs))
+	for name := range weightFactorSpecs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Compute 计算评分的最终权重，同时返回每个因子的值
+func (p *WeightPipeline) Compute(in *WeightInput) (float64, []FactorValue) {
+	weight := 1.0
+	values := make([]FactorValue, 0, len(p.factors))
+	for _, f := range p.factors {
+		v := f.Compute(in)
+		weight *= v
+		values = append(values, FactorValue{Name: f.Name(), Value: v})
+	}
+	return weight, values
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"github.com/novel/internal/model"
+	"github.com/novel/internal/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestDefaultWeightPipeline(t *testing.T) {
+	p, err := NewWeightPipeline(nil)
+	require.NoError(t, err)
+
+	author := &model.User{TrustScore: 1.2}
+	qualityScore := 0.5
+	// 社区认可度按投票者的信誉分之和计算，与投票数无关
+	rating := &model.Rating{Comment: "好看", UpvotesCount: 100, UpvoteTrust: 12.5, DownvoteTrust: 3.5, QualityScore: &qualityScore}
+	weight, factors := p.Compute(&WeightInput{Rating: rating, Author: author, Now: time.Now()})
+
+	// wAction * wQuality * wUser * wCommunity
+	expected := 1.0 * 0.9 * 1.2 * (1 + 0.5*math.Log10(10))
+	assert.InDelta(t, expected, weight, 1e-9)
+	require.Len(t, factors, 6)
+	assert.Equal(t, "action", factors[0].Name)
+	assert.Equal(t, "community", factors[3].Name)
+	assert.Equal(t, "suspicion", factors[4].Name)
+	assert.Equal(t, "probation", factors[5].Name)
+
+	// 被标记为疑似刷分后降权
+	incidentID := uint(1)
+	rating.SuspectedIncidentID = &incidentID
+	suspicious, _ := p.Compute(&WeightInput{Rating: rating, Author: author, Now: time.Now()})
+	assert.InDelta(t, expected*0.1, suspicious, 1e-9)
+
+	// 试用期账号的评分再降权
+	probation, _ := p.Compute(&WeightInput{Rating: rating, Author: author, Now: time.Now(), AuthorOnProbation: true})
+	assert.InDelta(t, expected*0.1*0.5, probation, 1e-9)
+}
+
+func TestWeightPipelineConfig(t *testing.T) {
+	disabled := false
+	p, err := NewWeightPipeline([]config.WeightFactorConfig{
+		{Name: "action", Params: map[string]float64{"without_comment": 0.2}},
+		{Name: "trust", Enabled: &disabled},
+		{Name: "account_age", Params: map[string]float64{"full_after_days": 10, "min_weight": 0.5}},
+	})
+	require.NoError(t, err)
+
+	now := time.Now()
+	author := &model.User{TrustScore: 1.5}
+	author.CreatedAt = now.Add(-5 * 24 * time.Hour)
+	weight, factors := p.Compute(&WeightInput{Rating: &model.Rating{}, Author: author, Now: now})
+	assert.InDelta(t, 0.2*0.75, weight, 1e-9)
+	assert.Len(t, factors, 2)
+}
+
+func TestWeightPipelineConfigErrors(t *testing.T) {
+	cases := [][]config.WeightFactorConfig{
+		{{Name: "no_such_factor"}},
+		{{Name: "action", Params: map[string]float64{"typo": 1}}},
+		{{Name: "trust"}, {Name: "trust"}},
+		{{Name: "comment_length", Params: map[string]float64{"short_chars": 100, "long_chars": 50}}},
+	}
+	for _, cfgs := range cases {
+		_, err := NewWeightPipeline(cfgs)
+		assert.Error(t, err, cfgs[0].Name)
+	}
+}